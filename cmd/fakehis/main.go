@@ -0,0 +1,34 @@
+// Command fakehis runs the fake hospital HIS for local development.
+//
+//	fakehis -addr :8081 -fixtures config/fakehis.example.json
+//
+// Point a hospital's base_url at http://localhost:8081/{hospital_id}. Failure
+// scenarios are toggled at runtime:
+//
+//	curl -X PUT localhost:8081/_admin/scenarios/HIS-1 -d '{"kind":"error","status":503,"count":5}'
+//	curl -X PUT localhost:8081/_admin/scenarios/HIS-1 -d '{"kind":"slow","delay":"3s"}'
+//	curl -X DELETE localhost:8081/_admin/scenarios/HIS-1
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/haniscreator/agnos-search/internal/fakehis"
+)
+
+func main() {
+	addr := flag.String("addr", ":8081", "listen address")
+	fixtures := flag.String("fixtures", "config/fakehis.example.json", "patient fixtures per hospital")
+	flag.Parse()
+
+	fx, err := fakehis.LoadFixtures(*fixtures)
+	if err != nil {
+		log.Fatalf("fakehis: %v", err)
+	}
+	for hid, patients := range fx {
+		log.Printf("fakehis: %s: %d patients at http://localhost%s/%s", hid, len(patients), *addr, hid)
+	}
+	log.Fatal(http.ListenAndServe(*addr, fakehis.New(fx)))
+}
@@ -0,0 +1,109 @@
+// Command mapcheck runs a hospital's field_mapping against sample HIS responses.
+//
+//	mapcheck -config config/his_registry.json -hospital HIS-2 samples/his2/*.json
+//	mapcheck -spec mapping.json sample.json
+//
+// It prints the mapped standard fields of every sample and exits 1 if the spec
+// is invalid or any value could not be mapped.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("mapcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", "", "HIS registry file (with -hospital)")
+	hospital := fs.String("hospital", "", "hospital_id whose field_mapping to use")
+	specPath := fs.String("spec", "", "file holding just a field_mapping object")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 || (*specPath == "") == (*configPath == "") {
+		fmt.Fprintln(stderr, "usage: mapcheck (-spec FILE | -config FILE -hospital ID) SAMPLE.json...")
+		return 2
+	}
+
+	spec, err := loadSpec(*specPath, *configPath, *hospital)
+	if err != nil {
+		fmt.Fprintf(stderr, "mapcheck: %v\n", err)
+		return 1
+	}
+	if err := spec.Validate(); err != nil {
+		fmt.Fprintf(stderr, "mapcheck: invalid spec:\n%v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		p, ferrs, err := spec.Apply(body)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			status = 1
+			continue
+		}
+		out, _ := json.MarshalIndent(standardValues(p), "", "  ")
+		fmt.Fprintf(stdout, "%s:\n%s\n", path, out)
+		for _, fe := range ferrs {
+			fmt.Fprintf(stderr, "%s: %v\n", path, fe)
+			status = 1
+		}
+	}
+	return status
+}
+
+func loadSpec(specPath, configPath, hospital string) (adapter.MappingSpec, error) {
+	if specPath != "" {
+		b, err := os.ReadFile(specPath)
+		if err != nil {
+			return nil, err
+		}
+		var spec adapter.MappingSpec
+		if err := json.Unmarshal(b, &spec); err != nil {
+			return nil, fmt.Errorf("decode %s: %w", specPath, err)
+		}
+		return spec, nil
+	}
+
+	if hospital == "" {
+		return nil, errors.New("-config needs -hospital")
+	}
+	configs, err := adapter.LoadRegistryFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range configs {
+		if c.HospitalID == hospital {
+			return c.FieldMapping, nil
+		}
+	}
+	return nil, fmt.Errorf("hospital %s not in %s", hospital, configPath)
+}
+
+// standardValues lists the mapped fields under their standard names.
+func standardValues(p *repository.Patient) map[string]string {
+	values := make(map[string]string, len(adapter.StandardFields))
+	for _, f := range adapter.StandardFields {
+		values[f] = p.Field(f)
+	}
+	return values
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	assert.NoError(t, os.WriteFile(p, []byte(content), 0o600))
+	return p
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	spec := writeFile(t, dir, "spec.json", `{
+		"patient_hn": "hn",
+		"date_of_birth": {"path": "dob", "date_format": "dd/mm/yyyy"},
+		"gender": {"path": "sex", "enum": {"male": "M", "female": "F"}}
+	}`)
+	good := writeFile(t, dir, "good.json", `{"hn":"HN-1","dob":"05/05/1985","sex":"Male"}`)
+	bad := writeFile(t, dir, "bad.json", `{"hn":"HN-2","dob":"1985-05-05","sex":"x"}`)
+
+	var out, errOut bytes.Buffer
+	assert.Equal(t, 0, run([]string{"-spec", spec, good}, &out, &errOut))
+	assert.Contains(t, out.String(), `"date_of_birth": "1985-05-05"`)
+	assert.Contains(t, out.String(), `"gender": "M"`)
+
+	out.Reset()
+	errOut.Reset()
+	assert.Equal(t, 1, run([]string{"-spec", spec, bad}, &out, &errOut))
+	assert.Contains(t, errOut.String(), "date_of_birth")
+	assert.Contains(t, errOut.String(), "gender")
+
+	invalid := writeFile(t, dir, "invalid.json", `{"shoe_size": "size"}`)
+	assert.Equal(t, 1, run([]string{"-spec", invalid, good}, &out, &errOut))
+
+	assert.Equal(t, 2, run([]string{good}, &out, &errOut))
+}
@@ -2,9 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,9 +20,13 @@ import (
 	"github.com/haniscreator/agnos-search/internal/adapter"
 	"github.com/haniscreator/agnos-search/internal/db"
 	"github.com/haniscreator/agnos-search/internal/handler"
+	"github.com/haniscreator/agnos-search/internal/hl7"
+	"github.com/haniscreator/agnos-search/internal/linkage"
 	"github.com/haniscreator/agnos-search/internal/middleware"
+	"github.com/haniscreator/agnos-search/internal/outbox"
 	"github.com/haniscreator/agnos-search/internal/repository"
 	"github.com/haniscreator/agnos-search/internal/service"
+	"github.com/haniscreator/agnos-search/internal/webhook"
 )
 
 func main() {
@@ -32,24 +42,57 @@ func main() {
 	}
 
 	// 2) Create DB pool with retries (BLOCK until success or fatal)
-	ctx := context.Background()
+	// ctx is cancelled on SIGINT/SIGTERM and stops background workers and event streams.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	pool := mustCreateDBPoolWithRetry(ctx)
+	defer pool.Close()
 
 	// 3) Wire repositories & services
 	staffRepo := repository.NewStaffRepo(pool)
 	patientRepo := repository.NewPatientRepo(pool)
 	analyticsRepo := repository.NewAnalyticsRepo(pool)
+	uow := repository.NewUnitOfWork(pool)
 
 	authSvc := service.NewAuthService(staffRepo)
 
-	base := os.Getenv("HOSPITAL_BASE")
-	if base == "" {
-		base = "http://hospital-a.api.co.th"
+	// per-hospital HIS adapters (HIS_REGISTRY_FILE, else the hospital_systems table)
+	registry := mustBuildRegistry(ctx, pool)
+	log.Printf("HIS registry: %d hospital(s) configured %v", len(registry.Hospitals()), registry.Hospitals())
+
+	// record linkage: duplicate warnings on create, merge candidates, flags on HIS / HL7 ingestion
+	linker := service.NewLinker(patientRepo, repository.NewPatientLinkRepo(pool), mustBuildLinkageEngine(os.Getenv("LINKAGE_CONFIG_FILE")))
+
+	// 4) Outbox relay publishes patient change events committed by the services
+	broker := outbox.NewBroker()
+	sinks := []outbox.Sink{broker}
+	if u := os.Getenv("OUTBOX_WEBHOOK_URL"); u != "" {
+		sinks = append(sinks, outbox.NewWebhookSink(u, 5*time.Second))
 	}
+	if path := os.Getenv("OUTBOX_FILE"); path != "" {
+		sinks = append(sinks, outbox.NewFileSink(path))
+	}
+	// per-hospital webhook subscriptions: the dispatcher queues deliveries, the deliverer sends them signed
+	sinks = append(sinks, webhook.NewDispatcher(uow))
+	relay := outbox.NewRelay(uow, time.Second, sinks...)
+	go relay.Run(ctx)
 
-	adapterClient, aErr := adapter.NewHospitalAdapter(base, 2*time.Second)
+	maxAttempts := 8
+	if v, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && v > 0 {
+		maxAttempts = v
+	}
+	// endpoints on loopback/private addresses are refused unless WEBHOOK_ALLOW_PRIVATE (local receivers)
+	allowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE") == "true"
+	deliverer := webhook.NewDeliverer(uow, 2*time.Second, 10*time.Second, maxAttempts, allowPrivate)
+	go deliverer.Run(ctx)
+	webhookMgr := webhook.NewManager(repository.NewWebhookRepo(pool), os.Getenv("WEBHOOK_ALLOW_HTTP") == "true", allowPrivate)
 
-	// 4) Setup Gin AFTER all deps are ready
+	// HL7 v2 ADT feeds pushed over MLLP by HIS systems without a lookup API
+	startHL7Listeners(ctx, os.Getenv("HL7_LISTENERS"),
+		repository.NewHL7MessageRepo(pool),
+		service.NewIngestWriter(uow, registry, repository.SourceHL7, linker, registry))
+
+	// 5) Setup Gin AFTER all deps are ready
 	r := gin.Default()
 
 	// basic endpoints that don't need DB
@@ -67,26 +110,59 @@ func main() {
 	authGroup := r.Group("/")
 	authGroup.Use(middleware.AuthMiddleware(jwtSecret))
 
-	// READ + SEARCH patient routes depend on adapter (HIS)
-	if aErr != nil {
-		log.Printf("warning: could not create adapter: %v; patient read/search routes will use stub", aErr)
-		stub := &dbUnavailableService{err: fmt.Errorf("hospital adapter not available")}
-		handler.RegisterPatientRoutes(authGroup, stub, analyticsRepo)
-	} else {
-		patientSvc := service.NewPatientService(patientRepo, adapterClient)
-		handler.RegisterPatientRoutes(authGroup, patientSvc, analyticsRepo)
+	// READ + SEARCH patient routes; hospitals without an HIS get DB-only reads
+	patientSvc := service.NewPatientService(patientRepo, uow, registry,
+		service.WithLinker(linker), service.WithFieldSources(repository.NewPatientFieldSourceRepo(pool)))
+	handler.RegisterPatientRoutes(authGroup, patientSvc, analyticsRepo)
+
+	// WRITE routes (POST /v1/patients) go through the unit of work; the registry supplies survivorship rules
+	handler.RegisterPatientWriteRoutes(authGroup, service.NewPatientWriter(uow, registry, registry), linker)
+
+	// cross-hospital lookup for transfers / emergencies, only for NETWORK_LOOKUP_ROLES
+	networkTimeout, _ := time.ParseDuration(os.Getenv("NETWORK_LOOKUP_TIMEOUT"))
+	networkGroup := authGroup.Group("/", middleware.RequireRole(networkLookupRoles(os.Getenv("NETWORK_LOOKUP_ROLES"))...))
+	handler.RegisterNetworkRoutes(networkGroup, service.NewNetworkLookup(registry, networkTimeout), analyticsRepo)
+
+	// refresh HIS-sourced patients older than their hospital's reconcile TTL
+	reconciler := service.NewReconciler(patientRepo, uow, registry)
+	if interval := reconcileInterval(os.Getenv("RECONCILE_INTERVAL")); interval > 0 {
+		go reconciler.Run(ctx, interval)
 	}
+	adminGroup := authGroup.Group("/", middleware.RequireRole("admin"))
+	handler.RegisterReconcilerRoutes(adminGroup, reconciler)
+	handler.RegisterHISLimitRoutes(adminGroup, registry)
+	// HIS records that failed validation, reviewed by the hospital's admins
+	handler.RegisterQuarantineRoutes(adminGroup, service.NewQuarantineReview(repository.NewQuarantineRepo(pool), uow, registry))
+	// field writes refused by the hospital's survivorship policy
+	handler.RegisterConflictRoutes(adminGroup, service.NewConflictReview(repository.NewFieldConflictRepo(pool), uow))
+	handler.RegisterLinkageRoutes(adminGroup, linker)
 
-	// WRITE routes (POST /v1/patients) use repo directly and do NOT depend on adapter
-	handler.RegisterPatientWriteRoutes(authGroup, patientRepo)
+	// webhook subscription management (scoped by hospital in token); a
+	// subscription receives the hospital's patient data, so admins only
+	handler.RegisterWebhookRoutes(adminGroup, webhookMgr)
 
-	// 5) Start server
+	// SSE stream of patient changes for the caller's hospital
+	feed := outbox.NewFeed(broker, repository.NewOutboxRepo(pool))
+	handler.RegisterPatientEventRoutes(authGroup, feed, 15*time.Second, ctx.Done())
+
+	// 6) Start server; on shutdown, streams close via ctx and in-flight requests get 10s to finish
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("failed to run server: %v", err)
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed to run server: %v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("shutting down server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("server shutdown: %v", err)
 	}
 }
 
@@ -113,6 +189,126 @@ func mustCreateDBPoolWithRetry(ctx context.Context) *pgxpool.Pool {
 	return nil // unreachable
 }
 
+// mustBuildLinkageEngine builds the record linkage engine from path (JSON,
+// see config/linkage.example.json) or linkage.DefaultConfig when path is empty.
+func mustBuildLinkageEngine(path string) *linkage.Engine {
+	cfg := linkage.DefaultConfig()
+	if path != "" {
+		var err error
+		if cfg, err = linkage.LoadConfig(path); err != nil {
+			log.Fatalf("could not load linkage config: %v", err)
+		}
+	}
+	engine, err := linkage.New(cfg)
+	if err != nil {
+		log.Fatalf("invalid linkage config: %v", err)
+	}
+	return engine
+}
+
+// mustBuildRegistry loads HIS configs from HIS_REGISTRY_FILE or, if unset, from
+// the hospital_systems table. HOSPITAL_BASE (+ HOSPITAL_BASE_ID) registers a
+// single legacy hospital when no other config exists.
+func mustBuildRegistry(ctx context.Context, pool *pgxpool.Pool) *adapter.Registry {
+	var (
+		configs []adapter.HospitalConfig
+		err     error
+	)
+	if path := os.Getenv("HIS_REGISTRY_FILE"); path != "" {
+		configs, err = adapter.LoadRegistryFile(path)
+		if err != nil {
+			log.Fatalf("could not load HIS registry: %v", err)
+		}
+	} else {
+		rows, err := repository.NewHospitalSystemRepo(pool).ListEnabled(ctx)
+		if err != nil {
+			log.Printf("warning: could not read hospital_systems: %v", err)
+		}
+		for _, row := range rows {
+			cfg, err := adapter.ParseHospitalConfig(row.HospitalID, row.Config)
+			if err != nil {
+				log.Fatalf("could not load HIS registry: %v", err)
+			}
+			configs = append(configs, cfg)
+		}
+	}
+
+	if len(configs) == 0 {
+		if base := os.Getenv("HOSPITAL_BASE"); base != "" {
+			hid := os.Getenv("HOSPITAL_BASE_ID")
+			if hid == "" {
+				hid = "HIS-1"
+			}
+			log.Printf("HIS registry empty; using legacy HOSPITAL_BASE for %s", hid)
+			configs = append(configs, adapter.HospitalConfig{HospitalID: hid, BaseURL: base})
+		}
+	}
+
+	opts := []adapter.RegistryOption{
+		adapter.WithRetryObserver(logHISRetry),
+		adapter.WithBreakerObserver(func(hospitalID string, from, to adapter.BreakerState) {
+			log.Printf("HIS circuit: hospital=%s %s -> %s", hospitalID, from, to)
+		}),
+	}
+	// HIS_CACHE_SIZE=0 disables the lookup cache
+	cacheSize := 10000
+	if v, err := strconv.Atoi(os.Getenv("HIS_CACHE_SIZE")); err == nil && v >= 0 {
+		cacheSize = v
+	}
+	if cacheSize > 0 {
+		opts = append(opts, adapter.WithCache(adapter.NewLRUCache(cacheSize)))
+	}
+
+	registry, err := adapter.NewRegistry(configs, opts...)
+	if err != nil {
+		log.Fatalf("could not build HIS registry: %v", err)
+	}
+	return registry
+}
+
+// logHISRetry logs HIS retry decisions; a metrics exporter can hook in here too.
+func logHISRetry(ev adapter.RetryEvent) {
+	log.Printf("HIS retry: hospital=%s attempt=%d outcome=%s status=%d delay=%s err=%v",
+		ev.HospitalID, ev.Attempt, ev.Outcome, ev.StatusCode, ev.Delay, ev.Err)
+}
+
+// startHL7Listeners starts one MLLP listener per "HOSPITAL_ID=addr" entry of
+// spec (comma separated, e.g. "HIS-4=:2575,HIS-5=:2576").
+func startHL7Listeners(ctx context.Context, spec string, archive hl7.Archiver, writer hl7.PatientWriter) {
+	listeners, err := parseHL7Listeners(spec)
+	if err != nil {
+		log.Fatalf("invalid HL7_LISTENERS: %v", err)
+	}
+	for hid, addr := range listeners {
+		srv := hl7.NewServer(hid, archive, writer)
+		go func() {
+			log.Printf("HL7 MLLP listener for %s on %s", hid, addr)
+			if err := srv.ListenAndServe(ctx, addr); err != nil {
+				log.Fatalf("HL7 listener for %s: %v", hid, err)
+			}
+		}()
+	}
+}
+
+func parseHL7Listeners(spec string) (map[string]string, error) {
+	out := map[string]string{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		hid, addr, ok := strings.Cut(item, "=")
+		if !ok || hid == "" || addr == "" {
+			return nil, fmt.Errorf("entry %q: want HOSPITAL_ID=addr", item)
+		}
+		if _, dup := out[hid]; dup {
+			return nil, fmt.Errorf("hospital %s listed twice", hid)
+		}
+		out[hid] = addr
+	}
+	return out, nil
+}
+
 func healthHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"status": "ok"})
 }
@@ -127,15 +323,29 @@ func searchHandler(c *gin.Context) {
 	c.JSON(200, gin.H{"query": q, "results": results})
 }
 
-// dbUnavailableService returns errors when DB or adapter not available.
-type dbUnavailableService struct {
-	err error
-}
-
-func (s *dbUnavailableService) Get(_ context.Context, _ string) (*repository.Patient, error) {
-	return nil, s.err
+// networkLookupRoles parses NETWORK_LOOKUP_ROLES ("admin,er"); default "admin".
+func networkLookupRoles(spec string) []string {
+	var roles []string
+	for _, r := range strings.Split(spec, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			roles = append(roles, r)
+		}
+	}
+	if len(roles) == 0 {
+		return []string{"admin"}
+	}
+	return roles
 }
 
-func (s *dbUnavailableService) Search(_ context.Context, _ string, _ repository.PatientFilters, _ int, _ int) ([]*repository.Patient, int, error) {
-	return nil, 0, s.err
+// reconcileInterval parses RECONCILE_INTERVAL; default 10m, "0" disables the reconciler.
+func reconcileInterval(spec string) time.Duration {
+	if spec == "" {
+		return 10 * time.Minute
+	}
+	d, err := time.ParseDuration(spec)
+	if err != nil {
+		log.Printf("invalid RECONCILE_INTERVAL %q; reconciler disabled", spec)
+		return 0
+	}
+	return d
 }
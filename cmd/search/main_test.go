@@ -36,3 +36,14 @@ func TestSearchMissingQuery(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 	assert.Contains(t, w.Body.String(), "q is required")
 }
+
+func TestParseHL7Listeners(t *testing.T) {
+	got, err := parseHL7Listeners(" HIS-4=:2575, HIS-5=127.0.0.1:2576 ,")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"HIS-4": ":2575", "HIS-5": "127.0.0.1:2576"}, got)
+
+	_, err = parseHL7Listeners("HIS-4")
+	assert.Error(t, err)
+	_, err = parseHL7Listeners("HIS-4=:1,HIS-4=:2")
+	assert.Error(t, err)
+}
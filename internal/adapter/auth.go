@@ -0,0 +1,279 @@
+package adapter
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth types for AuthConfig.Type.
+const (
+	AuthNone   = ""
+	AuthAPIKey = "api_key" // static header, e.g. X-API-Key
+	AuthOAuth2 = "oauth2"  // client credentials grant, bearer token cached until expiry
+	AuthMTLS   = "mtls"    // client certificate only
+)
+
+// AuthConfig describes how the adapter authenticates to one HIS.
+// CertFile/KeyFile (and CAFile) enable mutual TLS with any Type.
+type AuthConfig struct {
+	Type string `json:"type"`
+
+	// api_key
+	Header string `json:"header,omitempty"` // defaults to X-API-Key
+	Key    Secret `json:"key,omitempty"`
+
+	// oauth2 client credentials
+	TokenURL     string   `json:"token_url,omitempty"`
+	ClientID     string   `json:"client_id,omitempty"`
+	ClientSecret Secret   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	// mutual TLS
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	CAFile   string `json:"ca_file,omitempty"` // extra roots for the HIS server certificate
+}
+
+// Secret holds a credential. It prints and marshals as "[REDACTED]" so it
+// cannot leak through logs or error messages. In config files a value of the
+// form "env:NAME" is read from the environment variable NAME.
+type Secret string
+
+const redacted = "[REDACTED]"
+
+func (s Secret) String() string   { return redacted }
+func (s Secret) GoString() string { return redacted }
+
+// Reveal returns the actual credential; only the transport should call it.
+func (s Secret) Reveal() string { return string(s) }
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redacted)
+}
+
+func (s *Secret) UnmarshalJSON(b []byte) error {
+	var v string
+	if err := json.Unmarshal(b, &v); err != nil {
+		return errors.New("secret must be a string")
+	}
+	if name, ok := strings.CutPrefix(v, "env:"); ok {
+		v = os.Getenv(name)
+		if v == "" {
+			return fmt.Errorf("secret env var %s is empty", name)
+		}
+	}
+	*s = Secret(v)
+	return nil
+}
+
+func (a *AuthConfig) validate() error {
+	if a == nil {
+		return nil
+	}
+	switch a.Type {
+	case AuthNone:
+	case AuthAPIKey:
+		if a.Key == "" {
+			return errors.New("auth api_key: key is required")
+		}
+	case AuthOAuth2:
+		if a.TokenURL == "" || a.ClientID == "" || a.ClientSecret == "" {
+			return errors.New("auth oauth2: token_url, client_id and client_secret are required")
+		}
+	case AuthMTLS:
+		if a.CertFile == "" || a.KeyFile == "" {
+			return errors.New("auth mtls: cert_file and key_file are required")
+		}
+	default:
+		return fmt.Errorf("auth: unknown type %q", a.Type)
+	}
+	if (a.CertFile == "") != (a.KeyFile == "") {
+		return errors.New("auth: cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// transport builds the RoundTripper for a, on top of a clone of http.DefaultTransport.
+func (a *AuthConfig) transport() (http.RoundTripper, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if a == nil {
+		return base, nil
+	}
+
+	if a.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(a.CertFile, a.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tc := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		if a.CAFile != "" {
+			pem, err := os.ReadFile(a.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("read ca file: %w", err)
+			}
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("ca file %s has no certificates", a.CAFile)
+			}
+			tc.RootCAs = pool
+		}
+		base.TLSClientConfig = tc
+	}
+
+	switch a.Type {
+	case AuthAPIKey:
+		header := a.Header
+		if header == "" {
+			header = "X-API-Key"
+		}
+		return &apiKeyTransport{next: base, header: header, key: a.Key}, nil
+	case AuthOAuth2:
+		// the token endpoint is reached through the same (possibly mTLS) transport
+		return &oauth2Transport{next: base, tokens: newTokenSource(a, &http.Client{Transport: base, Timeout: 10 * time.Second})}, nil
+	}
+	return base, nil
+}
+
+type apiKeyTransport struct {
+	next   http.RoundTripper
+	header string
+	key    Secret
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Header.Set(t.header, t.key.Reveal())
+	return t.next.RoundTrip(r)
+}
+
+// oauth2Transport adds a bearer token and refreshes it once if the HIS answers 401.
+type oauth2Transport struct {
+	next   http.RoundTripper
+	tokens *tokenSource
+}
+
+func (t *oauth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	tok, err := t.tokens.token(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req, tok)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || req.Body != nil {
+		return resp, err
+	}
+
+	// the token may have been revoked early: fetch a new one and try once more
+	_ = resp.Body.Close()
+	t.tokens.invalidate(tok)
+	if tok, err = t.tokens.token(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.send(req, tok)
+}
+
+func (t *oauth2Transport) send(req *http.Request, tok string) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.Header.Set("Authorization", "Bearer "+tok)
+	return t.next.RoundTrip(r)
+}
+
+// tokenSource fetches and caches client-credentials access tokens.
+type tokenSource struct {
+	cfg    *AuthConfig
+	client *http.Client
+	now    func() time.Time
+
+	mu      sync.Mutex
+	access  string
+	expires time.Time
+}
+
+// tokenExpirySkew refreshes tokens a little before the server expires them.
+const tokenExpirySkew = 30 * time.Second
+
+func newTokenSource(cfg *AuthConfig, client *http.Client) *tokenSource {
+	return &tokenSource{cfg: cfg, client: client, now: time.Now}
+}
+
+func (s *tokenSource) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.access != "" && s.now().Before(s.expires) {
+		return s.access, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("oauth2 token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret.Reveal()))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", &transientError{fmt.Errorf("oauth2 token request: %w", err)}
+	}
+	defer resp.Body.Close()
+
+	// the body is not included in errors: token endpoints may echo credentials
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		err := fmt.Errorf("oauth2 token endpoint status %d", resp.StatusCode)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return "", &transientError{err}
+		}
+		return "", &authError{err}
+	}
+	var tr struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&tr); err != nil {
+		return "", &authError{errors.New("oauth2 token endpoint: invalid response")}
+	}
+	if tr.AccessToken == "" {
+		return "", &authError{errors.New("oauth2 token endpoint: no access_token")}
+	}
+
+	ttl := time.Duration(tr.ExpiresIn) * time.Second
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	if ttl > 2*tokenExpirySkew {
+		ttl -= tokenExpirySkew
+	}
+	s.access, s.expires = tr.AccessToken, s.now().Add(ttl)
+	return s.access, nil
+}
+
+// authError is a credential problem; retrying the same request will not help.
+type authError struct{ err error }
+
+func (e *authError) Error() string { return e.err.Error() }
+func (e *authError) Unwrap() error { return e.err }
+
+// invalidate drops tok if it is still the cached token.
+func (s *tokenSource) invalidate(tok string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.access == tok {
+		s.access = ""
+	}
+}
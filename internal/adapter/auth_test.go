@@ -0,0 +1,214 @@
+package adapter
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret_IsRedacted(t *testing.T) {
+	var cfg HospitalConfig
+	err := json.Unmarshal([]byte(`{"hospital_id":"HIS-1","base_url":"http://x",
+		"auth":{"type":"oauth2","token_url":"http://x/token","client_id":"agnos","client_secret":"s3cr3t"}}`), &cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cr3t", cfg.Auth.ClientSecret.Reveal())
+
+	out, _ := json.Marshal(cfg)
+	for _, s := range []string{
+		fmt.Sprintf("%v", cfg), fmt.Sprintf("%+v", *cfg.Auth), fmt.Sprintf("%#v", *cfg.Auth),
+		fmt.Sprintf("%s %q", cfg.Auth.ClientSecret, cfg.Auth.ClientSecret), string(out),
+	} {
+		assert.NotContains(t, s, "s3cr3t")
+	}
+}
+
+func TestSecret_FromEnv(t *testing.T) {
+	t.Setenv("HIS2_KEY", "from-env")
+	var s Secret
+	assert.NoError(t, json.Unmarshal([]byte(`"env:HIS2_KEY"`), &s))
+	assert.Equal(t, "from-env", s.Reveal())
+	assert.Error(t, json.Unmarshal([]byte(`"env:HIS2_MISSING"`), &s))
+}
+
+func TestAuth_APIKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-HIS-Key") != "k-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"national_id":"N-1"}`))
+	}))
+	defer ts.Close()
+
+	h, err := NewHospitalAdapterFromConfig(HospitalConfig{
+		HospitalID: "HIS-1", BaseURL: ts.URL,
+		Auth: &AuthConfig{Type: AuthAPIKey, Header: "X-HIS-Key", Key: "k-1"},
+	})
+	assert.NoError(t, err)
+	p, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+}
+
+func TestAuth_OAuth2CachesAndRefreshesToken(t *testing.T) {
+	var issued int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "agnos" || secret != "s3cr3t" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"invalid_client","client_secret":"s3cr3t"}`))
+			return
+		}
+		n := atomic.AddInt32(&issued, 1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": fmt.Sprintf("tok-%d", n), "expires_in": 3600})
+	})
+	var revoked atomic.Value
+	revoked.Store("")
+	mux.HandleFunc("/patient/search/", func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if auth == "" || auth == "Bearer "+revoked.Load().(string) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"national_id":"N-1"}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	auth := &AuthConfig{Type: AuthOAuth2, TokenURL: ts.URL + "/token", ClientID: "agnos", ClientSecret: "s3cr3t"}
+	h, err := NewHospitalAdapterFromConfig(HospitalConfig{HospitalID: "HIS-1", BaseURL: ts.URL, Auth: auth})
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err = h.LookupByIdentifier(ctx, "N-1")
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&issued), "token is cached")
+
+	// a 401 with the cached token triggers exactly one refresh
+	revoked.Store("tok-1")
+	_, err = h.LookupByIdentifier(ctx, "N-1")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&issued))
+
+	// bad credentials fail without leaking the secret or being retried
+	auth.ClientSecret = "wrong"
+	h, err = NewHospitalAdapterFromConfig(HospitalConfig{HospitalID: "HIS-1", BaseURL: ts.URL, Auth: auth})
+	assert.NoError(t, err)
+	_, err = h.LookupByIdentifier(ctx, "N-1")
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "s3cr3t")
+		assert.NotContains(t, err.Error(), "wrong")
+	}
+}
+
+func TestTokenSource_RefreshesBeforeExpiry(t *testing.T) {
+	var issued int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&issued, 1)
+		_, _ = w.Write([]byte(`{"access_token":"t","expires_in":120}`))
+	}))
+	defer ts.Close()
+
+	src := newTokenSource(&AuthConfig{TokenURL: ts.URL, ClientID: "a", ClientSecret: "b"}, ts.Client())
+	now := time.Now()
+	src.now = func() time.Time { return now }
+
+	_, _ = src.token(context.Background())
+	now = now.Add(80 * time.Second)
+	_, _ = src.token(context.Background())
+	assert.EqualValues(t, 1, atomic.LoadInt32(&issued))
+
+	now = now.Add(20 * time.Second) // within the expiry skew
+	_, _ = src.token(context.Background())
+	assert.EqualValues(t, 2, atomic.LoadInt32(&issued))
+}
+
+func TestAuth_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, clientCert := writeTestCert(t, dir)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"national_id":"N-1"}`))
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(clientCert)
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}), 0o600))
+
+	h, err := NewHospitalAdapterFromConfig(HospitalConfig{
+		HospitalID: "HIS-1", BaseURL: ts.URL,
+		Auth: &AuthConfig{Type: AuthMTLS, CertFile: certFile, KeyFile: keyFile, CAFile: caFile},
+	})
+	assert.NoError(t, err)
+	p, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+
+	// without the client certificate the handshake is refused
+	h, err = NewHospitalAdapterFromConfig(HospitalConfig{HospitalID: "HIS-1", BaseURL: ts.URL})
+	assert.NoError(t, err)
+	h.retry.MaxAttempts = 1
+	_, err = h.LookupByIdentifier(context.Background(), "N-1")
+	assert.Error(t, err)
+}
+
+func TestAuthConfig_Validate(t *testing.T) {
+	assert.Error(t, (&AuthConfig{Type: AuthAPIKey}).validate())
+	assert.Error(t, (&AuthConfig{Type: AuthOAuth2, TokenURL: "http://x"}).validate())
+	assert.Error(t, (&AuthConfig{Type: AuthMTLS, CertFile: "c.pem"}).validate())
+	assert.Error(t, (&AuthConfig{Type: "kerberos"}).validate())
+	assert.NoError(t, (&AuthConfig{Type: AuthAPIKey, Key: "k"}).validate())
+}
+
+// writeTestCert writes a self-signed client certificate and key as PEM files.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "agnos-search"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client-key.pem")
+	assert.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile, cert
+}
@@ -0,0 +1,258 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ErrHISUnavailable is returned when a hospital's HIS failed or its circuit is open.
+// Callers should fall back to what the local DB has.
+var ErrHISUnavailable = errors.New("HIS unavailable")
+
+// BreakerState is the state of a hospital's circuit breaker.
+type BreakerState int
+
+const (
+	StateClosed   BreakerState = iota // calls go through; failures are counted
+	StateOpen                         // calls fail fast with ErrHISUnavailable until Cooldown passes
+	StateHalfOpen                     // one probe call decides between closed and open
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// BreakerConfig controls when a hospital's circuit opens.
+// The circuit opens once at least MinRequests calls were made within Window
+// and FailureRate (0..1) or more of them failed.
+type BreakerConfig struct {
+	Window      Duration `json:"window"`
+	MinRequests int      `json:"min_requests"`
+	FailureRate float64  `json:"failure_rate"`
+	Cooldown    Duration `json:"cooldown"`
+}
+
+// DefaultBreakerConfig is used when a hospital has no breaker config.
+var DefaultBreakerConfig = BreakerConfig{
+	Window:      Duration(30 * time.Second),
+	MinRequests: 5,
+	FailureRate: 0.5,
+	Cooldown:    Duration(15 * time.Second),
+}
+
+// withDefaults fills unset fields from DefaultBreakerConfig.
+func (c *BreakerConfig) withDefaults() BreakerConfig {
+	out := DefaultBreakerConfig
+	if c == nil {
+		return out
+	}
+	if c.Window > 0 {
+		out.Window = c.Window
+	}
+	if c.MinRequests > 0 {
+		out.MinRequests = c.MinRequests
+	}
+	if c.FailureRate > 0 {
+		out.FailureRate = c.FailureRate
+	}
+	if c.Cooldown > 0 {
+		out.Cooldown = c.Cooldown
+	}
+	return out
+}
+
+// breakerBuckets splits the failure-rate window so old outcomes age out gradually.
+const breakerBuckets = 10
+
+type breakerBucket struct {
+	start         time.Time
+	total, failed int
+}
+
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored // the caller went away; says nothing about the HIS
+)
+
+// Breaker is a closed / open / half-open circuit breaker with a rolling failure-rate window.
+type Breaker struct {
+	mu       sync.Mutex
+	cfg      BreakerConfig
+	state    BreakerState
+	openedAt time.Time
+	probing  bool // half-open probe in flight
+	buckets  [breakerBuckets]breakerBucket
+
+	now      func() time.Time
+	onChange func(from, to BreakerState)
+}
+
+// NewBreaker constructs a closed Breaker. Unset config fields use DefaultBreakerConfig.
+func NewBreaker(cfg BreakerConfig) *Breaker {
+	return &Breaker{cfg: (&cfg).withDefaults(), now: time.Now}
+}
+
+// State reports the current state; an open circuit whose cooldown passed reads as half-open.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= time.Duration(b.cfg.Cooldown) {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// allow decides whether a call may proceed.
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Sub(b.openedAt) < time.Duration(b.cfg.Cooldown) {
+			return false
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return true
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// record reports the outcome of a call that allow let through.
+func (b *Breaker) record(o outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+		switch o {
+		case outcomeSuccess:
+			b.buckets = [breakerBuckets]breakerBucket{}
+			b.setState(StateClosed)
+		case outcomeFailure:
+			b.openedAt = b.now()
+			b.setState(StateOpen)
+		}
+		return
+	}
+	if o == outcomeIgnored || b.state != StateClosed {
+		return
+	}
+
+	now := b.now()
+	width := time.Duration(b.cfg.Window) / breakerBuckets
+	start := now.Truncate(width)
+	bk := &b.buckets[(start.UnixNano()/int64(width))%breakerBuckets]
+	if !bk.start.Equal(start) {
+		*bk = breakerBucket{start: start}
+	}
+	bk.total++
+	if o == outcomeFailure {
+		bk.failed++
+	}
+
+	total, failed := 0, 0
+	cutoff := now.Add(-time.Duration(b.cfg.Window))
+	for _, x := range b.buckets {
+		if x.start.After(cutoff) {
+			total += x.total
+			failed += x.failed
+		}
+	}
+	if total >= b.cfg.MinRequests && float64(failed)/float64(total) >= b.cfg.FailureRate {
+		b.openedAt = now
+		b.setState(StateOpen)
+	}
+}
+
+// setState must be called with mu held.
+func (b *Breaker) setState(to BreakerState) {
+	from := b.state
+	b.state = to
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
+
+// breakerClient guards a HospitalClient with a Breaker.
+type breakerClient struct {
+	hospitalID string
+	next       HospitalClient
+	breaker    *Breaker
+}
+
+// LookupByIdentifier implements HospitalClient. Every failure is reported as
+// ErrHISUnavailable (wrapping the cause) so callers can degrade the same way
+// whether the HIS just failed or the circuit is open.
+func (c *breakerClient) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	var p *repository.Patient
+	err := c.guard(ctx, func() error {
+		var err error
+		p, err = c.next.LookupByIdentifier(ctx, identifier)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// SearchDemographics implements HospitalClient; failures count like lookups.
+func (c *breakerClient) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	var out []*repository.Patient
+	err := c.guard(ctx, func() error {
+		var err error
+		out, err = c.next.SearchDemographics(ctx, q)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// guard runs call if the breaker allows it and records the outcome.
+func (c *breakerClient) guard(ctx context.Context, call func() error) error {
+	if !c.breaker.allow() {
+		return fmt.Errorf("%w: circuit open for %s", ErrHISUnavailable, c.hospitalID)
+	}
+	err := call()
+	if errors.Is(err, ErrAmbiguousMatch) {
+		// the HIS answered; the caller has to disambiguate
+		c.breaker.record(outcomeSuccess)
+		return err
+	}
+	if err != nil {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			c.breaker.record(outcomeIgnored)
+			return err
+		}
+		c.breaker.record(outcomeFailure)
+		return fmt.Errorf("%w: %w", ErrHISUnavailable, err)
+	}
+	c.breaker.record(outcomeSuccess)
+	return nil
+}
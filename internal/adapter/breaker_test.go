@@ -0,0 +1,126 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+type stubClient struct {
+	err   error
+	calls int
+}
+
+func (s *stubClient) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &repository.Patient{NationalID: identifier}, nil
+}
+
+func (s *stubClient) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	return nil, nil
+}
+
+func newTestBreakerClient(next HospitalClient) (*breakerClient, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBreaker(BreakerConfig{
+		Window:      Duration(10 * time.Second),
+		MinRequests: 4,
+		FailureRate: 0.5,
+		Cooldown:    Duration(5 * time.Second),
+	})
+	b.now = func() time.Time { return now }
+	return &breakerClient{hospitalID: "HIS-1", next: next, breaker: b}, &now
+}
+
+func TestBreaker_OpensAndFailsFast(t *testing.T) {
+	stub := &stubClient{err: errors.New("connection refused")}
+	c, _ := newTestBreakerClient(stub)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		_, err := c.LookupByIdentifier(ctx, "N-1")
+		assert.ErrorIs(t, err, ErrHISUnavailable)
+	}
+	assert.Equal(t, StateOpen, c.breaker.State())
+
+	_, err := c.LookupByIdentifier(ctx, "N-1")
+	assert.ErrorIs(t, err, ErrHISUnavailable)
+	assert.Equal(t, 4, stub.calls, "open circuit must not call the HIS")
+}
+
+func TestBreaker_StaysClosedBelowFailureRate(t *testing.T) {
+	stub := &stubClient{}
+	c, _ := newTestBreakerClient(stub)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		_, err := c.LookupByIdentifier(ctx, "N-1")
+		assert.NoError(t, err)
+	}
+	stub.err = errors.New("boom")
+	_, _ = c.LookupByIdentifier(ctx, "N-1")
+	assert.Equal(t, StateClosed, c.breaker.State())
+}
+
+func TestBreaker_HalfOpenProbe(t *testing.T) {
+	stub := &stubClient{err: errors.New("timeout")}
+	c, now := newTestBreakerClient(stub)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		_, _ = c.LookupByIdentifier(ctx, "N-1")
+	}
+	assert.Equal(t, StateOpen, c.breaker.State())
+
+	// after the cooldown one failing probe re-opens the circuit
+	*now = now.Add(6 * time.Second)
+	assert.Equal(t, StateHalfOpen, c.breaker.State())
+	_, err := c.LookupByIdentifier(ctx, "N-1")
+	assert.ErrorIs(t, err, ErrHISUnavailable)
+	assert.Equal(t, 5, stub.calls)
+	assert.Equal(t, StateOpen, c.breaker.State())
+
+	// a successful probe closes it
+	*now = now.Add(6 * time.Second)
+	stub.err = nil
+	p, err := c.LookupByIdentifier(ctx, "N-1")
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+	assert.Equal(t, StateClosed, c.breaker.State())
+}
+
+func TestBreaker_HalfOpenAllowsSingleProbe(t *testing.T) {
+	b := NewBreaker(BreakerConfig{MinRequests: 1, Cooldown: Duration(time.Second)})
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	assert.True(t, b.allow())
+	b.record(outcomeFailure)
+	now = now.Add(2 * time.Second)
+
+	assert.True(t, b.allow(), "first caller probes")
+	assert.False(t, b.allow(), "others fail fast while the probe is in flight")
+	b.record(outcomeIgnored)
+	assert.True(t, b.allow(), "an abandoned probe frees the slot")
+}
+
+func TestBreaker_CallerCancellationIsNotAFailure(t *testing.T) {
+	stub := &stubClient{err: context.Canceled}
+	c, _ := newTestBreakerClient(stub)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 10; i++ {
+		_, err := c.LookupByIdentifier(ctx, "N-1")
+		assert.False(t, errors.Is(err, ErrHISUnavailable))
+	}
+	assert.Equal(t, StateClosed, c.breaker.State())
+}
@@ -0,0 +1,202 @@
+package adapter
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Cache stores HIS lookup results. Implementations must be safe for concurrent use.
+type Cache interface {
+	Get(key string) (CacheEntry, bool)
+	Set(key string, e CacheEntry, ttl time.Duration)
+	Delete(key string)
+}
+
+// CacheEntry is one cached lookup. A nil Patient records that the HIS answered "not found".
+type CacheEntry struct {
+	Patient *repository.Patient
+}
+
+// Invalidator drops cached lookups after a local write changed the answer.
+type Invalidator interface {
+	Invalidate(hospitalID string, identifiers ...string)
+}
+
+// CacheConfig sets the TTLs of one hospital's cached lookups.
+type CacheConfig struct {
+	HitTTL  Duration `json:"hit_ttl"`  // found patients
+	MissTTL Duration `json:"miss_ttl"` // "not found" answers
+}
+
+// DefaultCacheConfig is used when a hospital has no cache config.
+var DefaultCacheConfig = CacheConfig{
+	HitTTL:  Duration(5 * time.Minute),
+	MissTTL: Duration(30 * time.Second),
+}
+
+// withDefaults fills unset fields from DefaultCacheConfig.
+func (c *CacheConfig) withDefaults() CacheConfig {
+	out := DefaultCacheConfig
+	if c == nil {
+		return out
+	}
+	if c.HitTTL > 0 {
+		out.HitTTL = c.HitTTL
+	}
+	if c.MissTTL > 0 {
+		out.MissTTL = c.MissTTL
+	}
+	return out
+}
+
+type bypassCacheKey struct{}
+
+// BypassCache marks lookups made with the returned context as refreshes: a
+// cached answer is not used, the HIS is asked and its answer cached.
+func BypassCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// CacheKey scopes an identifier to a hospital so tenants never share entries.
+func CacheKey(hospitalID, identifier string) string {
+	return hospitalID + "|" + identifier
+}
+
+// LRUCache is an in-process Cache bounded by entry count; entries also expire by TTL.
+type LRUCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List // front = most recently used
+	items map[string]*list.Element
+	now   func() time.Time
+}
+
+type lruItem struct {
+	key       string
+	entry     CacheEntry
+	expiresAt time.Time
+}
+
+// NewLRUCache constructs an LRUCache holding at most size entries.
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+		now:   time.Now,
+	}
+}
+
+// Get implements Cache. Expired entries are dropped on access.
+func (c *LRUCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+	it := el.Value.(*lruItem)
+	if !c.now().Before(it.expiresAt) {
+		c.remove(el)
+		return CacheEntry{}, false
+	}
+	c.ll.MoveToFront(el)
+	return it.entry, true
+}
+
+// Set implements Cache, evicting the least recently used entry when full.
+func (c *LRUCache) Set(key string, e CacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	exp := c.now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		it := el.Value.(*lruItem)
+		it.entry, it.expiresAt = e, exp
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&lruItem{key: key, entry: e, expiresAt: exp})
+	for c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+// Delete implements Cache.
+func (c *LRUCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len returns the number of stored entries (expired ones included until touched).
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *LRUCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*lruItem).key)
+}
+
+// cachingClient answers repeated lookups from a Cache, except under
+// BypassCache. Errors are never cached.
+type cachingClient struct {
+	hospitalID string
+	next       HospitalClient
+	cache      Cache
+	cfg        CacheConfig
+}
+
+// LookupByIdentifier implements HospitalClient.
+func (c *cachingClient) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	key := CacheKey(c.hospitalID, identifier)
+	if !cacheBypassed(ctx) {
+		if e, ok := c.cache.Get(key); ok {
+			return clonePatient(e.Patient), nil
+		}
+	}
+
+	p, err := c.next.LookupByIdentifier(ctx, identifier)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		c.cache.Set(key, CacheEntry{}, time.Duration(c.cfg.MissTTL))
+		return nil, nil
+	}
+	c.cache.Set(key, CacheEntry{Patient: clonePatient(p)}, time.Duration(c.cfg.HitTTL))
+	return p, nil
+}
+
+// SearchDemographics implements HospitalClient. Searches are not cached: their
+// results change as patients register and the key space is unbounded.
+func (c *cachingClient) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	return c.next.SearchDemographics(ctx, q)
+}
+
+// clonePatient copies p so callers (which set ID / HospitalID) never mutate a cached value.
+func clonePatient(p *repository.Patient) *repository.Patient {
+	if p == nil {
+		return nil
+	}
+	cp := *p
+	return &cp
+}
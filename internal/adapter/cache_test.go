@@ -0,0 +1,131 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// countingClient returns out and counts calls.
+type countingClient struct {
+	out   *repository.Patient
+	calls int
+}
+
+func (c *countingClient) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	c.calls++
+	if c.out == nil {
+		return nil, nil
+	}
+	cp := *c.out
+	return &cp, nil
+}
+
+func (c *countingClient) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	return nil, nil
+}
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUCache(2)
+	c.Set("a", CacheEntry{}, time.Minute)
+	c.Set("b", CacheEntry{}, time.Minute)
+	_, _ = c.Get("a") // a is now most recent
+	c.Set("c", CacheEntry{}, time.Minute)
+
+	_, okA := c.Get("a")
+	_, okB := c.Get("b")
+	_, okC := c.Get("c")
+	assert.True(t, okA)
+	assert.False(t, okB)
+	assert.True(t, okC)
+	assert.Equal(t, 2, c.Len())
+}
+
+func TestLRUCache_Expires(t *testing.T) {
+	c := NewLRUCache(10)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	c.Set("a", CacheEntry{}, time.Second)
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+
+	now = now.Add(2 * time.Second)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.Len())
+}
+
+func TestCachingClient_HitsMissesAndTTLs(t *testing.T) {
+	cache := NewLRUCache(10)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	found := &countingClient{out: &repository.Patient{NationalID: "N-1"}}
+	hit := &cachingClient{hospitalID: "HIS-1", next: found, cache: cache,
+		cfg: CacheConfig{HitTTL: Duration(time.Minute), MissTTL: Duration(10 * time.Second)}}
+
+	p, err := hit.LookupByIdentifier(context.Background(), "N-1")
+	assert.NoError(t, err)
+	p.ID = "mutated by caller"
+	p, err = hit.LookupByIdentifier(context.Background(), "N-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "", p.ID, "cached value must not be shared with callers")
+	assert.Equal(t, 1, found.calls)
+
+	// not-found answers are cached too, with their own (shorter) TTL and per-hospital key
+	missing := &countingClient{}
+	miss := &cachingClient{hospitalID: "HIS-2", next: missing, cache: cache,
+		cfg: CacheConfig{HitTTL: Duration(time.Minute), MissTTL: Duration(10 * time.Second)}}
+
+	for i := 0; i < 3; i++ {
+		p, err = miss.LookupByIdentifier(context.Background(), "N-1")
+		assert.NoError(t, err)
+		assert.Nil(t, p)
+	}
+	assert.Equal(t, 1, missing.calls)
+
+	now = now.Add(30 * time.Second)
+	_, _ = miss.LookupByIdentifier(context.Background(), "N-1")
+	_, _ = hit.LookupByIdentifier(context.Background(), "N-1")
+	assert.Equal(t, 2, missing.calls, "negative entry expired")
+	assert.Equal(t, 1, found.calls, "hit entry still fresh")
+}
+
+func TestCachingClient_BypassCacheRefreshesEntry(t *testing.T) {
+	his := &countingClient{out: &repository.Patient{NationalID: "N-1", PhoneNumber: "0811111111"}}
+	c := &cachingClient{hospitalID: "HIS-1", next: his, cache: NewLRUCache(10), cfg: DefaultCacheConfig}
+
+	_, _ = c.LookupByIdentifier(context.Background(), "N-1")
+	his.out.PhoneNumber = "0899999999"
+
+	p, err := c.LookupByIdentifier(BypassCache(context.Background()), "N-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "0899999999", p.PhoneNumber)
+	assert.Equal(t, 2, his.calls)
+
+	// the refreshed answer is what plain lookups get next
+	p, _ = c.LookupByIdentifier(context.Background(), "N-1")
+	assert.Equal(t, "0899999999", p.PhoneNumber)
+	assert.Equal(t, 2, his.calls)
+}
+
+func TestRegistry_InvalidateDropsCachedLookup(t *testing.T) {
+	cache := NewLRUCache(10)
+	reg, err := NewRegistry([]HospitalConfig{{HospitalID: "HIS-1", BaseURL: "http://127.0.0.1:1"}}, WithCache(cache))
+	assert.NoError(t, err)
+
+	cache.Set(CacheKey("HIS-1", "N-1"), CacheEntry{}, time.Minute)
+	cache.Set(CacheKey("HIS-2", "N-1"), CacheEntry{}, time.Minute)
+
+	reg.Invalidate("HIS-1", "N-1", "")
+
+	_, ok := cache.Get(CacheKey("HIS-1", "N-1"))
+	assert.False(t, ok)
+	_, ok = cache.Get(CacheKey("HIS-2", "N-1"))
+	assert.True(t, ok, "other hospitals keep their entries")
+}
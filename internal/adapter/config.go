@@ -0,0 +1,257 @@
+package adapter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// HospitalConfig describes how to reach one hospital's HIS.
+type HospitalConfig struct {
+	HospitalID string   `json:"hospital_id"`
+	BaseURL    string   `json:"base_url"`
+	Timeout    Duration `json:"timeout"` // e.g. "2s"; defaults to DefaultTimeout
+
+	// Protocol is ProtocolAgnos (default) or ProtocolFHIR.
+	Protocol string `json:"protocol,omitempty"`
+	// FHIR overrides DefaultFHIRConfig (protocol "fhir" only).
+	FHIR *FHIRConfig `json:"fhir,omitempty"`
+
+	// Headers are sent with every request. Put credentials in Auth instead:
+	// header values are not redacted.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Auth configures API key, OAuth2 client credentials and/or mutual TLS.
+	Auth *AuthConfig `json:"auth,omitempty"`
+
+	// FieldMapping says where this HIS returns each standard field (see MappingSpec);
+	// "patient_hn": "hn" is shorthand for a plain key rename.
+	FieldMapping MappingSpec `json:"field_mapping,omitempty"`
+
+	// Retry overrides DefaultRetryPolicy for this hospital.
+	Retry *RetryConfig `json:"retry,omitempty"`
+
+	// Breaker overrides DefaultBreakerConfig for this hospital.
+	Breaker *BreakerConfig `json:"breaker,omitempty"`
+
+	// Cache overrides DefaultCacheConfig (only used when the registry has a cache).
+	Cache *CacheConfig `json:"cache,omitempty"`
+
+	// Reconcile overrides DefaultReconcileConfig for the background refresh.
+	Reconcile *ReconcileConfig `json:"reconcile,omitempty"`
+
+	// Freshness overrides DefaultFreshnessConfig for lookups of stored patients.
+	Freshness *FreshnessConfig `json:"freshness,omitempty"`
+
+	// Limits caps outbound calls to this HIS; unset means unlimited.
+	Limits *LimitConfig `json:"limits,omitempty"`
+
+	// Survivorship says which source keeps each field when local edits and
+	// HIS data disagree; unset fields keep the last write.
+	Survivorship SurvivorshipConfig `json:"survivorship,omitempty"`
+}
+
+// DefaultTimeout is used when a HospitalConfig has no timeout.
+const DefaultTimeout = 2 * time.Second
+
+// RegistryFile is the on-disk format of HIS_REGISTRY_FILE.
+type RegistryFile struct {
+	Hospitals []HospitalConfig `json:"hospitals"`
+}
+
+// LoadRegistryFile reads hospital configs from a JSON file.
+func LoadRegistryFile(path string) ([]HospitalConfig, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read registry file: %w", err)
+	}
+	var rf RegistryFile
+	if err := json.Unmarshal(b, &rf); err != nil {
+		return nil, fmt.Errorf("decode registry file %s: %w", path, err)
+	}
+	return rf.Hospitals, nil
+}
+
+// ParseHospitalConfig decodes one hospital config (e.g. a hospital_systems.config value).
+// hospitalID overrides any hospital_id inside the document.
+func ParseHospitalConfig(hospitalID string, raw []byte) (HospitalConfig, error) {
+	var cfg HospitalConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("decode config for %s: %w", hospitalID, err)
+	}
+	cfg.HospitalID = hospitalID
+	return cfg, nil
+}
+
+func (c HospitalConfig) validate() error {
+	if c.HospitalID == "" {
+		return errors.New("hospital_id is required")
+	}
+	if c.BaseURL == "" {
+		return fmt.Errorf("%s: base_url is required", c.HospitalID)
+	}
+	switch c.Protocol {
+	case "", ProtocolAgnos, ProtocolFHIR:
+	default:
+		return fmt.Errorf("%s: unknown protocol %q", c.HospitalID, c.Protocol)
+	}
+	if err := c.FieldMapping.Validate(); err != nil {
+		return fmt.Errorf("%s: %w", c.HospitalID, err)
+	}
+	if err := c.Auth.validate(); err != nil {
+		return fmt.Errorf("%s: %w", c.HospitalID, err)
+	}
+	if err := c.Freshness.validate(); err != nil {
+		return fmt.Errorf("%s: %w", c.HospitalID, err)
+	}
+	if err := c.Limits.validate(); err != nil {
+		return fmt.Errorf("%s: %w", c.HospitalID, err)
+	}
+	if err := c.Survivorship.validate(); err != nil {
+		return fmt.Errorf("%s: %w", c.HospitalID, err)
+	}
+	return nil
+}
+
+// ReconcileConfig controls how stored HIS patients of one hospital are refreshed.
+type ReconcileConfig struct {
+	TTL           Duration `json:"ttl"`             // refresh patients synced longer ago than this
+	RatePerSecond float64  `json:"rate_per_second"` // HIS lookups per second
+	BatchSize     int      `json:"batch_size"`      // patients per run
+	Disabled      bool     `json:"disabled,omitempty"`
+}
+
+// DefaultReconcileConfig is used when a hospital has no reconcile config.
+var DefaultReconcileConfig = ReconcileConfig{
+	TTL:           Duration(24 * time.Hour),
+	RatePerSecond: 2,
+	BatchSize:     100,
+}
+
+// withDefaults fills unset fields from DefaultReconcileConfig.
+func (c *ReconcileConfig) withDefaults() ReconcileConfig {
+	out := DefaultReconcileConfig
+	if c == nil {
+		return out
+	}
+	if c.TTL > 0 {
+		out.TTL = c.TTL
+	}
+	if c.RatePerSecond > 0 {
+		out.RatePerSecond = c.RatePerSecond
+	}
+	if c.BatchSize > 0 {
+		out.BatchSize = c.BatchSize
+	}
+	out.Disabled = c.Disabled
+	return out
+}
+
+// FreshnessConfig controls how a stored HIS patient is served on lookup.
+// Younger than SoftTTL it is served as is; older, it is served and refreshed
+// in the background; older than HardTTL it is refreshed before answering.
+type FreshnessConfig struct {
+	SoftTTL  Duration `json:"soft_ttl"`
+	HardTTL  Duration `json:"hard_ttl"`
+	Disabled bool     `json:"disabled,omitempty"` // never refresh on lookup
+}
+
+// DefaultFreshnessConfig is used when a hospital has no freshness config.
+var DefaultFreshnessConfig = FreshnessConfig{
+	SoftTTL: Duration(time.Hour),
+	HardTTL: Duration(24 * time.Hour),
+}
+
+// withDefaults fills unset fields from DefaultFreshnessConfig.
+func (c *FreshnessConfig) withDefaults() FreshnessConfig {
+	out := DefaultFreshnessConfig
+	if c == nil {
+		return out
+	}
+	if c.SoftTTL > 0 {
+		out.SoftTTL = c.SoftTTL
+	}
+	if c.HardTTL > 0 {
+		out.HardTTL = c.HardTTL
+	}
+	out.Disabled = c.Disabled
+	return out
+}
+
+// validate checks the effective TTLs, so a soft_ttl alone cannot exceed
+// the default hard_ttl (or the reverse).
+func (c *FreshnessConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	eff := c.withDefaults()
+	if eff.HardTTL < eff.SoftTTL {
+		return fmt.Errorf("freshness: hard_ttl (%s) must not be shorter than soft_ttl (%s)",
+			time.Duration(eff.HardTTL), time.Duration(eff.SoftTTL))
+	}
+	return nil
+}
+
+// Survivorship rules.
+const (
+	SurvivorLatest = "latest" // the last write wins (default)
+	SurvivorLocal  = "local"  // staff edits win over HIS data
+	SurvivorHIS    = "his"    // HIS data (lookups, reconciler, HL7) wins over staff edits
+)
+
+// SurvivorshipConfig maps standard fields to a survivorship rule, e.g.
+//
+//	"survivorship": {"phone_number": "local", "first_name_th": "his", "last_name_th": "his"}
+//
+// A value written by the winning source is only replaced by that source;
+// other writes to the field are kept for review as conflicts. Identifiers
+// cannot have a rule: they decide which patient a write is for.
+type SurvivorshipConfig map[string]string
+
+// Rule returns the field's rule, SurvivorLatest if it has none.
+func (c SurvivorshipConfig) Rule(field string) string {
+	if r, ok := c[field]; ok {
+		return r
+	}
+	return SurvivorLatest
+}
+
+func (c SurvivorshipConfig) validate() error {
+	known := make(map[string]bool, len(StandardFields))
+	for _, f := range StandardFields {
+		known[f] = true
+	}
+	for field, rule := range c {
+		switch {
+		case field == "national_id" || field == "passport_id":
+			return fmt.Errorf("survivorship: %s is an identifier and cannot have a rule", field)
+		case !known[field]:
+			return fmt.Errorf("survivorship: unknown field %q", field)
+		case rule != SurvivorLatest && rule != SurvivorLocal && rule != SurvivorHIS:
+			return fmt.Errorf("survivorship: %s: unknown rule %q", field, rule)
+		}
+	}
+	return nil
+}
+
+// Duration is a time.Duration that reads and writes JSON strings like "2s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"2s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
@@ -0,0 +1,432 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ErrAmbiguousMatch is returned when a HIS finds several different patients
+// for one identifier. It is an answer, not a HIS failure.
+var ErrAmbiguousMatch = errors.New("HIS returned more than one matching patient")
+
+// Protocols for HospitalConfig.Protocol.
+const (
+	ProtocolAgnos = "agnos" // GET /patient/search/{id} returning the standard JSON (see StandardFields), the default
+	ProtocolFHIR  = "fhir"  // HL7 FHIR R4 Patient search
+)
+
+// FHIRConfig names the identifier systems a FHIR server uses.
+// Identifiers are also recognised by their v2-0203 type code (NI, PPN, MR).
+type FHIRConfig struct {
+	NationalIDSystem string `json:"national_id_system,omitempty"`
+	PassportSystem   string `json:"passport_system,omitempty"` // prefix; per-country systems like ".../passport-THA" match
+	HNSystem         string `json:"hn_system,omitempty"`
+}
+
+// DefaultFHIRConfig is used for unset FHIRConfig fields.
+var DefaultFHIRConfig = FHIRConfig{
+	NationalIDSystem: "https://terminology.moph.go.th/CodeSystem/cid",
+	PassportSystem:   "http://hl7.org/fhir/sid/passport",
+}
+
+func (c *FHIRConfig) withDefaults() FHIRConfig {
+	out := DefaultFHIRConfig
+	if c == nil {
+		return out
+	}
+	if c.NationalIDSystem != "" {
+		out.NationalIDSystem = c.NationalIDSystem
+	}
+	if c.PassportSystem != "" {
+		out.PassportSystem = c.PassportSystem
+	}
+	out.HNSystem = c.HNSystem
+	return out
+}
+
+// FHIRAdapter implements HospitalClient against a FHIR R4 server
+// (GET [base]/Patient?identifier=system|value). Transport, auth, headers and retries
+// are the same as HospitalAdapter's.
+type FHIRAdapter struct {
+	http *HospitalAdapter
+	cfg  FHIRConfig
+}
+
+// NewFHIRAdapterFromConfig constructs a FHIRAdapter for one registry entry.
+func NewFHIRAdapterFromConfig(cfg HospitalConfig) (*FHIRAdapter, error) {
+	h, err := NewHospitalAdapterFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &FHIRAdapter{http: h, cfg: cfg.FHIR.withDefaults()}, nil
+}
+
+// minimal FHIR R4 shapes used by the adapter
+type fhirBundle struct {
+	ResourceType string `json:"resourceType"`
+	Entry        []struct {
+		Resource json.RawMessage `json:"resource"`
+		Search   struct {
+			Mode string `json:"mode"`
+		} `json:"search"`
+	} `json:"entry"`
+}
+
+type fhirPatient struct {
+	ResourceType string           `json:"resourceType"`
+	ID           string           `json:"id"`
+	Active       *bool            `json:"active"`
+	Identifier   []fhirIdentifier `json:"identifier"`
+	Name         []fhirHumanName  `json:"name"`
+	Telecom      []struct {
+		System string `json:"system"`
+		Value  string `json:"value"`
+		Use    string `json:"use"`
+	} `json:"telecom"`
+	Gender    string `json:"gender"`
+	BirthDate string `json:"birthDate"`
+	Link      []struct {
+		Type string `json:"type"`
+	} `json:"link"`
+}
+
+type fhirIdentifier struct {
+	System string `json:"system"`
+	Value  string `json:"value"`
+	Type   struct {
+		Coding []struct {
+			Code string `json:"code"`
+		} `json:"coding"`
+	} `json:"type"`
+}
+
+type fhirHumanName struct {
+	Use       string   `json:"use"`
+	Text      string   `json:"text"`
+	Family    string   `json:"family"`
+	Given     []string `json:"given"`
+	Extension []struct {
+		URL       string `json:"url"`
+		ValueCode string `json:"valueCode"`
+	} `json:"extension"`
+}
+
+const fhirLanguageExt = "http://hl7.org/fhir/StructureDefinition/language"
+
+// LookupByIdentifier implements HospitalClient.
+// Several matches are narrowed to active patients that were not replaced;
+// if more than one remains, ErrAmbiguousMatch is returned.
+func (f *FHIRAdapter) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	var p *repository.Patient
+	err := f.http.retry.do(ctx, f.http.hospitalID, http.MethodGet, func() error {
+		var err error
+		p, err = f.lookupOnce(ctx, identifier)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (f *FHIRAdapter) lookupOnce(ctx context.Context, identifier string) (*repository.Patient, error) {
+	u := *f.http.baseURL // copy
+	u.Path = path.Join(f.http.baseURL.Path, "Patient")
+	u.RawQuery = url.Values{"identifier": {f.identifierParam(identifier)}}.Encode()
+
+	body, err := f.http.get(ctx, u.String(), "application/fhir+json")
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	bundle, err := decodeBundle(body)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		raw json.RawMessage
+		fp  fhirPatient
+	}
+	var all, byID []match
+	for _, e := range bundle.Entry {
+		if e.Search.Mode != "" && e.Search.Mode != "match" {
+			continue // "include" / "outcome" entries
+		}
+		var fp fhirPatient
+		if err := json.Unmarshal(e.Resource, &fp); err != nil || fp.ResourceType != "Patient" {
+			continue
+		}
+		if !fp.current() {
+			continue
+		}
+		m := match{raw: e.Resource, fp: fp}
+		all = append(all, m)
+		// passports are searched without a system (it varies by country), so
+		// prefer national id / passport hits over e.g. an HN with the same value
+		for _, id := range fp.Identifier {
+			if kind := f.cfg.kind(id); id.Value == identifier && (kind == "national_id" || kind == "passport_id") {
+				byID = append(byID, m)
+				break
+			}
+		}
+	}
+	if len(byID) > 0 {
+		all = byID
+	}
+
+	switch len(all) {
+	case 0:
+		return nil, nil
+	case 1:
+		return f.toPatient(all[0].fp, all[0].raw), nil
+	}
+	return nil, fmt.Errorf("%w: %d patients for identifier", ErrAmbiguousMatch, len(all))
+}
+
+// identifierParam is the identifier search value: system|value for a Thai
+// citizen id, the bare value for passports, whose configured system is a
+// prefix of per-country systems and cannot be searched on.
+func (f *FHIRAdapter) identifierParam(identifier string) string {
+	if IsThaiCitizenID(identifier) {
+		return f.cfg.NationalIDSystem + "|" + identifier
+	}
+	return identifier
+}
+
+// IsThaiCitizenID reports whether id has the 13-digit citizen id shape.
+func IsThaiCitizenID(id string) bool {
+	if len(id) != 13 {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchDemographics implements HospitalClient with the standard Patient
+// search parameters (family, given, birthdate, phone, email; identifier for an HN).
+// Inactive and replaced records are dropped.
+func (f *FHIRAdapter) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	var out []*repository.Patient
+	err := f.http.retry.do(ctx, f.http.hospitalID, http.MethodGet, func() error {
+		var err error
+		out, err = f.searchOnce(ctx, q)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (f *FHIRAdapter) searchOnce(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	v := url.Values{}
+	add := func(k, val string) {
+		if val != "" {
+			v.Add(k, val)
+		}
+	}
+	add("family", q.LastName)
+	add("given", q.FirstName)
+	add("given", q.MiddleName)
+	add("birthdate", q.DateOfBirth)
+	add("phone", q.PhoneNumber)
+	add("email", q.Email)
+	if q.PatientHN != "" {
+		if f.cfg.HNSystem != "" {
+			v.Set("identifier", f.cfg.HNSystem+"|"+q.PatientHN)
+		} else {
+			v.Set("identifier", q.PatientHN)
+		}
+	}
+
+	u := *f.http.baseURL // copy
+	u.Path = path.Join(f.http.baseURL.Path, "Patient")
+	u.RawQuery = v.Encode()
+
+	body, err := f.http.get(ctx, u.String(), "application/fhir+json")
+	if err != nil || body == nil {
+		return nil, err
+	}
+	bundle, err := decodeBundle(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*repository.Patient
+	for _, e := range bundle.Entry {
+		if e.Search.Mode != "" && e.Search.Mode != "match" {
+			continue
+		}
+		var fp fhirPatient
+		if err := json.Unmarshal(e.Resource, &fp); err != nil || fp.ResourceType != "Patient" || !fp.current() {
+			continue
+		}
+		out = append(out, f.toPatient(fp, e.Resource))
+	}
+	return out, nil
+}
+
+func decodeBundle(body []byte) (*fhirBundle, error) {
+	var bundle fhirBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return nil, fmt.Errorf("decode bundle: %w", err)
+	}
+	if bundle.ResourceType != "Bundle" {
+		return nil, fmt.Errorf("decode bundle: unexpected resourceType %q", bundle.ResourceType)
+	}
+	return &bundle, nil
+}
+
+// current reports whether the resource is an active, not superseded record.
+func (fp fhirPatient) current() bool {
+	if fp.Active != nil && !*fp.Active {
+		return false
+	}
+	for _, l := range fp.Link {
+		if l.Type == "replaced-by" {
+			return false
+		}
+	}
+	return true
+}
+
+// kind classifies an identifier as national_id, passport_id, hn or "".
+func (c FHIRConfig) kind(id fhirIdentifier) string {
+	switch {
+	case c.NationalIDSystem != "" && id.System == c.NationalIDSystem:
+		return "national_id"
+	case c.PassportSystem != "" && strings.HasPrefix(id.System, c.PassportSystem):
+		return "passport_id"
+	case c.HNSystem != "" && id.System == c.HNSystem:
+		return "hn"
+	}
+	for _, cd := range id.Type.Coding {
+		switch cd.Code {
+		case "NI", "CZ":
+			return "national_id"
+		case "PPN":
+			return "passport_id"
+		case "MR":
+			return "hn"
+		}
+	}
+	return ""
+}
+
+func (f *FHIRAdapter) toPatient(fp fhirPatient, raw json.RawMessage) *repository.Patient {
+	p := &repository.Patient{RawJSON: []byte(raw)}
+
+	for _, id := range fp.Identifier {
+		switch f.cfg.kind(id) {
+		case "national_id":
+			setOnce(&p.NationalID, id.Value)
+		case "passport_id":
+			setOnce(&p.PassportID, id.Value)
+		case "hn":
+			setOnce(&p.PatientHN, id.Value)
+		}
+	}
+
+	if n := pickName(fp.Name, "th"); n != nil {
+		p.FirstNameTH, p.MiddleNameTH, p.LastNameTH = splitName(*n)
+	}
+	if n := pickName(fp.Name, "en"); n != nil {
+		p.FirstNameEN, p.MiddleNameEN, p.LastNameEN = splitName(*n)
+	}
+
+	for _, t := range fp.Telecom {
+		switch t.System {
+		case "phone":
+			setOnce(&p.PhoneNumber, t.Value)
+		case "email":
+			setOnce(&p.Email, t.Value)
+		}
+	}
+
+	switch fp.Gender {
+	case "male":
+		p.Gender = "M"
+	case "female":
+		p.Gender = "F"
+	}
+
+	// only full dates fit the date column; FHIR also allows "1985" and "1985-05"
+	if len(fp.BirthDate) == len("2006-01-02") {
+		dob := fp.BirthDate
+		p.DateOfBirth = &dob
+	}
+	return p
+}
+
+// pickName returns the best name in lang ("th" or "en"): official before usual
+// before anything else; old/maiden names are skipped.
+func pickName(names []fhirHumanName, lang string) *fhirHumanName {
+	rank := map[string]int{"official": 0, "usual": 1, "": 2, "nickname": 3}
+	var best *fhirHumanName
+	bestRank := 99
+	for i := range names {
+		n := &names[i]
+		r, ok := rank[n.Use]
+		if !ok || nameLanguage(*n) != lang {
+			continue
+		}
+		if r < bestRank {
+			best, bestRank = n, r
+		}
+	}
+	return best
+}
+
+// nameLanguage reads the language extension, falling back to the script used.
+func nameLanguage(n fhirHumanName) string {
+	for _, e := range n.Extension {
+		if e.URL == fhirLanguageExt && e.ValueCode != "" {
+			return strings.ToLower(strings.SplitN(e.ValueCode, "-", 2)[0])
+		}
+	}
+	for _, r := range n.Family + strings.Join(n.Given, "") + n.Text {
+		if unicode.Is(unicode.Thai, r) {
+			return "th"
+		}
+	}
+	return "en"
+}
+
+// splitName maps given[0] to first, the remaining given names to middle, family to last.
+// A name with only text is split on spaces.
+func splitName(n fhirHumanName) (first, middle, last string) {
+	given, family := n.Given, n.Family
+	if len(given) == 0 && family == "" && n.Text != "" {
+		parts := strings.Fields(n.Text)
+		if len(parts) > 1 {
+			given, family = parts[:len(parts)-1], parts[len(parts)-1]
+		} else {
+			given = parts
+		}
+	}
+	if len(given) > 0 {
+		first = given[0]
+		middle = strings.Join(given[1:], " ")
+	}
+	return first, middle, family
+}
+
+func setOnce(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
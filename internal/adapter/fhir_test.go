@@ -0,0 +1,204 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+const fhirPatientSomchai = `{
+  "resourceType": "Patient",
+  "id": "pt-1",
+  "active": true,
+  "identifier": [
+    {"system": "https://terminology.moph.go.th/CodeSystem/cid", "value": "1103700000001"},
+    {"type": {"coding": [{"system": "http://terminology.hl7.org/CodeSystem/v2-0203", "code": "MR"}]}, "system": "urn:hospital-b:hn", "value": "HN-0042"},
+    {"system": "http://hl7.org/fhir/sid/passport-THA", "value": "AA1234567"}
+  ],
+  "name": [
+    {"use": "old", "family": "Oldname", "given": ["Somchai"]},
+    {"use": "official", "family": "Jaidee", "given": ["Somchai", "Ken"],
+     "extension": [{"url": "http://hl7.org/fhir/StructureDefinition/language", "valueCode": "en"}]},
+    {"use": "official", "family": "ใจดี", "given": ["สมชาย"]}
+  ],
+  "telecom": [
+    {"system": "email", "value": "somchai@example.com"},
+    {"system": "phone", "value": "0812345678", "use": "mobile"}
+  ],
+  "gender": "male",
+  "birthDate": "1985-05-05"
+}`
+
+func fhirServer(t *testing.T, bundle string) (*httptest.Server, *string) {
+	t.Helper()
+	var query string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/fhir/Patient", r.URL.Path)
+		assert.Equal(t, "application/fhir+json", r.Header.Get("Accept"))
+		query = r.URL.Query().Get("identifier")
+		w.Header().Set("Content-Type", "application/fhir+json")
+		_, _ = w.Write([]byte(bundle))
+	}))
+	return ts, &query
+}
+
+func bundleOf(entries ...string) string {
+	type entry struct {
+		Resource json.RawMessage `json:"resource"`
+		Search   map[string]any  `json:"search"`
+	}
+	b := map[string]any{"resourceType": "Bundle", "type": "searchset"}
+	var es []entry
+	for _, e := range entries {
+		es = append(es, entry{Resource: json.RawMessage(e), Search: map[string]any{"mode": "match"}})
+	}
+	b["entry"] = es
+	out, _ := json.Marshal(b)
+	return string(out)
+}
+
+func newTestFHIR(t *testing.T, url string) *FHIRAdapter {
+	t.Helper()
+	f, err := NewFHIRAdapterFromConfig(HospitalConfig{HospitalID: "HIS-2", BaseURL: url + "/fhir", Protocol: ProtocolFHIR})
+	assert.NoError(t, err)
+	return f
+}
+
+func TestFHIRAdapter_MapsPatient(t *testing.T) {
+	ts, query := fhirServer(t, bundleOf(fhirPatientSomchai))
+	defer ts.Close()
+
+	p, err := newTestFHIR(t, ts.URL).LookupByIdentifier(context.Background(), "1103700000001")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://terminology.moph.go.th/CodeSystem/cid|1103700000001", *query)
+	if !assert.NotNil(t, p) {
+		return
+	}
+	assert.Equal(t, "1103700000001", p.NationalID)
+	assert.Equal(t, "AA1234567", p.PassportID)
+	assert.Equal(t, "HN-0042", p.PatientHN)
+	assert.Equal(t, "Somchai", p.FirstNameEN)
+	assert.Equal(t, "Ken", p.MiddleNameEN)
+	assert.Equal(t, "Jaidee", p.LastNameEN)
+	assert.Equal(t, "สมชาย", p.FirstNameTH)
+	assert.Equal(t, "ใจดี", p.LastNameTH)
+	assert.Equal(t, "0812345678", p.PhoneNumber)
+	assert.Equal(t, "somchai@example.com", p.Email)
+	assert.Equal(t, "M", p.Gender)
+	if assert.NotNil(t, p.DateOfBirth) {
+		assert.Equal(t, "1985-05-05", *p.DateOfBirth)
+	}
+	// RawJSON is the Patient resource, not the bundle
+	assert.JSONEq(t, fhirPatientSomchai, string(p.RawJSON))
+}
+
+func TestFHIRAdapter_IdentifierQuery(t *testing.T) {
+	var raw string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw = r.URL.RawQuery
+		_, _ = w.Write([]byte(bundleOf(fhirPatientSomchai)))
+	}))
+	defer ts.Close()
+
+	f, err := NewFHIRAdapterFromConfig(HospitalConfig{
+		HospitalID: "HIS-2", BaseURL: ts.URL + "/fhir", Protocol: ProtocolFHIR,
+		FHIR: &FHIRConfig{NationalIDSystem: "urn:oid:2.16.764.1.1"},
+	})
+	assert.NoError(t, err)
+
+	_, err = f.LookupByIdentifier(context.Background(), "1103700000001")
+	assert.NoError(t, err)
+	assert.Equal(t, "identifier=urn%3Aoid%3A2.16.764.1.1%7C1103700000001", raw, "citizen ids are qualified with the configured system")
+
+	_, err = f.LookupByIdentifier(context.Background(), "AA1234567")
+	assert.NoError(t, err)
+	assert.Equal(t, "identifier=AA1234567", raw, "passports are searched across passport systems")
+}
+
+func TestFHIRAdapter_EmptyBundleIsNotFound(t *testing.T) {
+	ts, _ := fhirServer(t, `{"resourceType":"Bundle","type":"searchset","total":0}`)
+	defer ts.Close()
+
+	p, err := newTestFHIR(t, ts.URL).LookupByIdentifier(context.Background(), "N-404")
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+}
+
+func TestFHIRAdapter_MultipleMatches(t *testing.T) {
+	inactive := `{"resourceType":"Patient","id":"old","active":false,
+		"identifier":[{"system":"https://terminology.moph.go.th/CodeSystem/cid","value":"1103700000001"}]}`
+	otherHN := `{"resourceType":"Patient","id":"pt-9",
+		"identifier":[{"type":{"coding":[{"code":"MR"}]},"value":"1103700000001"}],"gender":"female"}`
+
+	// an inactive duplicate and a patient whose HN happens to equal the value are ignored
+	ts, _ := fhirServer(t, bundleOf(fhirPatientSomchai, inactive, otherHN))
+	defer ts.Close()
+	p, err := newTestFHIR(t, ts.URL).LookupByIdentifier(context.Background(), "1103700000001")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "Somchai", p.FirstNameEN)
+	}
+
+	// two current patients with the same national id cannot be told apart
+	twin := `{"resourceType":"Patient","id":"pt-2",
+		"identifier":[{"system":"https://terminology.moph.go.th/CodeSystem/cid","value":"1103700000001"}]}`
+	ts2, _ := fhirServer(t, bundleOf(fhirPatientSomchai, twin))
+	defer ts2.Close()
+	p, err = newTestFHIR(t, ts2.URL).LookupByIdentifier(context.Background(), "1103700000001")
+	assert.ErrorIs(t, err, ErrAmbiguousMatch)
+	assert.Nil(t, p)
+}
+
+func TestRegistry_FHIRProtocol(t *testing.T) {
+	ts, _ := fhirServer(t, bundleOf(fhirPatientSomchai))
+	defer ts.Close()
+
+	reg, err := NewRegistry([]HospitalConfig{{HospitalID: "HIS-2", BaseURL: ts.URL + "/fhir", Protocol: ProtocolFHIR}})
+	assert.NoError(t, err)
+	c, err := reg.ClientFor("HIS-2")
+	assert.NoError(t, err)
+	p, err := c.LookupByIdentifier(context.Background(), "1103700000001")
+	assert.NoError(t, err)
+	assert.NotNil(t, p)
+
+	_, err = NewRegistry([]HospitalConfig{{HospitalID: "HIS-3", BaseURL: "http://x", Protocol: "soap"}})
+	assert.Error(t, err)
+}
+
+func TestNameLanguage_FallsBackToScript(t *testing.T) {
+	assert.Equal(t, "th", nameLanguage(fhirHumanName{Family: "ใจดี"}))
+	assert.Equal(t, "en", nameLanguage(fhirHumanName{Family: "Jaidee"}))
+	assert.Equal(t, "th", nameLanguage(fhirHumanName{Family: "Jaidee",
+		Extension: []struct {
+			URL       string `json:"url"`
+			ValueCode string `json:"valueCode"`
+		}{{URL: fhirLanguageExt, ValueCode: "th-TH"}}}))
+}
+
+func TestFHIRAdapter_SearchDemographics(t *testing.T) {
+	inactive := `{"resourceType": "Patient", "id": "pt-9", "active": false, "name": [{"family": "Jaidee", "given": ["Somchai"]}]}`
+	var got map[string][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/fhir/Patient", r.URL.Path)
+		got = r.URL.Query()
+		_, _ = w.Write([]byte(bundleOf(fhirPatientSomchai, inactive)))
+	}))
+	defer ts.Close()
+
+	out, err := newTestFHIR(t, ts.URL).SearchDemographics(context.Background(),
+		repository.PatientFilters{FirstName: "Somchai", LastName: "Jaidee", DateOfBirth: "1985-05-05"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jaidee"}, got["family"])
+	assert.Equal(t, []string{"Somchai"}, got["given"])
+	assert.Equal(t, []string{"1985-05-05"}, got["birthdate"])
+	if assert.Len(t, out, 1) {
+		assert.Equal(t, "1103700000001", out[0].NationalID)
+		assert.Equal(t, "Jaidee", out[0].LastNameEN)
+	}
+}
@@ -3,8 +3,10 @@ package adapter
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"net/url"
 	"path"
@@ -18,17 +20,25 @@ type HospitalClient interface {
 	// LookupByIdentifier queries the hospital API by national_id or passport_id.
 	// Returns (nil, nil) if hospital returns 404 (not found).
 	LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error)
+	// SearchDemographics queries the hospital API by name, date of birth, HN,
+	// phone or e-mail (identifier filters are ignored). No match is (nil, nil).
+	SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error)
 }
 
 // HospitalAdapter calls a hospital HTTP API and maps the response to repository.Patient.
 type HospitalAdapter struct {
-	baseURL    *url.URL
-	httpClient *http.Client
+	hospitalID   string
+	baseURL      *url.URL
+	httpClient   *http.Client
+	headers      map[string]string
+	fieldMapping MappingSpec
+	retry        RetryPolicy
 }
 
 // NewHospitalAdapter constructs a HospitalAdapter.
 // base := "https://hospital-a.api.co.th" (no trailing slash required).
-// timeout controls the HTTP client request timeout.
+// timeout controls the HTTP client timeout of a single attempt; transient
+// failures are retried with DefaultRetryPolicy.
 func NewHospitalAdapter(base string, timeout time.Duration) (*HospitalAdapter, error) {
 	u, err := url.Parse(base)
 	if err != nil {
@@ -40,44 +50,158 @@ func NewHospitalAdapter(base string, timeout time.Duration) (*HospitalAdapter, e
 	return &HospitalAdapter{
 		baseURL:    u,
 		httpClient: c,
+		retry:      DefaultRetryPolicy,
 	}, nil
 }
 
-// expected hospital response JSON structure (partial mapping)
-type hospitalResponse struct {
-	FirstNameTH  string `json:"first_name_th"`
-	MiddleNameTH string `json:"middle_name_th"`
-	LastNameTH   string `json:"last_name_th"`
-
-	FirstNameEN  string `json:"first_name_en"`
-	MiddleNameEN string `json:"middle_name_en"`
-	LastNameEN   string `json:"last_name_en"`
-
-	DateOfBirth string `json:"date_of_birth"`
-	PatientHN   string `json:"patient_hn"`
-
-	NationalID string `json:"national_id"`
-	PassportID string `json:"passport_id"`
+// NewHospitalAdapterFromConfig constructs a HospitalAdapter for one registry entry.
+func NewHospitalAdapterFromConfig(cfg HospitalConfig) (*HospitalAdapter, error) {
+	timeout := time.Duration(cfg.Timeout)
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	h, err := NewHospitalAdapter(cfg.BaseURL, timeout)
+	if err != nil {
+		return nil, err
+	}
+	rt, err := cfg.Auth.transport()
+	if err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
+	}
+	h.httpClient.Transport = rt
+	h.hospitalID = cfg.HospitalID
+	h.headers = cfg.Headers
+	h.fieldMapping = cfg.FieldMapping
+	h.retry = cfg.Retry.policy()
+	return h, nil
+}
 
-	PhoneNumber string `json:"phone_number"`
-	Email       string `json:"email"`
-	Gender      string `json:"gender"`
+// SetRetryPolicy replaces the adapter's retry policy.
+func (h *HospitalAdapter) SetRetryPolicy(p RetryPolicy) {
+	h.retry = p
 }
 
 // LookupByIdentifier implements HospitalClient.
+// Transient failures are retried according to the adapter's RetryPolicy.
 func (h *HospitalAdapter) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	var p *repository.Patient
+	err := h.retry.do(ctx, h.hospitalID, http.MethodGet, func() error {
+		var err error
+		p, err = h.lookupOnce(ctx, identifier)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// lookupOnce makes a single HTTP attempt.
+func (h *HospitalAdapter) lookupOnce(ctx context.Context, identifier string) (*repository.Patient, error) {
 	// build URL: base + /patient/search/{id}
 	u := *h.baseURL // copy
 	u.Path = path.Join(h.baseURL.Path, "patient", "search", identifier)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	body, err := h.get(ctx, u.String(), "application/json")
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	// field_mapping (if any) says where this HIS puts each standard field
+	p, ferrs, err := h.fieldMapping.Apply(body)
+	if err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	for _, fe := range ferrs {
+		// the value itself is patient data and stays out of the log
+		log.Printf("HIS mapping (hospital=%s): field %s: %v", h.hospitalID, fe.Field, fe.Err)
+	}
+	return p, nil
+}
+
+// SearchDemographics implements HospitalClient:
+// GET /patient/search?first_name=..&last_name=..&date_of_birth=.. returning
+// a JSON array (or {"results": [...]}) of objects in the lookup format.
+func (h *HospitalAdapter) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	var out []*repository.Patient
+	err := h.retry.do(ctx, h.hospitalID, http.MethodGet, func() error {
+		var err error
+		out, err = h.searchOnce(ctx, q)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (h *HospitalAdapter) searchOnce(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	u := *h.baseURL // copy
+	u.Path = path.Join(h.baseURL.Path, "patient", "search")
+	v := url.Values{}
+	for k, val := range map[string]string{
+		"patient_hn":    q.PatientHN,
+		"first_name":    q.FirstName,
+		"middle_name":   q.MiddleName,
+		"last_name":     q.LastName,
+		"date_of_birth": q.DateOfBirth,
+		"phone_number":  q.PhoneNumber,
+		"email":         q.Email,
+	} {
+		if val != "" {
+			v.Set(k, val)
+		}
+	}
+	u.RawQuery = v.Encode()
+
+	body, err := h.get(ctx, u.String(), "application/json")
+	if err != nil || body == nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		var wrapped struct {
+			Results []json.RawMessage `json:"results"`
+		}
+		if err2 := json.Unmarshal(body, &wrapped); err2 != nil {
+			return nil, fmt.Errorf("decode search response: %w", err)
+		}
+		items = wrapped.Results
+	}
+
+	out := make([]*repository.Patient, 0, len(items))
+	for _, item := range items {
+		p, ferrs, err := h.fieldMapping.Apply(item)
+		if err != nil {
+			return nil, fmt.Errorf("decode search result: %w", err)
+		}
+		for _, fe := range ferrs {
+			log.Printf("HIS mapping (hospital=%s): field %s: %v", h.hospitalID, fe.Field, fe.Err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// get makes one GET request and returns the body of a 200 response,
+// (nil, nil) for 404 and a classified error (see retryable) otherwise.
+func (h *HospitalAdapter) get(ctx context.Context, rawURL, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
 	}
+	req.Header.Set("Accept", accept)
+	for k, v := range h.headers {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := h.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("http request: %w", err)
+		if errors.As(err, new(*authError)) {
+			return nil, fmt.Errorf("http request: %w", err)
+		}
+		return nil, &transientError{fmt.Errorf("http request: %w", err)}
 	}
 	defer resp.Body.Close()
 
@@ -87,42 +211,17 @@ func (h *HospitalAdapter) LookupByIdentifier(ctx context.Context, identifier str
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
-		return nil, fmt.Errorf("hospital api status %d: %s", resp.StatusCode, string(body))
+		// drained so the connection can be reused
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, &statusError{
+			code:       resp.StatusCode,
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 
-	// read body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
+		return nil, &transientError{fmt.Errorf("read body: %w", err)}
 	}
-
-	var hr hospitalResponse
-	if err := json.Unmarshal(body, &hr); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
-	}
-
-	// Map to repository.Patient
-	p := &repository.Patient{
-		// ID left empty here; repository.Create should set a UUID if needed
-		PatientHN:    hr.PatientHN,
-		NationalID:   hr.NationalID,
-		PassportID:   hr.PassportID,
-		FirstNameTH:  hr.FirstNameTH,
-		MiddleNameTH: hr.MiddleNameTH,
-		LastNameTH:   hr.LastNameTH,
-		FirstNameEN:  hr.FirstNameEN,
-		MiddleNameEN: hr.MiddleNameEN,
-		LastNameEN:   hr.LastNameEN,
-		DateOfBirth:  nil,
-		PhoneNumber:  hr.PhoneNumber,
-		Email:        hr.Email,
-		Gender:       hr.Gender,
-		RawJSON:      body,
-	}
-	if hr.DateOfBirth != "" {
-		p.DateOfBirth = &hr.DateOfBirth
-	}
-
-	return p, nil
+	return body, nil
 }
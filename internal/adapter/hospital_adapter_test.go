@@ -8,6 +8,8 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
 )
 
 func TestHospitalAdapter_LookupByIdentifier_Success(t *testing.T) {
@@ -134,3 +136,26 @@ func TestHospitalAdapter_MapsAllFields(t *testing.T) {
 	assert.Equal(t, "E", p.MiddleNameEN)
 	assert.Equal(t, "2000-02-02", *p.DateOfBirth)
 }
+
+func TestHospitalAdapter_SearchDemographics(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/patient/search", r.URL.Path)
+		assert.Equal(t, "Jaidee", r.URL.Query().Get("last_name"))
+		assert.Equal(t, "1990-01-01", r.URL.Query().Get("date_of_birth"))
+		assert.False(t, r.URL.Query().Has("first_name"))
+		_, _ = w.Write([]byte(`{"results": [
+			{"national_id": "N-1", "first_name_en": "Somchai", "last_name_en": "Jaidee"},
+			{"national_id": "N-2", "first_name_en": "Somsri", "last_name_en": "Jaidee"}
+		]}`))
+	}))
+	defer ts.Close()
+
+	h, err := NewHospitalAdapter(ts.URL, time.Second)
+	assert.NoError(t, err)
+	out, err := h.SearchDemographics(context.Background(), repository.PatientFilters{LastName: "Jaidee", DateOfBirth: "1990-01-01"})
+	assert.NoError(t, err)
+	if assert.Len(t, out, 2) {
+		assert.Equal(t, "N-1", out[0].NationalID)
+		assert.Equal(t, "Somsri", out[1].FirstNameEN)
+	}
+}
@@ -0,0 +1,291 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ErrLimited is returned when a call would exceed the hospital's outbound
+// limits. It wraps ErrHISUnavailable so callers degrade as for an outage.
+var ErrLimited = fmt.Errorf("%w: outbound limit reached", ErrHISUnavailable)
+
+// Limit modes.
+const (
+	LimitModeQueue    = "queue"     // wait for capacity up to MaxWait (and the request deadline)
+	LimitModeFailFast = "fail_fast" // fail with ErrLimited when no capacity is free right now
+)
+
+// LimitConfig caps the calls made to one hospital's HIS. Every HospitalClient
+// call takes a token and an in-flight slot; retries inside a call do not.
+// Zero RatePerSecond or MaxInFlight means that dimension is unlimited.
+type LimitConfig struct {
+	RatePerSecond float64  `json:"rate_per_second"`
+	Burst         int      `json:"burst"`         // bucket size; defaults to ceil(rate_per_second)
+	MaxInFlight   int      `json:"max_in_flight"` // concurrent calls
+	Mode          string   `json:"mode"`          // LimitModeQueue (default) or LimitModeFailFast
+	MaxWait       Duration `json:"max_wait"`      // queue mode only; defaults to DefaultLimitMaxWait
+}
+
+// DefaultLimitMaxWait bounds how long a queued call waits for capacity.
+const DefaultLimitMaxWait = time.Second
+
+// withDefaults fills unset fields.
+func (c LimitConfig) withDefaults() LimitConfig {
+	if c.Burst <= 0 {
+		c.Burst = int(math.Max(1, math.Ceil(c.RatePerSecond)))
+	}
+	if c.Mode == "" {
+		c.Mode = LimitModeQueue
+	}
+	if c.MaxWait <= 0 {
+		c.MaxWait = Duration(DefaultLimitMaxWait)
+	}
+	return c
+}
+
+func (c *LimitConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	if c.RatePerSecond < 0 || c.Burst < 0 || c.MaxInFlight < 0 || c.MaxWait < 0 {
+		return errors.New("limits: values must not be negative")
+	}
+	switch c.Mode {
+	case "", LimitModeQueue, LimitModeFailFast:
+	default:
+		return fmt.Errorf("limits: unknown mode %q", c.Mode)
+	}
+	return nil
+}
+
+// LimiterStats reports how saturated a hospital's limiter is.
+type LimiterStats struct {
+	RatePerSecond float64 `json:"rate_per_second,omitempty"`
+	Burst         int     `json:"burst,omitempty"`
+	MaxInFlight   int     `json:"max_in_flight,omitempty"`
+	Mode          string  `json:"mode"`
+	Tokens        float64 `json:"tokens"` // available now; negative while calls are queued for tokens
+	InFlight      int     `json:"in_flight"`
+	Waiting       int     `json:"waiting"`
+	Admitted      uint64  `json:"admitted"`
+	Queued        uint64  `json:"queued"` // admitted after waiting
+	Rejected      uint64  `json:"rejected"`
+	// Saturation is the busier of in_flight/max_in_flight and the share of the
+	// token bucket in use, 0..1.
+	Saturation float64 `json:"saturation"`
+}
+
+// Limiter is a token bucket plus a max-in-flight cap.
+type Limiter struct {
+	cfg LimitConfig
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	inFlight int
+	waiting  int
+	slotFree chan struct{} // closed and replaced whenever an in-flight slot is released
+
+	admitted, queued, rejected uint64
+
+	now func() time.Time
+}
+
+// NewLimiter constructs a Limiter with a full bucket.
+func NewLimiter(cfg LimitConfig) *Limiter {
+	cfg = cfg.withDefaults()
+	return &Limiter{cfg: cfg, tokens: float64(cfg.Burst), last: time.Now(), slotFree: make(chan struct{}), now: time.Now}
+}
+
+// Acquire waits for a token and an in-flight slot (fail-fast mode: takes them
+// only if free now). On success the caller must call release when done.
+func (l *Limiter) Acquire(ctx context.Context) (release func(), err error) {
+	waited := false
+	var deadline time.Time
+	if l.cfg.Mode == LimitModeQueue {
+		deadline = l.now().Add(time.Duration(l.cfg.MaxWait))
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+	}
+
+	// in-flight slot
+	l.mu.Lock()
+	for l.cfg.MaxInFlight > 0 && l.inFlight >= l.cfg.MaxInFlight {
+		if l.cfg.Mode == LimitModeFailFast {
+			return nil, l.rejectLocked("max in-flight")
+		}
+		wait := deadline.Sub(l.now())
+		if wait <= 0 {
+			return nil, l.rejectLocked("max in-flight")
+		}
+		ch := l.slotFree
+		l.waiting++
+		waited = true
+		l.mu.Unlock()
+		err := waitFor(ctx, ch, wait)
+		l.mu.Lock()
+		l.waiting--
+		if err != nil {
+			l.rejected++
+			l.mu.Unlock()
+			return nil, err
+		}
+	}
+	l.inFlight++
+
+	// token
+	if l.cfg.RatePerSecond > 0 {
+		l.refillLocked()
+		if l.tokens < 1 {
+			// reserve the next token; the bucket goes negative for queued callers
+			wait := time.Duration((1 - l.tokens) / l.cfg.RatePerSecond * float64(time.Second))
+			if l.cfg.Mode == LimitModeFailFast || l.now().Add(wait).After(deadline) {
+				l.inFlight--
+				l.signalLocked()
+				return nil, l.rejectLocked("rate")
+			}
+			l.tokens--
+			l.waiting++
+			l.mu.Unlock()
+			err := waitFor(ctx, nil, wait)
+			l.mu.Lock()
+			l.waiting--
+			if err != nil {
+				l.tokens++ // hand the reservation back
+				l.inFlight--
+				l.signalLocked()
+				l.rejected++
+				l.mu.Unlock()
+				return nil, err
+			}
+			waited = true
+		} else {
+			l.tokens--
+		}
+	}
+
+	l.admitted++
+	if waited {
+		l.queued++
+	}
+	l.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight--
+			l.signalLocked()
+			l.mu.Unlock()
+		})
+	}, nil
+}
+
+// Stats reports the limiter's current load and counters.
+func (l *Limiter) Stats() LimiterStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cfg.RatePerSecond > 0 {
+		l.refillLocked()
+	}
+	s := LimiterStats{
+		RatePerSecond: l.cfg.RatePerSecond,
+		MaxInFlight:   l.cfg.MaxInFlight,
+		Mode:          l.cfg.Mode,
+		InFlight:      l.inFlight,
+		Waiting:       l.waiting,
+		Admitted:      l.admitted,
+		Queued:        l.queued,
+		Rejected:      l.rejected,
+	}
+	if l.cfg.MaxInFlight > 0 {
+		s.Saturation = float64(l.inFlight) / float64(l.cfg.MaxInFlight)
+	}
+	if l.cfg.RatePerSecond > 0 {
+		s.Burst = l.cfg.Burst
+		s.Tokens = l.tokens
+		s.Saturation = math.Max(s.Saturation, 1-l.tokens/float64(l.cfg.Burst))
+	}
+	s.Saturation = math.Min(1, math.Max(0, s.Saturation))
+	return s
+}
+
+// refillLocked adds the tokens earned since the last refill. Call with mu held.
+func (l *Limiter) refillLocked() {
+	now := l.now()
+	l.tokens = math.Min(float64(l.cfg.Burst), l.tokens+now.Sub(l.last).Seconds()*l.cfg.RatePerSecond)
+	l.last = now
+}
+
+// signalLocked wakes callers waiting for an in-flight slot. Call with mu held.
+func (l *Limiter) signalLocked() {
+	close(l.slotFree)
+	l.slotFree = make(chan struct{})
+}
+
+// rejectLocked counts a rejection and unlocks mu.
+func (l *Limiter) rejectLocked(what string) error {
+	l.rejected++
+	l.mu.Unlock()
+	return fmt.Errorf("%w (%s)", ErrLimited, what)
+}
+
+// waitFor blocks until ch is closed (nil: never), d passes or ctx is done.
+// Running out of d on ch is an ErrLimited.
+func waitFor(ctx context.Context, ch <-chan struct{}, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ch:
+		return nil
+	case <-t.C:
+		if ch != nil {
+			return fmt.Errorf("%w (max in-flight)", ErrLimited)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// limitedClient applies a hospital's Limiter to every call.
+type limitedClient struct {
+	hospitalID string
+	next       HospitalClient
+	limiter    *Limiter
+}
+
+// LookupByIdentifier implements HospitalClient.
+func (c *limitedClient) LookupByIdentifier(ctx context.Context, identifier string) (*repository.Patient, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.next.LookupByIdentifier(ctx, identifier)
+}
+
+// SearchDemographics implements HospitalClient.
+func (c *limitedClient) SearchDemographics(ctx context.Context, q repository.PatientFilters) ([]*repository.Patient, error) {
+	release, err := c.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return c.next.SearchDemographics(ctx, q)
+}
+
+func (c *limitedClient) acquire(ctx context.Context) (func(), error) {
+	release, err := c.limiter.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.hospitalID, err)
+	}
+	return release, nil
+}
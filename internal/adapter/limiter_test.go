@@ -0,0 +1,117 @@
+package adapter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_FailFastInFlight(t *testing.T) {
+	l := NewLimiter(LimitConfig{MaxInFlight: 1, Mode: LimitModeFailFast})
+	ctx := context.Background()
+
+	release, err := l.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, l.Stats().Saturation)
+
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLimited)
+	assert.ErrorIs(t, err, ErrHISUnavailable)
+
+	release()
+	release() // idempotent
+	_, err = l.Acquire(ctx)
+	assert.NoError(t, err)
+
+	s := l.Stats()
+	assert.EqualValues(t, 2, s.Admitted)
+	assert.EqualValues(t, 1, s.Rejected)
+}
+
+func TestLimiter_QueueWaitsForSlot(t *testing.T) {
+	l := NewLimiter(LimitConfig{MaxInFlight: 1, MaxWait: Duration(time.Second)})
+	release, err := l.Acquire(context.Background())
+	assert.NoError(t, err)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		release()
+	}()
+	_, err = l.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, l.Stats().Queued)
+}
+
+func TestLimiter_QueueGivesUpAtMaxWaitOrDeadline(t *testing.T) {
+	l := NewLimiter(LimitConfig{MaxInFlight: 1, MaxWait: Duration(20 * time.Millisecond)})
+	_, err := l.Acquire(context.Background())
+	assert.NoError(t, err)
+
+	_, err = l.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrLimited)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = l.Acquire(ctx)
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 20*time.Millisecond, "request deadline is shorter than max_wait")
+	assert.Equal(t, 0, l.Stats().Waiting)
+}
+
+func TestLimiter_TokenBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(LimitConfig{RatePerSecond: 2, Mode: LimitModeFailFast})
+	l.now = func() time.Time { return now }
+	l.last = now
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ { // burst defaults to ceil(rate)
+		release, err := l.Acquire(ctx)
+		assert.NoError(t, err)
+		release()
+	}
+	_, err := l.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLimited)
+	assert.Equal(t, 1.0, l.Stats().Saturation)
+
+	now = now.Add(500 * time.Millisecond)
+	_, err = l.Acquire(ctx)
+	assert.NoError(t, err, "one token refilled")
+}
+
+func TestLimiter_QueueWaitsForToken(t *testing.T) {
+	l := NewLimiter(LimitConfig{RatePerSecond: 50, Burst: 1, MaxWait: Duration(time.Second)})
+	ctx := context.Background()
+
+	_, err := l.Acquire(ctx)
+	assert.NoError(t, err)
+	start := time.Now()
+	_, err = l.Acquire(ctx)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+
+	l = NewLimiter(LimitConfig{RatePerSecond: 1, Burst: 1, MaxWait: Duration(10 * time.Millisecond)})
+	_, err = l.Acquire(ctx)
+	assert.NoError(t, err)
+	_, err = l.Acquire(ctx)
+	assert.ErrorIs(t, err, ErrLimited, "next token is further away than max_wait")
+}
+
+func TestRegistry_AppliesLimits(t *testing.T) {
+	r, err := NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Limits: &LimitConfig{MaxInFlight: 2}},
+		{HospitalID: "HIS-2", BaseURL: "http://his-2"},
+	})
+	assert.NoError(t, err)
+	stats := r.LimiterStats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, LimitModeQueue, stats["HIS-1"].Mode)
+
+	_, err = NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Limits: &LimitConfig{Mode: "drop"}},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,335 @@
+package adapter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/thaidate"
+)
+
+// StandardFields are the keys a MappingSpec can map, i.e. the fields of the
+// default HIS response ("agnos" protocol) and of repository.Patient.
+var StandardFields = []string{
+	"patient_hn", "national_id", "passport_id",
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"date_of_birth", "phone_number", "email", "gender",
+}
+
+// MappingSpec maps standard fields to where (and how) a HIS returns them.
+// Fields without a rule are read from their standard key.
+//
+//	"field_mapping": {
+//	  "patient_hn":    "hn",                                    // shorthand: path only
+//	  "first_name_th": {"path": "name.th.first", "transform": ["trim"]},
+//	  "date_of_birth": {"path": "dob", "date_format": "dd/mm/yyyy"},
+//	  "gender":        {"path": "sex", "enum": {"male": "M", "female": "F", "*": ""}},
+//	  "phone_number":  {"path": "contacts[0].value", "fallback": ["mobile"], "transform": ["digits"]}
+//	}
+type MappingSpec map[string]FieldRule
+
+// FieldRule describes how to read one standard field.
+type FieldRule struct {
+	// Path is a dotted JSON path; "[n]" indexes arrays (e.g. "names[0].given").
+	Path string `json:"path"`
+	// Fallback paths are tried in order when Path is missing or empty.
+	Fallback []string `json:"fallback,omitempty"`
+	// Transform is applied in order: trim, upper, lower, title, digits.
+	Transform []string `json:"transform,omitempty"`
+	// Enum maps values case-insensitively; "*" is the value for anything unlisted.
+	// Without "*", an unlisted value is an error.
+	Enum map[string]string `json:"enum,omitempty"`
+	// DateFormat parses the value as a date ("dd/mm/yyyy", "yyyymmdd", "dd MMM yyyy",
+	// a Go layout, or "auto" for any form thaidate.Parse accepts) and stores it as
+	// CE yyyy-mm-dd; Buddhist Era years are converted. Without it, date_of_birth
+	// values in a form thaidate.Parse recognises are normalised too.
+	DateFormat string `json:"date_format,omitempty"`
+	// Default is used when no path yields a value.
+	Default string `json:"default,omitempty"`
+}
+
+// UnmarshalJSON accepts the shorthand "hn" for {"path": "hn"}, so configs
+// written for the old key-rename mapping keep working.
+func (r *FieldRule) UnmarshalJSON(b []byte) error {
+	if len(b) > 0 && b[0] == '"' {
+		var p string
+		if err := json.Unmarshal(b, &p); err != nil {
+			return err
+		}
+		*r = FieldRule{Path: p}
+		return nil
+	}
+	type plain FieldRule
+	return json.Unmarshal(b, (*plain)(r))
+}
+
+var transforms = map[string]func(string) string{
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"title": func(s string) string {
+		out := []rune(strings.ToLower(s))
+		for i := range out {
+			if i == 0 || unicode.IsSpace(out[i-1]) || out[i-1] == '-' {
+				out[i] = unicode.ToUpper(out[i])
+			}
+		}
+		return string(out)
+	},
+	"digits": func(s string) string {
+		return strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, s)
+	},
+}
+
+// Validate reports unknown fields, transforms and unusable date formats.
+func (s MappingSpec) Validate() error {
+	known := make(map[string]bool, len(StandardFields))
+	for _, f := range StandardFields {
+		known[f] = true
+	}
+	var errs []error
+	for _, field := range s.fields() {
+		rule := s[field]
+		if !known[field] {
+			errs = append(errs, fmt.Errorf("field_mapping: unknown field %q", field))
+			continue
+		}
+		if rule.Path == "" {
+			errs = append(errs, fmt.Errorf("field_mapping.%s: path is required", field))
+		}
+		for _, p := range append([]string{rule.Path}, rule.Fallback...) {
+			if _, err := parsePath(p); p != "" && err != nil {
+				errs = append(errs, fmt.Errorf("field_mapping.%s: %w", field, err))
+			}
+		}
+		for _, t := range rule.Transform {
+			if transforms[t] == nil {
+				errs = append(errs, fmt.Errorf("field_mapping.%s: unknown transform %q", field, t))
+			}
+		}
+		if rule.DateFormat != "" && field != "date_of_birth" {
+			errs = append(errs, fmt.Errorf("field_mapping.%s: date_format only applies to date_of_birth", field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FieldError is a value that could not be mapped; the field is left empty.
+type FieldError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %q: %v", e.Field, e.Value, e.Err)
+}
+
+// Apply maps a HIS JSON object to a Patient (RawJSON is body as received).
+// Values that fail a date format or enum are reported and left empty;
+// an error is returned only when body is not a JSON object.
+func (s MappingSpec) Apply(body []byte) (*repository.Patient, []FieldError, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	if _, ok := doc.(map[string]any); !ok {
+		return nil, nil, errors.New("response is not a JSON object")
+	}
+
+	p := &repository.Patient{RawJSON: body}
+	var ferrs []FieldError
+	for _, field := range StandardFields {
+		rule, ok := s[field]
+		if !ok {
+			rule = FieldRule{Path: field}
+		}
+		v, err := rule.value(doc)
+		if err != nil {
+			ferrs = append(ferrs, FieldError{Field: field, Value: v, Err: err})
+			continue
+		}
+		if field == "date_of_birth" && rule.DateFormat == "" && v != "" {
+			// best effort; anything unrecognised is left for validation to flag
+			if d, err := thaidate.Parse(v); err == nil {
+				v = d
+			}
+		}
+		p.SetField(field, v)
+	}
+	return p, ferrs, nil
+}
+
+func (r FieldRule) value(doc any) (string, error) {
+	var v string
+	for _, path := range append([]string{r.Path}, r.Fallback...) {
+		if v = lookupPath(doc, path); v != "" {
+			break
+		}
+	}
+	if v == "" {
+		v = r.Default
+	}
+	for _, t := range r.Transform {
+		if fn := transforms[t]; fn != nil {
+			v = fn(v)
+		}
+	}
+	if v == "" {
+		return "", nil
+	}
+
+	if len(r.Enum) > 0 {
+		mapped, ok := lookupEnum(r.Enum, v)
+		if !ok {
+			return v, errors.New("value not in enum")
+		}
+		v = mapped
+	}
+	if r.DateFormat != "" {
+		d, err := parseDate(r.DateFormat, v)
+		if err != nil {
+			return v, err
+		}
+		v = d
+	}
+	return v, nil
+}
+
+func lookupEnum(enum map[string]string, v string) (string, bool) {
+	if m, ok := enum[v]; ok {
+		return m, true
+	}
+	for k, m := range enum {
+		if strings.EqualFold(k, v) {
+			return m, true
+		}
+	}
+	m, ok := enum["*"]
+	return m, ok
+}
+
+// DateFormatAuto accepts any date thaidate.Parse understands.
+const DateFormatAuto = "auto"
+
+// parseDate parses v with format and returns the CE date as yyyy-mm-dd.
+// Buddhist Era years (2400 and later) are converted.
+func parseDate(format, v string) (string, error) {
+	switch {
+	case format == DateFormatAuto:
+		d, err := thaidate.Parse(v)
+		if err != nil {
+			return "", errors.New("not a recognised date")
+		}
+		return d, nil
+	case thaidate.IsFormat(format):
+		d, err := thaidate.ParseFormat(format, v)
+		if err != nil {
+			return "", fmt.Errorf("date does not match %q", format)
+		}
+		return d, nil
+	}
+	// a Go layout
+	t, err := time.Parse(format, v)
+	if err != nil {
+		return "", fmt.Errorf("date does not match %q", format)
+	}
+	d, err := thaidate.Parse(t.Format("2006-01-02")) // converts a BE year
+	if err != nil {
+		return "", fmt.Errorf("date does not match %q", format)
+	}
+	return d, nil
+}
+
+type pathStep struct {
+	key   string
+	index int // -1 when the step is a key
+}
+
+// parsePath splits "a.b[0].c" into steps.
+func parsePath(path string) ([]pathStep, error) {
+	var steps []pathStep
+	for _, part := range strings.Split(path, ".") {
+		key, rest, hasIndex := strings.Cut(part, "[")
+		if key != "" {
+			steps = append(steps, pathStep{key: key, index: -1})
+		}
+		for hasIndex {
+			idx, after, ok := strings.Cut(rest, "]")
+			n, err := strconv.Atoi(idx)
+			if !ok || err != nil || n < 0 {
+				return nil, fmt.Errorf("bad path %q", path)
+			}
+			steps = append(steps, pathStep{index: n})
+			if after == "" {
+				break
+			}
+			if !strings.HasPrefix(after, "[") {
+				return nil, fmt.Errorf("bad path %q", path)
+			}
+			rest = after[1:]
+		}
+		if key == "" && !hasIndex {
+			return nil, fmt.Errorf("bad path %q", path)
+		}
+	}
+	return steps, nil
+}
+
+// lookupPath returns the scalar at path as a string ("" if missing, null or not a scalar).
+func lookupPath(doc any, path string) string {
+	steps, err := parsePath(path)
+	if err != nil {
+		return ""
+	}
+	cur := doc
+	for _, st := range steps {
+		switch node := cur.(type) {
+		case map[string]any:
+			if st.index >= 0 {
+				return ""
+			}
+			cur = node[st.key]
+		case []any:
+			if st.index < 0 || st.index >= len(node) {
+				return ""
+			}
+			cur = node[st.index]
+		default:
+			return ""
+		}
+	}
+	switch v := cur.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
+
+func (s MappingSpec) fields() []string {
+	out := make([]string, 0, len(s))
+	for f := range s {
+		out = append(out, f)
+	}
+	sort.Strings(out)
+	return out
+}
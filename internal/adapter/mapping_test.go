@@ -0,0 +1,92 @@
+package adapter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMappingSpec_Apply(t *testing.T) {
+	var spec MappingSpec
+	err := json.Unmarshal([]byte(`{
+		"patient_hn": "hn",
+		"national_id": {"path": "ids.cid", "transform": ["digits"]},
+		"first_name_th": "name.th.first",
+		"last_name_th": "name.th.last",
+		"first_name_en": {"path": "name.en.first", "transform": ["trim", "title"]},
+		"date_of_birth": {"path": "dob", "date_format": "dd/mm/yyyy"},
+		"gender": {"path": "sex", "enum": {"male": "M", "female": "F", "*": ""}},
+		"phone_number": {"path": "contacts[0].value", "fallback": ["mobile"]},
+		"email": {"path": "email", "default": "none@example.com"}
+	}`), &spec)
+	assert.NoError(t, err)
+	assert.NoError(t, spec.Validate())
+
+	body := []byte(`{
+		"hn": 42,
+		"ids": {"cid": "1-1037-00000-00-1"},
+		"name": {"th": {"first": "สมชาย", "last": "ใจดี"}, "en": {"first": "  SOMCHAI "}},
+		"dob": "05/05/1985",
+		"sex": "Male",
+		"contacts": [],
+		"mobile": "0812345678",
+		"passport_id": "AA1"
+	}`)
+	p, ferrs, err := spec.Apply(body)
+	assert.NoError(t, err)
+	assert.Empty(t, ferrs)
+	assert.Equal(t, "42", p.PatientHN)
+	assert.Equal(t, "1103700000001", p.NationalID)
+	assert.Equal(t, "AA1", p.PassportID, "unmapped fields use their standard key")
+	assert.Equal(t, "สมชาย", p.FirstNameTH)
+	assert.Equal(t, "ใจดี", p.LastNameTH)
+	assert.Equal(t, "Somchai", p.FirstNameEN)
+	assert.Equal(t, "1985-05-05", *p.DateOfBirth)
+	assert.Equal(t, "M", p.Gender)
+	assert.Equal(t, "0812345678", p.PhoneNumber)
+	assert.Equal(t, "none@example.com", p.Email)
+	assert.JSONEq(t, string(body), string(p.RawJSON))
+}
+
+func TestMappingSpec_FieldErrors(t *testing.T) {
+	spec := MappingSpec{
+		"date_of_birth": {Path: "dob", DateFormat: "yyyymmdd"},
+		"gender":        {Path: "sex", Enum: map[string]string{"1": "M", "2": "F"}},
+	}
+	p, ferrs, err := spec.Apply([]byte(`{"dob":"05/05/1985","sex":"9","national_id":"N-1"}`))
+	assert.NoError(t, err)
+	assert.Len(t, ferrs, 2)
+	assert.Nil(t, p.DateOfBirth)
+	assert.Equal(t, "", p.Gender)
+	assert.Equal(t, "N-1", p.NationalID)
+
+	_, _, err = spec.Apply([]byte(`[1,2]`))
+	assert.Error(t, err)
+}
+
+func TestMappingSpec_Validate(t *testing.T) {
+	assert.Error(t, MappingSpec{"shoe_size": {Path: "x"}}.Validate())
+	assert.Error(t, MappingSpec{"email": {Path: "x", Transform: []string{"rot13"}}}.Validate())
+	assert.Error(t, MappingSpec{"email": {Path: "a[x]"}}.Validate())
+	assert.Error(t, MappingSpec{"email": {Path: "x", DateFormat: "yyyy"}}.Validate())
+	assert.NoError(t, MappingSpec{"email": {Path: "contacts[0].emails[1]"}}.Validate())
+}
+
+func TestMappingSpec_BuddhistEraDates(t *testing.T) {
+	spec := MappingSpec{"date_of_birth": {Path: "dob", DateFormat: "dd/mm/yyyy"}}
+	p, ferrs, err := spec.Apply([]byte(`{"dob":"29/02/2527"}`))
+	assert.NoError(t, err)
+	assert.Empty(t, ferrs)
+	assert.Equal(t, "1984-02-29", *p.DateOfBirth)
+
+	spec = MappingSpec{"date_of_birth": {Path: "dob", DateFormat: DateFormatAuto}}
+	p, _, _ = spec.Apply([]byte(`{"dob":"5 พ.ค. 2528"}`))
+	assert.Equal(t, "1985-05-05", *p.DateOfBirth)
+
+	// no rule: recognised forms are normalised, anything else is kept for validation
+	p, _, _ = MappingSpec(nil).Apply([]byte(`{"date_of_birth":"2528-05-05"}`))
+	assert.Equal(t, "1985-05-05", *p.DateOfBirth)
+	p, _, _ = MappingSpec(nil).Apply([]byte(`{"date_of_birth":"soon"}`))
+	assert.Equal(t, "soon", *p.DateOfBirth)
+}
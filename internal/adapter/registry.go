@@ -0,0 +1,192 @@
+package adapter
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrNoHIS is returned for hospitals that have no HIS configured.
+var ErrNoHIS = errors.New("no HIS configured for hospital")
+
+// ClientResolver picks the HospitalClient of a tenant.
+type ClientResolver interface {
+	ClientFor(hospitalID string) (HospitalClient, error)
+}
+
+// Registry maps hospital_id to the HospitalClient built from its config.
+// Every client is guarded by its own circuit breaker, is throttled by the
+// hospital's limits (if any) and, with WithCache, answers repeated lookups
+// from the cache.
+type Registry struct {
+	configs  map[string]HospitalConfig
+	clients  map[string]HospitalClient
+	breakers map[string]*Breaker
+	limiters map[string]*Limiter
+	cache    Cache
+}
+
+type registryOptions struct {
+	cache           Cache
+	retryObserver   func(RetryEvent)
+	breakerObserver func(hospitalID string, from, to BreakerState)
+}
+
+// RegistryOption customises the clients built by NewRegistry.
+type RegistryOption func(*registryOptions)
+
+// WithRetryObserver reports every adapter's retry decisions to fn.
+func WithRetryObserver(fn func(RetryEvent)) RegistryOption {
+	return func(o *registryOptions) { o.retryObserver = fn }
+}
+
+// WithCache puts cache in front of every hospital's client (keys are per hospital).
+func WithCache(cache Cache) RegistryOption {
+	return func(o *registryOptions) { o.cache = cache }
+}
+
+// WithBreakerObserver reports every circuit breaker state change to fn.
+// fn runs while the breaker's lock is held and must not block.
+func WithBreakerObserver(fn func(hospitalID string, from, to BreakerState)) RegistryOption {
+	return func(o *registryOptions) { o.breakerObserver = fn }
+}
+
+// NewRegistry builds one adapter per hospital config.
+// A duplicate hospital_id or an invalid config is an error.
+func NewRegistry(configs []HospitalConfig, opts ...RegistryOption) (*Registry, error) {
+	var o registryOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	r := &Registry{
+		configs:  make(map[string]HospitalConfig, len(configs)),
+		clients:  make(map[string]HospitalClient, len(configs)),
+		breakers: make(map[string]*Breaker, len(configs)),
+		limiters: make(map[string]*Limiter),
+		cache:    o.cache,
+	}
+	for _, cfg := range configs {
+		if err := cfg.validate(); err != nil {
+			return nil, fmt.Errorf("invalid HIS config: %w", err)
+		}
+		if _, dup := r.configs[cfg.HospitalID]; dup {
+			return nil, fmt.Errorf("duplicate HIS config for %s", cfg.HospitalID)
+		}
+		c, err := newClientFromConfig(cfg, o.retryObserver)
+		if err != nil {
+			return nil, fmt.Errorf("build adapter for %s: %w", cfg.HospitalID, err)
+		}
+
+		b := NewBreaker(cfg.Breaker.withDefaults())
+		if o.breakerObserver != nil {
+			hid := cfg.HospitalID
+			b.onChange = func(from, to BreakerState) { o.breakerObserver(hid, from, to) }
+		}
+
+		// cache hits never reach (or count against) the limiter or the breaker,
+		// and calls the limiter turns away are not HIS failures
+		var client HospitalClient = &breakerClient{hospitalID: cfg.HospitalID, next: c, breaker: b}
+		if cfg.Limits != nil {
+			l := NewLimiter(*cfg.Limits)
+			client = &limitedClient{hospitalID: cfg.HospitalID, next: client, limiter: l}
+			r.limiters[cfg.HospitalID] = l
+		}
+		if o.cache != nil {
+			client = &cachingClient{hospitalID: cfg.HospitalID, next: client, cache: o.cache, cfg: cfg.Cache.withDefaults()}
+		}
+
+		r.configs[cfg.HospitalID] = cfg
+		r.clients[cfg.HospitalID] = client
+		r.breakers[cfg.HospitalID] = b
+	}
+	return r, nil
+}
+
+// ClientFor returns the hospital's client, or ErrNoHIS.
+func (r *Registry) ClientFor(hospitalID string) (HospitalClient, error) {
+	c, ok := r.clients[hospitalID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrNoHIS, hospitalID)
+	}
+	return c, nil
+}
+
+// Hospitals returns the configured hospital ids, sorted.
+func (r *Registry) Hospitals() []string {
+	ids := make([]string, 0, len(r.clients))
+	for id := range r.clients {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ReconcileConfig returns the hospital's reconcile settings with defaults applied.
+func (r *Registry) ReconcileConfig(hospitalID string) ReconcileConfig {
+	cfg := r.configs[hospitalID]
+	return cfg.Reconcile.withDefaults()
+}
+
+// FreshnessConfig returns the hospital's freshness settings with defaults applied.
+func (r *Registry) FreshnessConfig(hospitalID string) FreshnessConfig {
+	cfg := r.configs[hospitalID]
+	return cfg.Freshness.withDefaults()
+}
+
+// SurvivorshipConfig returns the hospital's survivorship rules (nil: the
+// last write wins for every field).
+func (r *Registry) SurvivorshipConfig(hospitalID string) SurvivorshipConfig {
+	return r.configs[hospitalID].Survivorship
+}
+
+// BreakerStates reports the circuit state of every configured hospital.
+func (r *Registry) BreakerStates() map[string]BreakerState {
+	out := make(map[string]BreakerState, len(r.breakers))
+	for id, b := range r.breakers {
+		out[id] = b.State()
+	}
+	return out
+}
+
+// LimiterStats reports the outbound limiter of every hospital that has limits.
+func (r *Registry) LimiterStats() map[string]LimiterStats {
+	out := make(map[string]LimiterStats, len(r.limiters))
+	for id, l := range r.limiters {
+		out[id] = l.Stats()
+	}
+	return out
+}
+
+// Invalidate implements Invalidator: it drops the hospital's cached lookups
+// (hits and "not found" answers) for the given identifiers.
+func (r *Registry) Invalidate(hospitalID string, identifiers ...string) {
+	if r.cache == nil {
+		return
+	}
+	for _, id := range identifiers {
+		if id != "" {
+			r.cache.Delete(CacheKey(hospitalID, id))
+		}
+	}
+}
+
+// newClientFromConfig builds the protocol-specific adapter of one hospital.
+func newClientFromConfig(cfg HospitalConfig, retryObserver func(RetryEvent)) (HospitalClient, error) {
+	switch cfg.Protocol {
+	case ProtocolFHIR:
+		f, err := NewFHIRAdapterFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		f.http.retry.Observer = retryObserver
+		return f, nil
+	default:
+		h, err := NewHospitalAdapterFromConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		h.retry.Observer = retryObserver
+		return h, nil
+	}
+}
@@ -0,0 +1,125 @@
+package adapter
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_ClientForPerHospital(t *testing.T) {
+	var gotKey string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.Header.Get("X-API-Key")
+		// this HIS uses its own key names
+		_, _ = w.Write([]byte(`{"hn":"HN-77","national_id":"N-7","fname_en":"Ann"}`))
+	}))
+	defer ts.Close()
+
+	reg, err := NewRegistry([]HospitalConfig{{
+		HospitalID:   "HIS-2",
+		BaseURL:      ts.URL,
+		Timeout:      Duration(time.Second),
+		Headers:      map[string]string{"X-API-Key": "k-123"},
+		FieldMapping: MappingSpec{"patient_hn": {Path: "hn"}, "first_name_en": {Path: "fname_en"}},
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HIS-2"}, reg.Hospitals())
+
+	c, err := reg.ClientFor("HIS-2")
+	assert.NoError(t, err)
+	p, err := c.LookupByIdentifier(context.Background(), "N-7")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "HN-77", p.PatientHN)
+		assert.Equal(t, "Ann", p.FirstNameEN)
+		assert.JSONEq(t, `{"hn":"HN-77","national_id":"N-7","fname_en":"Ann"}`, string(p.RawJSON))
+	}
+	assert.Equal(t, "k-123", gotKey)
+
+	_, err = reg.ClientFor("HIS-9")
+	assert.ErrorIs(t, err, ErrNoHIS)
+}
+
+func TestRegistry_RejectsInvalidConfig(t *testing.T) {
+	_, err := NewRegistry([]HospitalConfig{{HospitalID: "HIS-1"}})
+	assert.Error(t, err)
+
+	_, err = NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://a"},
+		{HospitalID: "HIS-1", BaseURL: "http://b"},
+	})
+	assert.Error(t, err)
+}
+
+func TestLoadRegistryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "his.json")
+	b, _ := json.Marshal(map[string]any{
+		"hospitals": []map[string]any{
+			{"hospital_id": "HIS-1", "base_url": "http://hospital-a", "timeout": "1500ms"},
+		},
+	})
+	assert.NoError(t, os.WriteFile(path, b, 0o600))
+
+	cfgs, err := LoadRegistryFile(path)
+	assert.NoError(t, err)
+	if assert.Len(t, cfgs, 1) {
+		assert.Equal(t, "HIS-1", cfgs[0].HospitalID)
+		assert.Equal(t, 1500*time.Millisecond, time.Duration(cfgs[0].Timeout))
+	}
+
+	cfg, err := ParseHospitalConfig("HIS-3", []byte(`{"base_url":"http://c","timeout":"3s"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "HIS-3", cfg.HospitalID)
+}
+
+func TestRegistry_FreshnessConfig(t *testing.T) {
+	r, err := NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Freshness: &FreshnessConfig{SoftTTL: Duration(5 * time.Minute)}},
+	})
+	assert.NoError(t, err)
+	cfg := r.FreshnessConfig("HIS-1")
+	assert.Equal(t, Duration(5*time.Minute), cfg.SoftTTL)
+	assert.Equal(t, DefaultFreshnessConfig.HardTTL, cfg.HardTTL)
+	assert.Equal(t, DefaultFreshnessConfig, r.FreshnessConfig("HIS-9"))
+
+	_, err = NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Freshness: &FreshnessConfig{SoftTTL: Duration(time.Hour), HardTTL: Duration(time.Minute)}},
+	})
+	assert.Error(t, err)
+
+	// checked against the defaults too
+	_, err = NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Freshness: &FreshnessConfig{SoftTTL: Duration(48 * time.Hour)}},
+	})
+	assert.ErrorContains(t, err, "hard_ttl (24h0m0s) must not be shorter than soft_ttl (48h0m0s)")
+	_, err = NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Freshness: &FreshnessConfig{HardTTL: Duration(30 * time.Minute)}},
+	})
+	assert.Error(t, err)
+}
+
+func TestRegistry_SurvivorshipConfig(t *testing.T) {
+	r, err := NewRegistry([]HospitalConfig{
+		{HospitalID: "HIS-1", BaseURL: "http://his-1", Survivorship: SurvivorshipConfig{"phone_number": SurvivorLocal, "last_name_th": SurvivorHIS}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, SurvivorLocal, r.SurvivorshipConfig("HIS-1").Rule("phone_number"))
+	assert.Equal(t, SurvivorLatest, r.SurvivorshipConfig("HIS-1").Rule("email"))
+	assert.Equal(t, SurvivorLatest, r.SurvivorshipConfig("HIS-9").Rule("phone_number"))
+
+	for _, bad := range []SurvivorshipConfig{
+		{"national_id": SurvivorHIS},
+		{"shoe_size": SurvivorLocal},
+		{"email": "oldest"},
+	} {
+		_, err := NewRegistry([]HospitalConfig{{HospitalID: "HIS-1", BaseURL: "http://his-1", Survivorship: bad}})
+		assert.Error(t, err, bad)
+	}
+}
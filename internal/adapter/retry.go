@@ -0,0 +1,217 @@
+package adapter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how HospitalAdapter retries transient HIS failures.
+// Only idempotent requests are retried, and only on network errors,
+// 429, 502, 503 and 504. The caller's context deadline always wins.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first; <= 1 disables retries
+	BaseDelay   time.Duration // backoff before the first retry
+	MaxDelay    time.Duration // cap for backoff and for Retry-After
+
+	// Observer, if set, is told about every retry decision (for logging / metrics).
+	Observer func(RetryEvent)
+}
+
+// DefaultRetryPolicy is used when a hospital has no retry config.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// Retry outcomes reported in RetryEvent.Outcome.
+const (
+	RetryScheduled = "retry"     // attempt failed, another one follows after Delay
+	RetryRecovered = "recovered" // a retry succeeded
+	RetryExhausted = "exhausted" // MaxAttempts reached, last error returned
+	RetryGaveUp    = "gave_up"   // next wait would pass the deadline (or Retry-After > MaxDelay)
+)
+
+// RetryEvent describes one retry decision.
+type RetryEvent struct {
+	HospitalID string
+	Attempt    int // 1-based attempt that just finished
+	Outcome    string
+	StatusCode int // 0 for network errors
+	Delay      time.Duration
+	Err        error
+}
+
+// RetryConfig is the JSON form of a RetryPolicy inside HospitalConfig.
+type RetryConfig struct {
+	MaxAttempts int      `json:"max_attempts"`
+	BaseDelay   Duration `json:"base_delay"`
+	MaxDelay    Duration `json:"max_delay"`
+}
+
+// policy fills unset fields from DefaultRetryPolicy.
+func (c *RetryConfig) policy() RetryPolicy {
+	p := DefaultRetryPolicy
+	if c == nil {
+		return p
+	}
+	if c.MaxAttempts > 0 {
+		p.MaxAttempts = c.MaxAttempts
+	}
+	if c.BaseDelay > 0 {
+		p.BaseDelay = time.Duration(c.BaseDelay)
+	}
+	if c.MaxDelay > 0 {
+		p.MaxDelay = time.Duration(c.MaxDelay)
+	}
+	return p
+}
+
+// statusError is a non-2xx, non-404 HIS response.
+// The response body is not kept: it may echo patient data.
+type statusError struct {
+	code       int
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	if reason := http.StatusText(e.code); reason != "" {
+		return fmt.Sprintf("hospital api status %d (%s)", e.code, reason)
+	}
+	return fmt.Sprintf("hospital api status %d", e.code)
+}
+
+// transientError marks failures where the HIS may succeed on a second try
+// (connection reset, client timeout, truncated body).
+type transientError struct{ err error }
+
+func (e *transientError) Error() string { return e.err.Error() }
+func (e *transientError) Unwrap() error { return e.err }
+
+// retryable reports whether err is worth another attempt and the server's Retry-After, if any.
+func retryable(err error) (bool, int, time.Duration) {
+	var se *statusError
+	if errors.As(err, &se) {
+		switch se.code {
+		case http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true, se.code, se.retryAfter
+		}
+		return false, se.code, 0
+	}
+	var te *transientError
+	return errors.As(err, &te), 0, 0
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// do runs attempt until it succeeds, fails permanently, runs out of attempts
+// or the next wait would not fit in ctx's deadline.
+func (p RetryPolicy) do(ctx context.Context, hospitalID, method string, attempt func() error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 || !isIdempotent(method) {
+		attempts = 1
+	}
+
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			if n > 1 {
+				p.observe(RetryEvent{HospitalID: hospitalID, Attempt: n, Outcome: RetryRecovered})
+			}
+			return nil
+		}
+		// the caller gave up; nothing to retry for
+		if ctx.Err() != nil {
+			return err
+		}
+		ok, code, retryAfter := retryable(err)
+		if !ok {
+			return err
+		}
+		ev := RetryEvent{HospitalID: hospitalID, Attempt: n, StatusCode: code, Err: err}
+		if n >= attempts {
+			if attempts > 1 {
+				ev.Outcome = RetryExhausted
+				p.observe(ev)
+			}
+			return err
+		}
+
+		delay := p.backoff(n)
+		if retryAfter > 0 {
+			if retryAfter > p.MaxDelay {
+				ev.Outcome, ev.Delay = RetryGaveUp, retryAfter
+				p.observe(ev)
+				return err
+			}
+			delay = retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			ev.Outcome, ev.Delay = RetryGaveUp, delay
+			p.observe(ev)
+			return err
+		}
+
+		ev.Outcome, ev.Delay = RetryScheduled, delay
+		p.observe(ev)
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+	}
+}
+
+// backoff is BaseDelay*2^(n-1) capped at MaxDelay, with "equal jitter":
+// half fixed, half random, so concurrent callers spread out without collapsing to zero.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+func (p RetryPolicy) observe(ev RetryEvent) {
+	if p.Observer != nil {
+		p.Observer(ev)
+	}
+}
+
+// parseRetryAfter reads a Retry-After header (delta-seconds or HTTP-date).
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(now); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
@@ -0,0 +1,169 @@
+package adapter
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/fakehis"
+)
+
+func newRetryAdapter(t *testing.T, url string, events *[]RetryEvent) *HospitalAdapter {
+	t.Helper()
+	h, err := NewHospitalAdapterFromConfig(HospitalConfig{
+		HospitalID: "HIS-1",
+		BaseURL:    url,
+		Timeout:    Duration(time.Second),
+		Retry:      &RetryConfig{MaxAttempts: 3, BaseDelay: Duration(time.Millisecond), MaxDelay: Duration(50 * time.Millisecond)},
+	})
+	assert.NoError(t, err)
+	h.retry.Observer = func(ev RetryEvent) { *events = append(*events, ev) }
+	return h
+}
+
+func TestHospitalAdapter_RetriesTransientStatus(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"national_id":"N-1"}`))
+	}))
+	defer ts.Close()
+
+	var events []RetryEvent
+	h := newRetryAdapter(t, ts.URL, &events)
+
+	p, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "N-1", p.NationalID)
+	}
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, RetryScheduled, events[0].Outcome)
+		assert.Equal(t, http.StatusServiceUnavailable, events[0].StatusCode)
+		assert.Equal(t, "HIS-1", events[0].HospitalID)
+		assert.Equal(t, RetryRecovered, events[2].Outcome)
+	}
+}
+
+func TestHospitalAdapter_DoesNotRetryPermanentStatus(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(`{"error":"bad row","national_id":"1234567890123","first_name_en":"Somchai"}`))
+	}))
+	defer ts.Close()
+
+	var events []RetryEvent
+	h := newRetryAdapter(t, ts.URL, &events)
+
+	_, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "hospital api status 500 (Internal Server Error)")
+	assert.NotContains(t, err.Error(), "Somchai", "response bodies may carry PHI and are not logged")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	assert.Empty(t, events)
+}
+
+func TestHospitalAdapter_RetryExhausted(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	var events []RetryEvent
+	h := newRetryAdapter(t, ts.URL, &events)
+
+	_, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.ErrorContains(t, err, "status 502")
+	assert.EqualValues(t, 3, atomic.LoadInt32(&calls))
+	if assert.NotEmpty(t, events) {
+		assert.Equal(t, RetryExhausted, events[len(events)-1].Outcome)
+	}
+}
+
+func TestHospitalAdapter_RetryAfterBeyondDeadlineGivesUp(t *testing.T) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	var events []RetryEvent
+	h := newRetryAdapter(t, ts.URL, &events)
+	h.retry.MaxDelay = 5 * time.Second // allow the 1s Retry-After itself
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := h.LookupByIdentifier(ctx, "N-1")
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), 150*time.Millisecond, "must not sleep past the caller's deadline")
+	assert.EqualValues(t, 1, atomic.LoadInt32(&calls))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, RetryGaveUp, events[0].Outcome)
+		assert.Equal(t, time.Second, events[0].Delay)
+	}
+}
+
+func TestRetryPolicy_OnlyIdempotentMethods(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 3}
+	calls := 0
+	err := p.do(context.Background(), "HIS-1", http.MethodPost, func() error {
+		calls++
+		return &statusError{code: http.StatusServiceUnavailable}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 10*time.Second, parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func TestHospitalAdapter_RecoversFromFakeHISErrorBurst(t *testing.T) {
+	fake := fakehis.New(fakehis.Fixtures{"HIS-1": {{"national_id": "N-1", "first_name_en": "Manop"}}})
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+	assert.NoError(t, fake.SetScenario("HIS-1", fakehis.Scenario{Kind: fakehis.ScenarioError, Status: http.StatusBadGateway, Count: 2}))
+
+	var events []RetryEvent
+	h := newRetryAdapter(t, ts.URL+"/HIS-1", &events)
+
+	p, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "Manop", p.FirstNameEN)
+	}
+	assert.Equal(t, 3, fake.Requests("HIS-1"))
+}
+
+func TestHospitalAdapter_TruncatedFakeHISResponseFails(t *testing.T) {
+	fake := fakehis.New(fakehis.Fixtures{"HIS-1": {{"national_id": "N-1", "first_name_en": "Manop"}}})
+	ts := httptest.NewServer(fake)
+	defer ts.Close()
+	assert.NoError(t, fake.SetScenario("HIS-1", fakehis.Scenario{Kind: fakehis.ScenarioTruncated}))
+
+	var events []RetryEvent
+	h := newRetryAdapter(t, ts.URL+"/HIS-1", &events)
+
+	_, err := h.LookupByIdentifier(context.Background(), "N-1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,299 @@
+// Package fakehis is a fake hospital HIS speaking the "agnos" protocol
+// (GET /patient/search/{id} and the demographic GET /patient/search).
+// It serves patient fixtures for several hospitals under /{hospital_id}/ and
+// can be told to misbehave (latency, 5xx bursts, 429 + Retry-After, truncated
+// JSON) through /_admin/scenarios or SetScenario.
+//
+// In Go tests:
+//
+//	fake := fakehis.New(fakehis.Fixtures{"HIS-1": {{"national_id": "N-1", ...}}})
+//	ts := httptest.NewServer(fake)
+//	defer ts.Close()
+//	a, _ := adapter.NewHospitalAdapter(ts.URL+"/HIS-1", time.Second)
+package fakehis
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fixtures are the patients of each hospital, as JSON objects in the HIS's
+// response format. Lookups match the top-level "national_id" or "passport_id".
+type Fixtures map[string][]map[string]any
+
+// LoadFixtures reads Fixtures from a JSON file ({"HIS-1": [{...}, ...], ...}).
+func LoadFixtures(path string) (Fixtures, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures: %w", err)
+	}
+	var fx Fixtures
+	if err := json.Unmarshal(b, &fx); err != nil {
+		return nil, fmt.Errorf("decode fixtures %s: %w", path, err)
+	}
+	return fx, nil
+}
+
+// Scenario kinds.
+const (
+	ScenarioSlow      = "slow"       // answer after Delay
+	ScenarioError     = "error"      // answer Status (default 503)
+	ScenarioRateLimit = "rate_limit" // answer 429 with Retry-After: RetryAfter seconds (default 1)
+	ScenarioTruncated = "truncated"  // answer 200 with the first half of the JSON body
+)
+
+// Scenario makes a hospital misbehave.
+type Scenario struct {
+	Kind       string
+	Delay      time.Duration
+	Status     int
+	RetryAfter int
+	// Count is how many requests are affected before the hospital recovers; 0 means until reset.
+	Count int
+}
+
+func (sc Scenario) validate() error {
+	switch sc.Kind {
+	case ScenarioSlow:
+		if sc.Delay <= 0 {
+			return fmt.Errorf("scenario %q needs a delay", sc.Kind)
+		}
+	case ScenarioError:
+		if sc.Status != 0 && (sc.Status < 500 || sc.Status > 599) {
+			return fmt.Errorf("scenario %q needs a 5xx status", sc.Kind)
+		}
+	case ScenarioRateLimit, ScenarioTruncated:
+	default:
+		return fmt.Errorf("unknown scenario kind %q", sc.Kind)
+	}
+	if sc.Count < 0 {
+		return fmt.Errorf("count must not be negative")
+	}
+	return nil
+}
+
+// Server is the fake HIS. It implements http.Handler.
+type Server struct {
+	mu        sync.Mutex
+	patients  Fixtures
+	scenarios map[string]*Scenario
+	requests  map[string]int
+	mux       *http.ServeMux
+}
+
+// New constructs a Server serving fx.
+func New(fx Fixtures) *Server {
+	s := &Server{
+		patients:  fx,
+		scenarios: make(map[string]*Scenario),
+		requests:  make(map[string]int),
+		mux:       http.NewServeMux(),
+	}
+	s.mux.HandleFunc("GET /{hospital}/patient/search/{id}", s.handleLookup)
+	s.mux.HandleFunc("GET /{hospital}/patient/search", s.handleSearch)
+	s.mux.HandleFunc("GET /_admin/scenarios", s.handleListScenarios)
+	s.mux.HandleFunc("PUT /_admin/scenarios/{hospital}", s.handleSetScenario)
+	s.mux.HandleFunc("DELETE /_admin/scenarios/{hospital}", s.handleResetScenario)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// SetScenario makes hospitalID misbehave until Count requests were affected or Reset.
+func (s *Server) SetScenario(hospitalID string, sc Scenario) error {
+	if err := sc.validate(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenarios[hospitalID] = &sc
+	return nil
+}
+
+// Reset makes hospitalID behave again.
+func (s *Server) Reset(hospitalID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scenarios, hospitalID)
+}
+
+// Requests returns how many patient requests hospitalID received.
+func (s *Server) Requests(hospitalID string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[hospitalID]
+}
+
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	hid, id := r.PathValue("hospital"), r.PathValue("id")
+	s.serve(w, r, hid, func(patients []map[string]any) (any, bool) {
+		for _, p := range patients {
+			if str(p, "national_id") == id || str(p, "passport_id") == id {
+				return p, true
+			}
+		}
+		return nil, false
+	})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	s.serve(w, r, r.PathValue("hospital"), func(patients []map[string]any) (any, bool) {
+		results := []map[string]any{}
+		for _, p := range patients {
+			if matches(p, q.Get("first_name"), "first_name_en") &&
+				matches(p, q.Get("last_name"), "last_name_en") &&
+				(q.Get("date_of_birth") == "" || str(p, "date_of_birth") == q.Get("date_of_birth")) &&
+				(q.Get("patient_hn") == "" || str(p, "patient_hn") == q.Get("patient_hn")) {
+				results = append(results, p)
+			}
+		}
+		return map[string]any{"results": results}, true
+	})
+}
+
+// serve counts the request, applies the hospital's scenario and writes find's answer.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request, hid string, find func([]map[string]any) (any, bool)) {
+	s.mu.Lock()
+	patients, known := s.patients[hid]
+	s.requests[hid]++
+	sc := s.takeScenario(hid)
+	s.mu.Unlock()
+
+	if !known {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "unknown hospital"})
+		return
+	}
+
+	truncate := false
+	if sc != nil {
+		switch sc.Kind {
+		case ScenarioSlow:
+			select {
+			case <-time.After(sc.Delay):
+			case <-r.Context().Done():
+				return
+			}
+		case ScenarioError:
+			status := sc.Status
+			if status == 0 {
+				status = http.StatusServiceUnavailable
+			}
+			writeJSON(w, status, map[string]string{"error": "scenario error"})
+			return
+		case ScenarioRateLimit:
+			after := sc.RetryAfter
+			if after <= 0 {
+				after = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(after))
+			writeJSON(w, http.StatusTooManyRequests, map[string]string{"error": "rate limited"})
+			return
+		case ScenarioTruncated:
+			truncate = true
+		}
+	}
+
+	body, ok := find(patients)
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	b, _ := json.Marshal(body)
+	if truncate {
+		b = b[:len(b)/2]
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(b)
+}
+
+// takeScenario returns the active scenario and uses up one of its requests. Call with mu held.
+func (s *Server) takeScenario(hid string) *Scenario {
+	sc, ok := s.scenarios[hid]
+	if !ok {
+		return nil
+	}
+	out := *sc
+	if sc.Count > 0 {
+		if sc.Count--; sc.Count == 0 {
+			delete(s.scenarios, hid)
+		}
+	}
+	return &out
+}
+
+// scenarioJSON is the admin API form of a Scenario.
+type scenarioJSON struct {
+	Kind       string `json:"kind"`
+	Delay      string `json:"delay,omitempty"` // e.g. "1500ms"
+	Status     int    `json:"status,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+	Count      int    `json:"count,omitempty"`
+}
+
+func (s *Server) handleListScenarios(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	out := make(map[string]scenarioJSON, len(s.scenarios))
+	for hid, sc := range s.scenarios {
+		out[hid] = scenarioJSON{Kind: sc.Kind, Delay: durationString(sc.Delay), Status: sc.Status, RetryAfter: sc.RetryAfter, Count: sc.Count}
+	}
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) handleSetScenario(w http.ResponseWriter, r *http.Request) {
+	var req scenarioJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request"})
+		return
+	}
+	sc := Scenario{Kind: req.Kind, Status: req.Status, RetryAfter: req.RetryAfter, Count: req.Count}
+	if req.Delay != "" {
+		d, err := time.ParseDuration(req.Delay)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid delay"})
+			return
+		}
+		sc.Delay = d
+	}
+	if err := s.SetScenario(r.PathValue("hospital"), sc); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleResetScenario(w http.ResponseWriter, r *http.Request) {
+	s.Reset(r.PathValue("hospital"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func matches(p map[string]any, want, key string) bool {
+	return want == "" || strings.Contains(strings.ToLower(str(p, key)), strings.ToLower(want))
+}
+
+func str(p map[string]any, key string) string {
+	v, _ := p[key].(string)
+	return v
+}
+
+func durationString(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
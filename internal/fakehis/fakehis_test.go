@@ -0,0 +1,131 @@
+package fakehis
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server) {
+	t.Helper()
+	fake := New(Fixtures{
+		"HIS-1": {
+			{"national_id": "N-1", "first_name_en": "Manop", "last_name_en": "Sukjai", "date_of_birth": "1985-05-05"},
+			{"passport_id": "P-2", "first_name_en": "Somying", "last_name_en": "Jaingam"},
+		},
+	})
+	ts := httptest.NewServer(fake)
+	t.Cleanup(ts.Close)
+	return fake, ts
+}
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return resp, string(b)
+}
+
+func TestLookupByIdentifier(t *testing.T) {
+	fake, ts := newTestServer(t)
+
+	resp, body := get(t, ts.URL+"/HIS-1/patient/search/P-2")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "Somying")
+
+	resp, _ = get(t, ts.URL+"/HIS-1/patient/search/N-404")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = get(t, ts.URL+"/HIS-9/patient/search/N-1")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.Equal(t, 2, fake.Requests("HIS-1"))
+}
+
+func TestDemographicSearch(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	resp, body := get(t, ts.URL+"/HIS-1/patient/search?last_name=sukjai&date_of_birth=1985-05-05")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	var out struct {
+		Results []map[string]any `json:"results"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(body), &out))
+	if assert.Len(t, out.Results, 1) {
+		assert.Equal(t, "N-1", out.Results[0]["national_id"])
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	fake, ts := newTestServer(t)
+
+	assert.NoError(t, fake.SetScenario("HIS-1", Scenario{Kind: ScenarioError, Count: 2}))
+	for i := 0; i < 2; i++ {
+		resp, _ := get(t, ts.URL+"/HIS-1/patient/search/N-1")
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	resp, _ := get(t, ts.URL+"/HIS-1/patient/search/N-1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "recovers after Count requests")
+
+	assert.NoError(t, fake.SetScenario("HIS-1", Scenario{Kind: ScenarioRateLimit, RetryAfter: 7}))
+	resp, _ = get(t, ts.URL+"/HIS-1/patient/search/N-1")
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "7", resp.Header.Get("Retry-After"))
+
+	assert.NoError(t, fake.SetScenario("HIS-1", Scenario{Kind: ScenarioTruncated}))
+	_, body := get(t, ts.URL+"/HIS-1/patient/search/N-1")
+	assert.False(t, json.Valid([]byte(body)))
+
+	assert.NoError(t, fake.SetScenario("HIS-1", Scenario{Kind: ScenarioSlow, Delay: 50 * time.Millisecond, Count: 1}))
+	start := time.Now()
+	resp, _ = get(t, ts.URL+"/HIS-1/patient/search/N-1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	assert.Error(t, fake.SetScenario("HIS-1", Scenario{Kind: "explode"}))
+	assert.Error(t, fake.SetScenario("HIS-1", Scenario{Kind: ScenarioSlow}))
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	_, ts := newTestServer(t)
+
+	do := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodPut, "/_admin/scenarios/HIS-1", `{"kind":"error","status":502}`).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPut, "/_admin/scenarios/HIS-1", `{"kind":"slow","delay":"soon"}`).StatusCode)
+
+	_, body := get(t, ts.URL+"/_admin/scenarios")
+	assert.JSONEq(t, `{"HIS-1":{"kind":"error","status":502}}`, body)
+
+	resp, _ := get(t, ts.URL+"/HIS-1/patient/search/N-1")
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+	assert.Equal(t, http.StatusNoContent, do(http.MethodDelete, "/_admin/scenarios/HIS-1", "").StatusCode)
+	resp, _ = get(t, ts.URL+"/HIS-1/patient/search/N-1")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestLoadFixtures_ExampleFile(t *testing.T) {
+	fx, err := LoadFixtures("../../config/fakehis.example.json")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fx["HIS-1"])
+	assert.NotEmpty(t, fx["HIS-2"])
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+// ConflictReviewer defines the review operations used by the field conflict routes.
+type ConflictReviewer interface {
+	List(ctx context.Context, hospitalID, status string, limit, offset int) ([]*repository.FieldConflict, error)
+	Accept(ctx context.Context, hospitalID, id, staffID string) (*repository.Patient, error)
+	Dismiss(ctx context.Context, hospitalID, id, staffID string) error
+}
+
+// RegisterConflictRoutes registers the review routes for field writes refused
+// by the hospital's survivorship policy. Register them on a group restricted
+// with middleware.RequireRole; every route is scoped to the hospital_id from the JWT.
+func RegisterConflictRoutes(r gin.IRoutes, cr ConflictReviewer) {
+	// GET /v1/admin/conflicts?status=pending
+	r.GET("/v1/admin/conflicts", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		status := c.Query("status")
+		switch status {
+		case "", repository.ConflictPending, repository.ConflictAccepted, repository.ConflictDismissed:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		conflicts, err := cr.List(c.Request.Context(), hid, status, limit, offset)
+		if err != nil {
+			log.Printf("conflicts/list error (hospital=%s): %v", hid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"limit": limit, "offset": offset, "results": conflicts})
+	})
+
+	// POST /v1/admin/conflicts/:id/accept - apply the refused value
+	r.POST("/v1/admin/conflicts/:id/accept", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		p, err := cr.Accept(c.Request.Context(), hid, c.Param("id"), c.GetString("staff_id"))
+		if errors.Is(err, service.ErrConflictNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("conflicts/accept error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusOK, p)
+	})
+
+	// POST /v1/admin/conflicts/:id/dismiss - keep the stored value
+	r.POST("/v1/admin/conflicts/:id/dismiss", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		err := cr.Dismiss(c.Request.Context(), hid, c.Param("id"), c.GetString("staff_id"))
+		if errors.Is(err, service.ErrConflictNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("conflicts/dismiss error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
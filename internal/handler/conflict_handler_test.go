@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+type mockConflicts struct {
+	conflicts []*repository.FieldConflict
+	gotHID    string
+	gotStaff  string
+	err       error
+}
+
+func (m *mockConflicts) List(_ context.Context, hospitalID, status string, limit, offset int) ([]*repository.FieldConflict, error) {
+	m.gotHID = hospitalID
+	return m.conflicts, m.err
+}
+
+func (m *mockConflicts) Accept(_ context.Context, hospitalID, id, staffID string) (*repository.Patient, error) {
+	m.gotHID, m.gotStaff = hospitalID, staffID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &repository.Patient{ID: "p1", PhoneNumber: "0899999999"}, nil
+}
+
+func (m *mockConflicts) Dismiss(_ context.Context, hospitalID, id, staffID string) error {
+	m.gotHID, m.gotStaff = hospitalID, staffID
+	return m.err
+}
+
+func conflictRouter(m *mockConflicts) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Set("staff_id", "admin-1")
+		c.Next()
+	})
+	RegisterConflictRoutes(r, m)
+	return r
+}
+
+func TestConflicts_ListScopedToHospital(t *testing.T) {
+	m := &mockConflicts{conflicts: []*repository.FieldConflict{{ID: "c1", Field: "phone_number", KeptSource: "staff", OverriddenSource: "his"}}}
+	w := httptest.NewRecorder()
+	conflictRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/conflicts?status=pending", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIS-1", m.gotHID)
+	assert.Contains(t, w.Body.String(), `"field":"phone_number"`)
+
+	w = httptest.NewRecorder()
+	conflictRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/conflicts?status=bogus", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestConflicts_AcceptAndDismiss(t *testing.T) {
+	m := &mockConflicts{}
+	w := httptest.NewRecorder()
+	conflictRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/conflicts/c1/accept", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "admin-1", m.gotStaff)
+	assert.Contains(t, w.Body.String(), `"PhoneNumber":"0899999999"`)
+
+	w = httptest.NewRecorder()
+	conflictRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/conflicts/c1/dismiss", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	m.err = service.ErrConflictNotFound
+	w = httptest.NewRecorder()
+	conflictRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/conflicts/c9/accept", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+	"github.com/haniscreator/agnos-search/internal/thaidate"
+)
+
+// parseDOB normalises a date_of_birth from a request (BE or CE, yyyy-mm-dd,
+// dd/mm/yyyy, Thai month names; see thaidate.Parse) to CE yyyy-mm-dd.
+// An empty value is valid and stays empty.
+func parseDOB(s string) (string, bool) {
+	if strings.TrimSpace(s) == "" {
+		return "", true
+	}
+	d, err := thaidate.Parse(s)
+	return d, err == nil
+}
+
+// wantsBE reports whether the caller asked for Buddhist Era dates (?date_era=be).
+func wantsBE(c *gin.Context) bool {
+	return strings.EqualFold(c.Query("date_era"), "be")
+}
+
+// patientBE returns a copy of p whose date_of_birth is dd/mm/yyyy in BE.
+func patientBE(p *repository.Patient) *repository.Patient {
+	if p == nil || p.DateOfBirth == nil {
+		return p
+	}
+	out := *p
+	d := thaidate.FormatBE(*p.DateOfBirth)
+	out.DateOfBirth = &d
+	return &out
+}
+
+// resolvedBE applies patientBE to a resolved patient.
+func resolvedBE(p *service.ResolvedPatient) *service.ResolvedPatient {
+	out := *p
+	out.Patient = patientBE(p.Patient)
+	return &out
+}
+
+// resultsBE applies patientBE to search results (patients or federated hits).
+func resultsBE(results any) any {
+	switch rs := results.(type) {
+	case []*repository.Patient:
+		out := make([]*repository.Patient, len(rs))
+		for i, p := range rs {
+			out[i] = patientBE(p)
+		}
+		return out
+	case []service.SearchHit:
+		out := make([]service.SearchHit, len(rs))
+		for i, h := range rs {
+			out[i] = service.SearchHit{Patient: patientBE(h.Patient), Source: h.Source}
+		}
+		return out
+	}
+	return results
+}
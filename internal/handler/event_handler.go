@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// replayPageSize bounds each outbox read when resuming from Last-Event-ID.
+const replayPageSize = 500
+
+// PatientEventSource provides live and replayed patient change events.
+type PatientEventSource interface {
+	Subscribe(buffer int) (<-chan outbox.Event, func())
+	Since(ctx context.Context, hospitalID string, afterSeq int64, limit int) ([]outbox.Event, error)
+}
+
+// RegisterPatientEventRoutes registers the Server-Sent Events stream of patient changes.
+// Streams end when the client disconnects or when done is closed (server shutdown).
+func RegisterPatientEventRoutes(r gin.IRoutes, src PatientEventSource, heartbeat time.Duration, done <-chan struct{}) {
+	// GET /v1/patients/events
+	// Streams patient.created / patient.updated events of the caller's hospital.
+	// Optional query filters use the same names as POST /patient/search.
+	// Resume with the Last-Event-ID header (or ?last_event_id=) to replay missed events.
+	r.GET("/v1/patients/events", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+
+		lastRaw := c.GetHeader("Last-Event-ID")
+		if lastRaw == "" {
+			lastRaw = c.Query("last_event_id")
+		}
+		var last int64
+		resume := lastRaw != ""
+		if resume {
+			v, err := strconv.ParseInt(lastRaw, 10, 64)
+			if err != nil || v < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+				return
+			}
+			last = v
+		}
+
+		dob, ok := parseDOB(c.Query("date_of_birth"))
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date_of_birth"})
+			return
+		}
+		f := repository.PatientFilters{
+			PatientHN:   c.Query("patient_hn"),
+			NationalID:  c.Query("national_id"),
+			PassportID:  c.Query("passport_id"),
+			FirstName:   c.Query("first_name"),
+			MiddleName:  c.Query("middle_name"),
+			LastName:    c.Query("last_name"),
+			DateOfBirth: dob,
+			PhoneNumber: c.Query("phone_number"),
+			Email:       c.Query("email"),
+		}
+
+		// subscribe before replaying so nothing published in between is lost;
+		// live duplicates of replayed events are skipped by event id below
+		live, cancel := src.Subscribe(256)
+		defer cancel()
+
+		w := c.Writer
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+			return
+		}
+		w.Flush()
+
+		ctx := c.Request.Context()
+
+		// Replayed event ids, so live copies are not sent twice. Sequences alone
+		// are not enough: an event with a lower outbox id can commit (and arrive
+		// live) after a higher one. The relay releases in id order, so once a
+		// live event passes the highest replayed sequence every live copy has
+		// been seen and the ids are dropped.
+		replayed := map[string]struct{}{}
+		var replayedMax int64
+		if resume {
+			for {
+				evs, err := src.Since(ctx, hid, last, replayPageSize)
+				if err != nil {
+					log.Printf("patients/events replay error (hospital=%s, after=%d): %v", hid, last, err)
+					return
+				}
+				for _, ev := range evs {
+					last = ev.Sequence
+					replayed[ev.EventID] = struct{}{}
+					replayedMax = ev.Sequence
+					if !eventMatches(ev, f) {
+						continue
+					}
+					if err := writeEvent(w, ev, last); err != nil {
+						return
+					}
+				}
+				w.Flush()
+				if len(evs) < replayPageSize {
+					break
+				}
+			}
+		}
+
+		tick := time.NewTicker(heartbeat)
+		defer tick.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case ev, ok := <-live:
+				if !ok {
+					// dropped by the broker as too slow; client reconnects with Last-Event-ID
+					return
+				}
+				if ev.HospitalID != hid {
+					continue
+				}
+				if ev.Sequence > replayedMax {
+					replayed = nil
+				} else if _, dup := replayed[ev.EventID]; dup {
+					delete(replayed, ev.EventID)
+					continue
+				}
+				last = max(last, ev.Sequence)
+				if !eventMatches(ev, f) {
+					continue
+				}
+				if err := writeEvent(w, ev, last); err != nil {
+					return
+				}
+				w.Flush()
+			case <-tick.C:
+				if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+					return
+				}
+				w.Flush()
+			}
+		}
+	})
+}
+
+// writeEvent writes one SSE frame. Its id is the resume cursor, the highest
+// sequence sent on the stream so far: an event released late with a lower
+// sequence must not move Last-Event-ID back and replay what was already sent.
+func writeEvent(w gin.ResponseWriter, ev outbox.Event, cursor int64) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", cursor, ev.Type, data)
+	return err
+}
+
+func eventMatches(ev outbox.Event, f repository.PatientFilters) bool {
+	if f == (repository.PatientFilters{}) {
+		return true
+	}
+	var p repository.Patient
+	if err := json.Unmarshal(ev.Patient, &p); err != nil {
+		return false
+	}
+	return f.Matches(&p)
+}
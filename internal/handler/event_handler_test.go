@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// fakeEventSource replays a fixed history and serves a pre-filled live channel.
+type fakeEventSource struct {
+	history   []outbox.Event
+	live      chan outbox.Event
+	sinceFrom int64
+}
+
+func (f *fakeEventSource) Subscribe(_ int) (<-chan outbox.Event, func()) {
+	return f.live, func() {}
+}
+
+func (f *fakeEventSource) Since(_ context.Context, hospitalID string, afterSeq int64, _ int) ([]outbox.Event, error) {
+	f.sinceFrom = afterSeq
+	var out []outbox.Event
+	for _, e := range f.history {
+		if e.HospitalID == hospitalID && e.Sequence > afterSeq {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func patientEvent(seq int64, hid, nationalID string) outbox.Event {
+	b, _ := json.Marshal(&repository.Patient{ID: "p", NationalID: nationalID, HospitalID: hid})
+	return outbox.Event{EventID: fmt.Sprintf("e%d", seq), Sequence: seq, Type: outbox.EventPatientCreated, HospitalID: hid, Patient: b}
+}
+
+func TestPatientEvents_ResumeFilterAndDedupe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	src := &fakeEventSource{
+		history: []outbox.Event{
+			patientEvent(3, "HIS-1", "N-1"),
+			patientEvent(4, "HIS-1", "N-2"), // filtered out
+			patientEvent(5, "HIS-1", "N-1"),
+		},
+		live: make(chan outbox.Event, 5),
+	}
+	src.live <- patientEvent(5, "HIS-1", "N-1") // already replayed
+	src.live <- patientEvent(6, "HIS-2", "N-1") // other hospital
+	src.live <- patientEvent(8, "HIS-1", "N-1")
+	src.live <- patientEvent(7, "HIS-1", "N-1") // committed after 8
+
+	done := make(chan struct{})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterPatientEventRoutes(r, src, time.Hour, done)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patients/events?national_id=N-1", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	w := httptest.NewRecorder()
+
+	time.AfterFunc(100*time.Millisecond, func() { close(done) })
+	r.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	assert.Equal(t, int64(2), src.sinceFrom)
+	// a late lower sequence is still sent, without moving the resume cursor back
+	assert.Equal(t, []string{"id: 3", "id: 5", "id: 8", "id: 8"}, eventIDs(body))
+	assert.Contains(t, body, `"sequence":7`)
+}
+
+func TestPatientEvents_Heartbeat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	src := &fakeEventSource{live: make(chan outbox.Event)}
+	done := make(chan struct{})
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterPatientEventRoutes(r, src, 10*time.Millisecond, done)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patients/events", nil)
+	w := httptest.NewRecorder()
+
+	time.AfterFunc(60*time.Millisecond, func() { close(done) })
+	r.ServeHTTP(w, req)
+
+	assert.Contains(t, w.Body.String(), ": heartbeat")
+}
+
+func TestPatientEvents_InvalidLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterPatientEventRoutes(r, &fakeEventSource{live: make(chan outbox.Event)}, time.Hour, make(chan struct{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patients/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func eventIDs(body string) []string {
+	var ids []string
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, line)
+		}
+	}
+	return ids
+}
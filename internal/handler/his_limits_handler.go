@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+)
+
+// LimiterStatser reports the outbound HIS limiters per hospital.
+type LimiterStatser interface {
+	LimiterStats() map[string]adapter.LimiterStats
+}
+
+// RegisterHISLimitRoutes registers GET /v1/admin/his-limits. Register it on
+// a group restricted with middleware.RequireRole. Admins only see their own
+// hospital's limiter.
+func RegisterHISLimitRoutes(r gin.IRoutes, l LimiterStatser) {
+	r.GET("/v1/admin/his-limits", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		hospitals := map[string]adapter.LimiterStats{}
+		if st, ok := l.LimiterStats()[hid]; ok {
+			hospitals[hid] = st
+		}
+		c.JSON(http.StatusOK, gin.H{"hospitals": hospitals})
+	})
+}
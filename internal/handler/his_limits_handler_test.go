@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+)
+
+type fakeLimiterStats map[string]adapter.LimiterStats
+
+func (f fakeLimiterStats) LimiterStats() map[string]adapter.LimiterStats { return f }
+
+func TestHISLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-2")
+		c.Next()
+	})
+	RegisterHISLimitRoutes(r, fakeLimiterStats{
+		"HIS-2": {Mode: adapter.LimitModeQueue, MaxInFlight: 4, InFlight: 3, Saturation: 0.75},
+		"HIS-3": {Mode: adapter.LimitModeFailFast, MaxInFlight: 2},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/his-limits", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"HIS-2":{`)
+	assert.Contains(t, w.Body.String(), `"saturation":0.75`)
+	assert.NotContains(t, w.Body.String(), "HIS-3", "other hospitals are not listed")
+}
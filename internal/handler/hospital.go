@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requireHospital returns the hospital_id set by the JWT middleware.
+// If it is missing it writes a 401 and returns false.
+func requireHospital(c *gin.Context) (string, bool) {
+	hv, ok := c.Get("hospital_id")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "missing hospital in token"})
+		return "", false
+	}
+	hid, ok := hv.(string)
+	if !ok || hid == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid hospital in token"})
+		return "", false
+	}
+	return hid, true
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/linkage"
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+// DuplicateFinder finds stored patients that are probably the same person
+// (used for duplicate warnings on POST /v1/patients).
+type DuplicateFinder interface {
+	Candidates(ctx context.Context, hospitalID string, p *repository.Patient) ([]linkage.Candidate, error)
+}
+
+// MergeCandidateFinder defines the record linkage operations of the admin routes.
+type MergeCandidateFinder interface {
+	MergeCandidates(ctx context.Context, hospitalID, patientID string) (*repository.Patient, []linkage.Candidate, error)
+	Flagged(ctx context.Context, hospitalID string, limit, offset int) ([]repository.PatientLink, error)
+}
+
+// createdPatient is the POST /v1/patients response: the stored patient plus
+// likely duplicates the caller may want to merge instead.
+type createdPatient struct {
+	*repository.Patient
+	PossibleDuplicates []linkage.Candidate `json:"possible_duplicates,omitempty"`
+}
+
+// RegisterLinkageRoutes registers the merge candidate routes. Register them
+// on a group restricted with middleware.RequireRole; every route is scoped
+// to the hospital_id from the JWT.
+func RegisterLinkageRoutes(r gin.IRoutes, l MergeCandidateFinder) {
+	// GET /v1/admin/patients/:id/merge-candidates - scored now, best first
+	r.GET("/v1/admin/patients/:id/merge-candidates", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		p, cands, err := l.MergeCandidates(c.Request.Context(), hid, c.Param("id"))
+		if errors.Is(err, service.ErrPatientNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("merge-candidates error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		if cands == nil {
+			cands = []linkage.Candidate{}
+		}
+		c.JSON(http.StatusOK, gin.H{"patient": p, "candidates": cands})
+	})
+
+	// GET /v1/admin/duplicates - likely duplicates flagged during HIS / HL7 ingestion
+	r.GET("/v1/admin/duplicates", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		links, err := l.Flagged(c.Request.Context(), hid, limit, offset)
+		if err != nil {
+			log.Printf("duplicates/list error (hospital=%s): %v", hid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		if links == nil {
+			links = []repository.PatientLink{}
+		}
+		c.JSON(http.StatusOK, gin.H{"limit": limit, "offset": offset, "results": links})
+	})
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/linkage"
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+type mockLinker struct {
+	cands  []linkage.Candidate
+	links  []repository.PatientLink
+	gotHID string
+	err    error
+}
+
+func (m *mockLinker) Candidates(_ context.Context, hospitalID string, p *repository.Patient) ([]linkage.Candidate, error) {
+	m.gotHID = hospitalID
+	return m.cands, m.err
+}
+
+func (m *mockLinker) MergeCandidates(_ context.Context, hospitalID, patientID string) (*repository.Patient, []linkage.Candidate, error) {
+	m.gotHID = hospitalID
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	return &repository.Patient{ID: patientID, HospitalID: hospitalID}, m.cands, nil
+}
+
+func (m *mockLinker) Flagged(_ context.Context, hospitalID string, limit, offset int) ([]repository.PatientLink, error) {
+	m.gotHID = hospitalID
+	return m.links, m.err
+}
+
+// mockWriter satisfies PatientWriter.
+type mockWriter struct{ err error }
+
+func (m *mockWriter) Upsert(_ context.Context, p *repository.Patient) error { return m.err }
+
+func linkageRouter(register func(r *gin.Engine)) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Set("staff_id", "staff-1")
+		c.Next()
+	})
+	register(r)
+	return r
+}
+
+func TestCreatePatient_WarnsAboutPossibleDuplicates(t *testing.T) {
+	m := &mockLinker{cands: []linkage.Candidate{{
+		Patient: &repository.Patient{ID: "p-old", PassportID: "AB1234567"},
+		Result:  linkage.Result{Score: 31.5, Decision: linkage.DecisionMatch},
+	}}}
+	r := linkageRouter(func(r *gin.Engine) { RegisterPatientWriteRoutes(r, &mockWriter{}, m) })
+
+	body := `{"passport_id":"AB1234576","first_name_en":"Somchai","last_name_en":"Jaidee"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/patients", strings.NewReader(body)))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "HIS-1", m.gotHID)
+	assert.Contains(t, w.Body.String(), `"PassportID":"AB1234576"`)
+	assert.Contains(t, w.Body.String(), `"possible_duplicates":[{"ID":"p-old"`)
+	assert.Contains(t, w.Body.String(), `"decision":"match"`)
+
+	// no candidates, no key
+	m.cands = nil
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/patients", strings.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "possible_duplicates")
+}
+
+func TestMergeCandidates(t *testing.T) {
+	m := &mockLinker{cands: []linkage.Candidate{{
+		Patient: &repository.Patient{ID: "p-2"},
+		Result:  linkage.Result{Score: 12, Decision: linkage.DecisionPossible},
+	}}}
+	r := linkageRouter(func(r *gin.Engine) { RegisterLinkageRoutes(r, m) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/patients/p-1/merge-candidates", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIS-1", m.gotHID)
+	assert.Contains(t, w.Body.String(), `"candidates":[{"ID":"p-2"`)
+
+	m.err = service.ErrPatientNotFound
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/patients/p-9/merge-candidates", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFlaggedDuplicates(t *testing.T) {
+	m := &mockLinker{links: []repository.PatientLink{{PatientID: "p-new", CandidateID: "p-old", Decision: linkage.DecisionMatch}}}
+	r := linkageRouter(func(r *gin.Engine) { RegisterLinkageRoutes(r, m) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/duplicates", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIS-1", m.gotHID)
+	assert.Contains(t, w.Body.String(), `"candidate_id":"p-old"`)
+}
@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+// NetworkLookuper is the service used by the network lookup route.
+type NetworkLookuper interface {
+	Lookup(ctx context.Context, identifier string) *service.NetworkResult
+}
+
+// RegisterNetworkRoutes registers cross-hospital lookup routes. Register them on
+// a group restricted with middleware.RequireRole: they reveal patients of other hospitals.
+// analytics can be nil if audit logging is not desired.
+func RegisterNetworkRoutes(r gin.IRoutes, lookup NetworkLookuper, analytics repository.AnalyticsRepo) {
+	// GET /v1/network/patient/:id
+	// Asks every hospital's HIS for national_id / passport_id :id at once.
+	// Always 200: hospitals that did not answer in time have status "timeout"
+	// and "complete" is false.
+	r.GET("/v1/network/patient/:id", func(c *gin.Context) {
+		identifier := c.Param("id")
+		if identifier == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+			return
+		}
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+
+		res := lookup.Lookup(c.Request.Context(), identifier)
+
+		// audit under the caller's hospital, like the other searches; the
+		// lookup reveals other hospitals' patients, so it is not answered
+		// unless the audit is stored
+		if analytics != nil {
+			sid := c.GetString("staff_id")
+			if err := analytics.LogSearch(c.Request.Context(), sid, hid, res.AuditFilters(identifier), res.Found); err != nil {
+				log.Printf("network lookup analytics error (staff_id=%s, hospital=%s): %v", sid, hid, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"identifier": identifier,
+			"found":      res.Found,
+			"complete":   res.Complete,
+			"hospitals":  res.Hospitals,
+		})
+	})
+}
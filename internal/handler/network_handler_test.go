@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/middleware"
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+type mockNetworkLookup struct {
+	res   *service.NetworkResult
+	calls int
+}
+
+func (m *mockNetworkLookup) Lookup(_ context.Context, _ string) *service.NetworkResult {
+	m.calls++
+	return m.res
+}
+
+type failingAnalytics struct{}
+
+func (failingAnalytics) LogSearch(context.Context, string, string, repository.PatientFilters, int) error {
+	return errors.New("db down")
+}
+
+func networkRouter(role string, m *mockNetworkLookup) *gin.Engine {
+	return networkRouterWithAudit(role, m, nil)
+}
+
+func networkRouterWithAudit(role string, m *mockNetworkLookup, analytics repository.AnalyticsRepo) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Set("role", role)
+		c.Set("staff_id", "staff-1")
+		c.Next()
+	})
+	g := r.Group("/", middleware.RequireRole("admin", "er"))
+	RegisterNetworkRoutes(g, m, analytics)
+	return r
+}
+
+func TestNetworkLookup_PartialResults(t *testing.T) {
+	m := &mockNetworkLookup{res: &service.NetworkResult{
+		Hospitals: []service.HospitalLookup{
+			{HospitalID: "HIS-1", Status: service.NetworkFound, Patient: &repository.Patient{NationalID: "N-1"}},
+			{HospitalID: "HIS-2", Status: service.NetworkTimeout},
+		},
+		Found: 1,
+	}}
+	w := httptest.NewRecorder()
+	networkRouter("er", m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/network/patient/N-1", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"complete":false`)
+	assert.Contains(t, w.Body.String(), `"status":"timeout"`)
+	assert.Contains(t, w.Body.String(), `"found":1`)
+}
+
+func TestNetworkLookup_RoleRequired(t *testing.T) {
+	m := &mockNetworkLookup{}
+	w := httptest.NewRecorder()
+	networkRouter("staff", m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/network/patient/N-1", nil))
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, 0, m.calls)
+}
+
+func TestNetworkLookup_AuditsPassport(t *testing.T) {
+	m := &mockNetworkLookup{res: &service.NetworkResult{
+		Hospitals: []service.HospitalLookup{
+			{HospitalID: "HIS-2", Status: service.NetworkFound, Patient: &repository.Patient{PassportID: "P-1"}},
+		},
+		Found: 1,
+	}}
+	ma := &mockAnalytics{}
+	w := httptest.NewRecorder()
+	networkRouterWithAudit("er", m, ma).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/network/patient/P-1", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	// written before the response, no waiting
+	assert.True(t, ma.called)
+	assert.Equal(t, repository.PatientFilters{PassportID: "P-1"}, ma.lastFilter)
+	assert.Equal(t, "HIS-1", ma.lastHosp)
+	assert.Equal(t, "staff-1", ma.lastStaff)
+	assert.Equal(t, 1, ma.lastCount)
+}
+
+func TestNetworkLookup_AuditFailure(t *testing.T) {
+	m := &mockNetworkLookup{res: &service.NetworkResult{Complete: true}}
+	w := httptest.NewRecorder()
+	networkRouterWithAudit("er", m, failingAnalytics{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/network/patient/N-1", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "hospitals")
+}
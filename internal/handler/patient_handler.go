@@ -5,18 +5,22 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgconn"
 
 	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
 )
 
 // PatientService defines the minimal service used by the handlers.
 type PatientService interface {
-	Get(ctx context.Context, identifier string) (*repository.Patient, error)
+	Resolve(ctx context.Context, hospitalID, identifier string) (*service.ResolvedPatient, error)
 	Search(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) ([]*repository.Patient, int, error)
+	SearchFederated(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) (*service.FederatedResult, error)
+	Provenance(ctx context.Context, patientID string) (map[string]repository.FieldProvenance, error)
 }
 
 // PatientWriter defines minimal write operations for patients (used by create endpoint).
@@ -25,11 +29,14 @@ type PatientWriter interface {
 }
 
 // RegisterPatientRoutes registers patient-related routes on the provided Gin router.
-// Note: analytics can be nil if audit logging is not desired.
+// Note: analytics can be nil if audit logging is not desired. Identifier
+// lookups are audited by the service.
 func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics repository.AnalyticsRepo) {
-	// GET /v1/patient/search/:id
-	// id can be either national_id or passport_id.
-	r.GET("/v1/patient/search/:id", func(c *gin.Context) {
+	// GET /v1/patient/search/:id and GET /v1/patient/:id
+	// id can be either national_id or passport_id. Both resolve within the
+	// hospital from the JWT: local DB first, then the hospital's HIS.
+	// include=provenance adds the source, actor and time of each field's last write.
+	resolve := func(c *gin.Context) {
 		identifier := c.Param("id")
 		if identifier == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
@@ -48,87 +55,29 @@ func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics reposito
 			return
 		}
 
-		limit := 1
-		offset := 0
-
-		var (
-			results     []*repository.Patient
-			total       int
-			err         error
-			filtersUsed repository.PatientFilters
-		)
-
-		// 1) Try as national_id
-		fNat := repository.PatientFilters{
-			NationalID: identifier,
-		}
-		results, total, err = svc.Search(c.Request.Context(), hid, fNat, limit, offset)
-		if err != nil {
-			log.Printf(
-				"patient/search-by-id service error (hospital=%s, identifier=%s as national_id): %v",
-				hid, identifier, err,
-			)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+		p, err := svc.Resolve(c.Request.Context(), hid, identifier)
+		if errors.Is(err, service.ErrNoHIS) {
+			// not in our DB and the caller's hospital has no HIS to ask
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found", "his_status": "not_configured"})
 			return
 		}
-
-		if total > 0 && len(results) > 0 {
-			filtersUsed = fNat
-		} else {
-			// 2) If no result, try as passport_id
-			fPass := repository.PatientFilters{
-				PassportID: identifier,
-			}
-			results, total, err = svc.Search(c.Request.Context(), hid, fPass, limit, offset)
-			if err != nil {
-				log.Printf(
-					"patient/search-by-id service error (hospital=%s, identifier=%s as passport_id): %v",
-					hid, identifier, err,
-				)
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
-				return
-			}
-			if total > 0 && len(results) > 0 {
-				filtersUsed = fPass
-			}
-		}
-
-		if total == 0 || len(results) == 0 {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		if errors.Is(err, service.ErrAmbiguousMatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": "identifier matches more than one patient at the hospital HIS"})
 			return
 		}
-
-		// Log audit event if analytics repo provided (same style as POST /patient/search).
-		if analytics != nil {
-			if staffVal, ok := c.Get("staff_id"); ok {
-				if staffID, _ok := staffVal.(string); _ok {
-					go func() {
-						if err := analytics.LogSearch(context.Background(), staffID, hid, filtersUsed, total); err != nil {
-							log.Printf(
-								"patient/search-by-id analytics error (staff_id=%s, hospital=%s): %v",
-								staffID, hid, err,
-							)
-						}
-					}()
-				}
-			}
+		if errors.Is(err, service.ErrQuarantined) {
+			// the HIS record failed validation and awaits an admin's review
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found", "his_status": "quarantined"})
+			return
 		}
-
-		// Return the first matched patient
-		c.JSON(http.StatusOK, results[0])
-	})
-
-	// GET /v1/patient/:id
-	r.GET("/v1/patient/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		if id == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "id is required"})
+		if errors.Is(err, service.ErrHISUnavailable) {
+			// serve what the DB has (nothing) and flag it instead of failing the request
+			log.Printf("patient/resolve degraded (hospital=%s, id=%s): %v", hid, identifier, err)
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found", "his_status": "unavailable", "degraded": true})
 			return
 		}
-
-		p, err := svc.Get(c.Request.Context(), id)
 		if err != nil {
-			log.Printf("patient/get error (id=%s): %v", id, err)
+			log.Printf("patient/resolve error (hospital=%s, id=%s): %v", hid, identifier, err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
 			return
 		}
@@ -137,24 +86,22 @@ func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics reposito
 			return
 		}
 
-		hidVal, exists := c.Get("hospital_id")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "missing hospital in token"})
-			return
-		}
-		hid, ok := hidVal.(string)
-		if !ok || hid == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid hospital in token"})
-			return
+		if includes(c, "provenance") {
+			prov, err := svc.Provenance(c.Request.Context(), p.ID)
+			if err != nil {
+				log.Printf("patient/resolve provenance error (hospital=%s, patient=%s): %v", hid, p.ID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal error"})
+				return
+			}
+			p.Provenance = prov
 		}
-
-		if p.HospitalID != "" && p.HospitalID != hid {
-			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
-			return
+		if wantsBE(c) {
+			p = resolvedBE(p)
 		}
-
 		c.JSON(http.StatusOK, p)
-	})
+	}
+	r.GET("/v1/patient/search/:id", resolve)
+	r.GET("/v1/patient/:id", resolve)
 
 	// POST /patient/search
 	r.POST("/patient/search", func(c *gin.Context) {
@@ -183,6 +130,7 @@ func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics reposito
 			Email       string `json:"email"`
 			Limit       int    `json:"limit"`
 			Offset      int    `json:"offset"`
+			Federated   bool   `json:"federated"` // also ask the hospital's HIS when local results are few
 		}
 		if err := c.BindJSON(&req); err != nil {
 			log.Printf("patient/search bind error: %v", err)
@@ -192,6 +140,11 @@ func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics reposito
 		if req.Limit == 0 {
 			req.Limit = 10
 		}
+		dob, ok := parseDOB(req.DateOfBirth)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date_of_birth"})
+			return
+		}
 
 		// Prefer *_en if provided; fall back to generic
 		effectiveFirstName := req.FirstName
@@ -210,12 +163,25 @@ func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics reposito
 			FirstName:   effectiveFirstName,
 			MiddleName:  req.MiddleName,
 			LastName:    effectiveLastName,
-			DateOfBirth: req.DateOfBirth,
+			DateOfBirth: dob,
 			PhoneNumber: req.PhoneNumber,
 			Email:       req.Email,
 		}
 
-		results, total, err := svc.Search(c.Request.Context(), hid, f, req.Limit, req.Offset)
+		var (
+			results any
+			total   int
+			fed     *service.FederatedResult
+			err     error
+		)
+		if req.Federated {
+			fed, err = svc.SearchFederated(c.Request.Context(), hid, f, req.Limit, req.Offset)
+			if fed != nil {
+				results, total = fed.Results, fed.Total
+			}
+		} else {
+			results, total, err = svc.Search(c.Request.Context(), hid, f, req.Limit, req.Offset)
+		}
 		if err != nil {
 			log.Printf(
 				"patient/search service error (hospital=%s, filters=%+v, limit=%d, offset=%d): %v",
@@ -243,17 +209,25 @@ func RegisterPatientRoutes(r gin.IRoutes, svc PatientService, analytics reposito
 			}
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		if wantsBE(c) {
+			results = resultsBE(results)
+		}
+		resp := gin.H{
 			"count":   total,
 			"limit":   req.Limit,
 			"offset":  req.Offset,
 			"results": results,
-		})
+		}
+		if fed != nil {
+			resp["his_status"] = fed.HISStatus
+		}
+		c.JSON(http.StatusOK, resp)
 	})
 }
 
 // RegisterPatientWriteRoutes registers write endpoints like POST /v1/patients.
-func RegisterPatientWriteRoutes(r gin.IRoutes, writer PatientWriter) {
+// dups (may be nil) adds possible_duplicates to the create response.
+func RegisterPatientWriteRoutes(r gin.IRoutes, writer PatientWriter, dups DuplicateFinder) {
 	// POST /v1/patients - create (or upsert) a patient
 	r.POST("/v1/patients", func(c *gin.Context) {
 		// Get hospital from JWT (already set by AuthMiddleware)
@@ -279,7 +253,7 @@ func RegisterPatientWriteRoutes(r gin.IRoutes, writer PatientWriter) {
 			FirstNameEN  string `json:"first_name_en"`
 			MiddleNameEN string `json:"middle_name_en"`
 			LastNameEN   string `json:"last_name_en"`
-			DateOfBirth  string `json:"date_of_birth"` // yyyy-mm-dd
+			DateOfBirth  string `json:"date_of_birth"` // yyyy-mm-dd or dd/mm/yyyy, BE or CE
 			PhoneNumber  string `json:"phone_number"`
 			Email        string `json:"email"`
 			Gender       string `json:"gender"` // "M", "F", etc.
@@ -300,7 +274,11 @@ func RegisterPatientWriteRoutes(r gin.IRoutes, writer PatientWriter) {
 		// Map to repository.Patient
 		var dob *string
 		if req.DateOfBirth != "" {
-			d := req.DateOfBirth
+			d, ok := parseDOB(req.DateOfBirth)
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date_of_birth"})
+				return
+			}
 			dob = &d
 		}
 
@@ -341,6 +319,25 @@ func RegisterPatientWriteRoutes(r gin.IRoutes, writer PatientWriter) {
 			return
 		}
 
-		c.JSON(http.StatusCreated, p)
+		resp := createdPatient{Patient: p}
+		if dups != nil {
+			// best-effort warning: the patient is stored either way
+			cands, err := dups.Candidates(c.Request.Context(), hid, p)
+			if err != nil {
+				log.Printf("patient/create linkage error (hospital=%s, id=%s): %v", hid, p.ID, err)
+			}
+			resp.PossibleDuplicates = cands
+		}
+		c.JSON(http.StatusCreated, resp)
 	})
 }
+
+// includes reports whether the comma-separated include query parameter names part.
+func includes(c *gin.Context, part string) bool {
+	for _, v := range strings.Split(c.Query("include"), ",") {
+		if strings.EqualFold(strings.TrimSpace(v), part) {
+			return true
+		}
+	}
+	return false
+}
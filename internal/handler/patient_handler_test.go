@@ -2,32 +2,52 @@ package handler
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
 )
 
-// mockService satisfies PatientService (Get + Search)
+// mockService satisfies PatientService (Resolve + Search)
 type mockService struct {
 	out   *repository.Patient
 	sout  []*repository.Patient
 	total int
+	fed   *service.FederatedResult
+	prov  map[string]repository.FieldProvenance
 	err   error
+	hid   string // hospital the last Resolve was scoped to
+	provs int    // Provenance calls
 }
 
-func (m *mockService) Get(_ context.Context, identifier string) (*repository.Patient, error) {
-	return m.out, m.err
+func (m *mockService) Resolve(_ context.Context, hospitalID, identifier string) (*service.ResolvedPatient, error) {
+	m.hid = hospitalID
+	if m.out == nil {
+		return nil, m.err
+	}
+	return &service.ResolvedPatient{Patient: m.out, Freshness: service.FreshnessLocal}, m.err
 }
 
 func (m *mockService) Search(_ context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) ([]*repository.Patient, int, error) {
 	return m.sout, m.total, m.err
 }
 
+func (m *mockService) SearchFederated(_ context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) (*service.FederatedResult, error) {
+	return m.fed, m.err
+}
+
+func (m *mockService) Provenance(_ context.Context, patientID string) (map[string]repository.FieldProvenance, error) {
+	m.provs++
+	return m.prov, m.err
+}
+
 // setupRouterWithMock returns a new Gin engine for tests.
 // It does NOT register routes so tests can set middleware before registration.
 func setupRouterWithMock(m PatientService) *gin.Engine {
@@ -74,6 +94,7 @@ func TestGetPatient_Found(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Somchai")
 	assert.Contains(t, w.Body.String(), "HN-123")
+	assert.Contains(t, w.Body.String(), `"freshness":"local"`)
 }
 
 func TestGetPatient_NotFound(t *testing.T) {
@@ -113,6 +134,63 @@ func TestGetPatient_Error(t *testing.T) {
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 }
 
+func TestGetPatient_HospitalWithoutHIS(t *testing.T) {
+	mock := &mockService{out: nil, err: fmt.Errorf("adapter resolve: %w", service.ErrNoHIS)}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-9")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patient/N-404", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"his_status":"not_configured"`)
+}
+
+func TestGetPatient_HISUnavailableIsDegraded(t *testing.T) {
+	mock := &mockService{out: nil, err: fmt.Errorf("adapter lookup: %w", service.ErrHISUnavailable)}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patient/N-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"his_status":"unavailable"`)
+	assert.Contains(t, w.Body.String(), `"degraded":true`)
+}
+
+func TestGetPatient_AmbiguousHISMatch(t *testing.T) {
+	mock := &mockService{out: nil, err: fmt.Errorf("adapter lookup: %w", service.ErrAmbiguousMatch)}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patient/N-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
 // helpers
 func strptr(s string) *string { return &s }
 func errExample() error       { return &customErr{"boom"} }
@@ -120,3 +198,92 @@ func errExample() error       { return &customErr{"boom"} }
 type customErr struct{ s string }
 
 func (e *customErr) Error() string { return e.s }
+
+func TestGetPatient_QuarantinedHISRecord(t *testing.T) {
+	mock := &mockService{out: nil, err: fmt.Errorf("%w: name: missing", service.ErrQuarantined)}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patient/N-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"his_status":"quarantined"`)
+}
+
+func TestGetPatient_BuddhistEraDates(t *testing.T) {
+	mock := &mockService{out: &repository.Patient{ID: "uuid-1", NationalID: "N-1", DateOfBirth: strptr("1990-01-01"), HospitalID: "HIS-1"}}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patient/N-1?date_era=be", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"DateOfBirth":"01/01/2533"`)
+}
+
+func TestGetPatient_IncludeProvenance(t *testing.T) {
+	at := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	mock := &mockService{
+		out: &repository.Patient{ID: "uuid-1", NationalID: "N-1", Email: "a@example.com", HospitalID: "HIS-1"},
+		prov: map[string]repository.FieldProvenance{
+			"email":       {Source: repository.SourceStaff, Actor: "staff-7", UpdatedAt: at},
+			"national_id": {Source: repository.SourceHIS, UpdatedAt: at},
+		},
+	}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/patient/N-1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "provenance")
+	assert.Equal(t, 0, mock.provs)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/patient/search/N-1?include=provenance&date_era=be", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"email":{"source":"staff","actor":"staff-7","updated_at":"2026-10-01T09:30:00Z"}`)
+	assert.Contains(t, w.Body.String(), `"national_id":{"source":"his","updated_at":"2026-10-01T09:30:00Z"}`)
+}
+
+func TestSearchByID_ResolvesWithinTokenHospital(t *testing.T) {
+	mock := &mockService{err: fmt.Errorf("adapter lookup: %w", service.ErrHISUnavailable)}
+	r := setupRouterWithMock(mock)
+
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-2")
+		c.Next()
+	})
+
+	RegisterPatientRoutes(r, mock, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/patient/search/P-1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, "HIS-2", mock.hid)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), `"his_status":"unavailable"`)
+}
@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+// QuarantineReviewer defines the review operations used by the quarantine routes.
+type QuarantineReviewer interface {
+	List(ctx context.Context, hospitalID, status string, limit, offset int) ([]*repository.QuarantinedRecord, error)
+	Release(ctx context.Context, hospitalID, id, staffID string) (*repository.Patient, error)
+	Reject(ctx context.Context, hospitalID, id, staffID string) error
+}
+
+// RegisterQuarantineRoutes registers the review routes for quarantined HIS
+// records. Register them on a group restricted with middleware.RequireRole;
+// every route is scoped to the hospital_id from the JWT.
+func RegisterQuarantineRoutes(r gin.IRoutes, q QuarantineReviewer) {
+	// GET /v1/admin/quarantine?status=pending
+	r.GET("/v1/admin/quarantine", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		status := c.Query("status")
+		switch status {
+		case "", repository.QuarantinePending, repository.QuarantineReleased, repository.QuarantineRejected:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+		recs, err := q.List(c.Request.Context(), hid, status, limit, offset)
+		if err != nil {
+			log.Printf("quarantine/list error (hospital=%s): %v", hid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"limit": limit, "offset": offset, "results": recs})
+	})
+
+	// POST /v1/admin/quarantine/:id/release - store the record as received
+	r.POST("/v1/admin/quarantine/:id/release", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		p, err := q.Release(c.Request.Context(), hid, c.Param("id"), c.GetString("staff_id"))
+		if errors.Is(err, service.ErrQuarantineNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("quarantine/release error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusOK, p)
+	})
+
+	// POST /v1/admin/quarantine/:id/reject
+	r.POST("/v1/admin/quarantine/:id/reject", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		err := q.Reject(c.Request.Context(), hid, c.Param("id"), c.GetString("staff_id"))
+		if errors.Is(err, service.ErrQuarantineNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("quarantine/reject error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
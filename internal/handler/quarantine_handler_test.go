@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+type mockQuarantine struct {
+	recs     []*repository.QuarantinedRecord
+	gotHID   string
+	gotStaff string
+	err      error
+}
+
+func (m *mockQuarantine) List(_ context.Context, hospitalID, status string, limit, offset int) ([]*repository.QuarantinedRecord, error) {
+	m.gotHID = hospitalID
+	return m.recs, m.err
+}
+
+func (m *mockQuarantine) Release(_ context.Context, hospitalID, id, staffID string) (*repository.Patient, error) {
+	m.gotHID, m.gotStaff = hospitalID, staffID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &repository.Patient{ID: "stored-id"}, nil
+}
+
+func (m *mockQuarantine) Reject(_ context.Context, hospitalID, id, staffID string) error {
+	m.gotHID, m.gotStaff = hospitalID, staffID
+	return m.err
+}
+
+func quarantineRouter(m *mockQuarantine) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Set("staff_id", "admin-1")
+		c.Next()
+	})
+	RegisterQuarantineRoutes(r, m)
+	return r
+}
+
+func TestQuarantine_ListScopedToHospital(t *testing.T) {
+	m := &mockQuarantine{recs: []*repository.QuarantinedRecord{{ID: "q-1", Reasons: []string{"name: missing"}}}}
+	w := httptest.NewRecorder()
+	quarantineRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/quarantine?status=pending", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "HIS-1", m.gotHID)
+	assert.Contains(t, w.Body.String(), `"reasons":["name: missing"]`)
+
+	w = httptest.NewRecorder()
+	quarantineRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/quarantine?status=bogus", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestQuarantine_ReleaseAndReject(t *testing.T) {
+	m := &mockQuarantine{}
+	w := httptest.NewRecorder()
+	quarantineRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/quarantine/q-1/release", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "admin-1", m.gotStaff)
+	assert.Contains(t, w.Body.String(), "stored-id")
+
+	m.err = service.ErrQuarantineNotFound
+	w = httptest.NewRecorder()
+	quarantineRouter(m).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/admin/quarantine/q-1/reject", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
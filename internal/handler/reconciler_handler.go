@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+// ReconcileStatser reports the last reconciler run per hospital.
+type ReconcileStatser interface {
+	Stats() []service.ReconcileStats
+}
+
+// RegisterReconcilerRoutes registers GET /v1/admin/reconciler. Register it on
+// a group restricted with middleware.RequireRole. Admins only see their own
+// hospital's run.
+func RegisterReconcilerRoutes(r gin.IRoutes, rec ReconcileStatser) {
+	r.GET("/v1/admin/reconciler", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		hospitals := []service.ReconcileStats{}
+		for _, st := range rec.Stats() {
+			if st.HospitalID == hid {
+				hospitals = append(hospitals, st)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"hospitals": hospitals})
+	})
+}
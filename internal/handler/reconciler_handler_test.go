@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/service"
+)
+
+type fakeReconcileStats []service.ReconcileStats
+
+func (f fakeReconcileStats) Stats() []service.ReconcileStats { return f }
+
+func TestReconcilerStats_CallerHospitalOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterReconcilerRoutes(r, fakeReconcileStats{
+		{HospitalID: "HIS-1", Checked: 3, Updated: 1},
+		{HospitalID: "HIS-2", Checked: 7},
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/admin/reconciler", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"hospital_id":"HIS-1"`)
+	assert.NotContains(t, w.Body.String(), "HIS-2")
+}
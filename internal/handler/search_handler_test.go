@@ -11,23 +11,35 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/service"
 )
 
 // mockService implements PatientService for tests
 type mockPatientService struct {
-	out   []*repository.Patient
-	total int
-	err   error
+	out     []*repository.Patient
+	total   int
+	fed     *service.FederatedResult
+	err     error
+	filters repository.PatientFilters // last filters searched with
 }
 
-func (m *mockPatientService) Get(ctx context.Context, identifier string) (*repository.Patient, error) {
+func (m *mockPatientService) Resolve(ctx context.Context, hospitalID, identifier string) (*service.ResolvedPatient, error) {
 	return nil, nil
 }
 
 func (m *mockPatientService) Search(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) ([]*repository.Patient, int, error) {
+	m.filters = filters
 	return m.out, m.total, m.err
 }
 
+func (m *mockPatientService) SearchFederated(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) (*service.FederatedResult, error) {
+	return m.fed, m.err
+}
+
+func (m *mockPatientService) Provenance(ctx context.Context, patientID string) (map[string]repository.FieldProvenance, error) {
+	return nil, nil
+}
+
 func TestSearchHandler_ReturnsResults(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
@@ -65,3 +77,69 @@ func TestSearchHandler_ReturnsResults(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "Somchai")
 }
+
+func TestSearchHandler_FederatedMarksSources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	mock := &mockPatientService{
+		fed: &service.FederatedResult{
+			Results: []service.SearchHit{
+				{Patient: &repository.Patient{ID: "p1", FirstNameEN: "Somchai"}, Source: service.HitSourceLocal},
+				{Patient: &repository.Patient{NationalID: "N-2", FirstNameEN: "Somchai"}, Source: service.HitSourceHIS},
+			},
+			Total:     2,
+			HISStatus: service.HISStatusOK,
+		},
+	}
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterPatientRoutes(r, mock, nil)
+
+	body := `{"first_name":"Somchai","last_name":"Jaidee","federated":true}`
+	req := httptest.NewRequest(http.MethodPost, "/patient/search", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"his_status":"ok"`)
+	assert.Contains(t, w.Body.String(), `"source":"local"`)
+	assert.Contains(t, w.Body.String(), `"source":"his"`)
+	assert.Contains(t, w.Body.String(), `"count":2`)
+}
+
+func TestSearchHandler_BuddhistEraDates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	dob := "1985-05-05"
+	mock := &mockPatientService{
+		out:   []*repository.Patient{{ID: "p1", NationalID: "N-1", DateOfBirth: &dob}},
+		total: 1,
+	}
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterPatientRoutes(r, mock, nil)
+
+	body := `{"last_name":"Sukjai","date_of_birth":"5 พ.ค. 2528"}`
+	req := httptest.NewRequest(http.MethodPost, "/patient/search?date_era=be", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1985-05-05", mock.filters.DateOfBirth, "filter is normalised to CE")
+	assert.Contains(t, w.Body.String(), `"DateOfBirth":"05/05/2528"`)
+	assert.Equal(t, "1985-05-05", *mock.out[0].DateOfBirth, "stored patient is not modified")
+
+	req = httptest.NewRequest(http.MethodPost, "/patient/search", strings.NewReader(`{"date_of_birth":"31/02/2528"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
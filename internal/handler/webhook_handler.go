@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/webhook"
+)
+
+// WebhookManager defines the subscription management used by the webhook routes.
+type WebhookManager interface {
+	Subscribe(ctx context.Context, hospitalID, url string, eventTypes []string) (*repository.WebhookSubscription, string, error)
+	List(ctx context.Context, hospitalID string) ([]*repository.WebhookSubscription, error)
+	Unsubscribe(ctx context.Context, hospitalID, id string) error
+	Deliveries(ctx context.Context, hospitalID, status string, limit, offset int) ([]*repository.WebhookDelivery, error)
+	Redeliver(ctx context.Context, hospitalID, id string) (*repository.WebhookDelivery, error)
+}
+
+// RegisterWebhookRoutes registers webhook subscription routes. All routes are
+// scoped to the hospital_id from the JWT.
+func RegisterWebhookRoutes(r gin.IRoutes, m WebhookManager) {
+	// POST /v1/webhooks - register an endpoint; the signing secret is only returned here
+	r.POST("/v1/webhooks", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			URL        string   `json:"url" binding:"required"`
+			EventTypes []string `json:"event_types"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request", "detail": err.Error()})
+			return
+		}
+
+		sub, secret, err := m.Subscribe(c.Request.Context(), hid, req.URL, req.EventTypes)
+		if err != nil {
+			if errors.Is(err, webhook.ErrInvalidURL) || errors.Is(err, webhook.ErrBlockedAddress) ||
+				errors.Is(err, webhook.ErrInvalidEventType) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			log.Printf("webhooks/create error (hospital=%s): %v", hid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"id":          sub.ID,
+			"url":         sub.URL,
+			"event_types": sub.EventTypes,
+			"active":      sub.Active,
+			"created_at":  sub.CreatedAt,
+			"secret":      secret,
+		})
+	})
+
+	// GET /v1/webhooks
+	r.GET("/v1/webhooks", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		subs, err := m.List(c.Request.Context(), hid)
+		if err != nil {
+			log.Printf("webhooks/list error (hospital=%s): %v", hid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"results": subs})
+	})
+
+	// DELETE /v1/webhooks/:id
+	r.DELETE("/v1/webhooks/:id", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		err := m.Unsubscribe(c.Request.Context(), hid, c.Param("id"))
+		if errors.Is(err, webhook.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("webhooks/delete error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	// GET /v1/webhooks/deliveries?status=dead - dead-letter view when status=dead
+	r.GET("/v1/webhooks/deliveries", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		status := c.Query("status")
+		switch status {
+		case "", repository.DeliveryPending, repository.DeliveryDelivered, repository.DeliveryDead:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
+			return
+		}
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if limit <= 0 || limit > 500 {
+			limit = 50
+		}
+		if offset < 0 {
+			offset = 0
+		}
+
+		ds, err := m.Deliveries(c.Request.Context(), hid, status, limit, offset)
+		if err != nil {
+			log.Printf("webhooks/deliveries error (hospital=%s): %v", hid, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"limit": limit, "offset": offset, "results": ds})
+	})
+
+	// POST /v1/webhooks/deliveries/:id/redeliver
+	r.POST("/v1/webhooks/deliveries/:id/redeliver", func(c *gin.Context) {
+		hid, ok := requireHospital(c)
+		if !ok {
+			return
+		}
+		d, err := m.Redeliver(c.Request.Context(), hid, c.Param("id"))
+		if errors.Is(err, webhook.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		if err != nil {
+			log.Printf("webhooks/redeliver error (hospital=%s, id=%s): %v", hid, c.Param("id"), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal"})
+			return
+		}
+		c.JSON(http.StatusAccepted, d)
+	})
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/webhook"
+)
+
+// mockWebhooks implements WebhookManager and records the hospital it was called with.
+type mockWebhooks struct {
+	lastHospital string
+	lastStatus   string
+	err          error
+}
+
+func (m *mockWebhooks) Subscribe(_ context.Context, hospitalID, url string, eventTypes []string) (*repository.WebhookSubscription, string, error) {
+	m.lastHospital = hospitalID
+	if m.err != nil {
+		return nil, "", m.err
+	}
+	return &repository.WebhookSubscription{ID: "s1", HospitalID: hospitalID, URL: url, EventTypes: eventTypes, Active: true}, "whsec_x", nil
+}
+
+func (m *mockWebhooks) List(_ context.Context, hospitalID string) ([]*repository.WebhookSubscription, error) {
+	m.lastHospital = hospitalID
+	return []*repository.WebhookSubscription{{ID: "s1", HospitalID: hospitalID, Secret: "whsec_x"}}, nil
+}
+
+func (m *mockWebhooks) Unsubscribe(_ context.Context, hospitalID, id string) error {
+	m.lastHospital = hospitalID
+	return m.err
+}
+
+func (m *mockWebhooks) Deliveries(_ context.Context, hospitalID, status string, limit, offset int) ([]*repository.WebhookDelivery, error) {
+	m.lastHospital = hospitalID
+	m.lastStatus = status
+	return []*repository.WebhookDelivery{{ID: "d1", Status: status}}, nil
+}
+
+func (m *mockWebhooks) Redeliver(_ context.Context, hospitalID, id string) (*repository.WebhookDelivery, error) {
+	m.lastHospital = hospitalID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &repository.WebhookDelivery{ID: id, Status: repository.DeliveryPending}, nil
+}
+
+func setupWebhookRouter(m WebhookManager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("hospital_id", "HIS-1")
+		c.Next()
+	})
+	RegisterWebhookRoutes(r, m)
+	return r
+}
+
+func TestWebhooks_CreateReturnsSecretOnce(t *testing.T) {
+	m := &mockWebhooks{}
+	r := setupWebhookRouter(m)
+
+	body := `{"url":"https://lab.example/hook","event_types":["patient.created"]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"secret":"whsec_x"`)
+	assert.Equal(t, "HIS-1", m.lastHospital)
+
+	// listing never exposes the secret
+	req = httptest.NewRequest(http.MethodGet, "/v1/webhooks", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "whsec_x")
+}
+
+func TestWebhooks_CreateInvalidURL(t *testing.T) {
+	r := setupWebhookRouter(&mockWebhooks{err: webhook.ErrInvalidURL})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(`{"url":"ftp://x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestWebhooks_DeadLetterAndRedeliver(t *testing.T) {
+	m := &mockWebhooks{}
+	r := setupWebhookRouter(m)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/webhooks/deliveries?status=dead", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "dead", m.lastStatus)
+
+	req = httptest.NewRequest(http.MethodPost, "/v1/webhooks/deliveries/d1/redeliver", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusAccepted, w.Code)
+
+	m.err = webhook.ErrNotFound
+	req = httptest.NewRequest(http.MethodPost, "/v1/webhooks/deliveries/other/redeliver", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
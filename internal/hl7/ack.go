@@ -0,0 +1,41 @@
+package hl7
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ACK codes (original acknowledgment mode, MSA-1).
+const (
+	AckAccept = "AA" // processed
+	AckError  = "AE" // processing failed; the sender may resend
+	AckReject = "AR" // the message is invalid or unsupported; resending will not help
+)
+
+// BuildACK answers msg (which may be nil if it could not be parsed) with code and text.
+// Sender and receiver are swapped and MSA-2 echoes the original control id.
+func BuildACK(msg *Message, code, text string, now time.Time) []byte {
+	var sendApp, sendFac, recvApp, recvFac, ctrl, trigger, version string
+	version = "2.5"
+	if msg != nil {
+		sendApp, sendFac = msg.Field("MSH", 3), msg.Field("MSH", 4)
+		recvApp, recvFac = msg.Field("MSH", 5), msg.Field("MSH", 6)
+		ctrl = msg.ControlID()
+		trigger = component(msg.Components("MSH", 9), 2)
+		if v := msg.Field("MSH", 12); v != "" {
+			version = v
+		}
+	}
+
+	msh := strings.Join([]string{
+		"MSH", `^~\&`,
+		escape(recvApp), escape(recvFac), escape(sendApp), escape(sendFac),
+		now.Format("20060102150405"), "",
+		"ACK^" + escape(trigger) + "^ACK",
+		fmt.Sprintf("ACK%d", now.UnixNano()),
+		"P", escape(version),
+	}, "|")
+	msa := strings.Join([]string{"MSA", code, escape(ctrl), escape(truncate(text, 80))}, "|")
+	return []byte(msh + "\r" + msa + "\r")
+}
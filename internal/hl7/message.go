@@ -0,0 +1,187 @@
+// Package hl7 receives HL7 v2 ADT messages over MLLP and stores the patients they carry.
+package hl7
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Message is a parsed HL7 v2 message.
+type Message struct {
+	Segments []Segment
+	enc      encoding
+}
+
+// Segment is one segment; Fields[0] is the segment name and Fields[n] is field n
+// (for MSH, Fields[1] is the field separator itself, as in the standard numbering).
+type Segment struct {
+	Fields []string
+}
+
+// Name returns the segment id, e.g. "PID".
+func (s Segment) Name() string {
+	if len(s.Fields) == 0 {
+		return ""
+	}
+	return s.Fields[0]
+}
+
+type encoding struct {
+	field, component, repetition, escape, subcomponent byte
+}
+
+var defaultEncoding = encoding{'|', '^', '~', '\\', '&'}
+
+// ErrNoMSH is returned for input that does not start with an MSH segment.
+var ErrNoMSH = errors.New("hl7: message does not start with MSH")
+
+// Parse splits raw into segments using the delimiters declared in MSH-1/MSH-2.
+// Segments may be separated by CR, LF or CRLF.
+func Parse(raw []byte) (*Message, error) {
+	text := strings.ReplaceAll(string(raw), "\r\n", "\r")
+	text = strings.ReplaceAll(text, "\n", "\r")
+	lines := strings.Split(strings.Trim(text, "\r"), "\r")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "MSH") || len(lines[0]) < 8 {
+		return nil, ErrNoMSH
+	}
+
+	msh := lines[0]
+	enc := encoding{
+		field:        msh[3],
+		component:    msh[4],
+		repetition:   msh[5],
+		escape:       msh[6],
+		subcomponent: msh[7],
+	}
+
+	m := &Message{enc: enc}
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, string(enc.field))
+		if i == 0 {
+			// MSH-1 is the separator, MSH-2 starts right after it
+			parts = append([]string{"MSH", string(enc.field)}, parts[1:]...)
+		}
+		if len(parts[0]) != 3 {
+			return nil, fmt.Errorf("hl7: bad segment %d %q", i+1, truncate(line, 20))
+		}
+		m.Segments = append(m.Segments, Segment{Fields: parts})
+	}
+	return m, nil
+}
+
+// Segment returns the first segment with the given name.
+func (m *Message) Segment(name string) (Segment, bool) {
+	for _, s := range m.Segments {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return Segment{}, false
+}
+
+// Field returns field n of the first segment named seg, unescaped as one string.
+func (m *Message) Field(seg string, n int) string {
+	s, ok := m.Segment(seg)
+	if !ok {
+		return ""
+	}
+	return m.unescape(s.field(n))
+}
+
+// Components returns the components of the first repetition of field n of seg.
+func (m *Message) Components(seg string, n int) []string {
+	reps := m.Repetitions(seg, n)
+	if len(reps) == 0 {
+		return nil
+	}
+	return reps[0]
+}
+
+// Repetitions returns every repetition of field n of seg, split into unescaped components.
+// Only the first subcomponent of each component is kept.
+func (m *Message) Repetitions(seg string, n int) [][]string {
+	s, ok := m.Segment(seg)
+	if !ok {
+		return nil
+	}
+	f := s.field(n)
+	if f == "" {
+		return nil
+	}
+	var out [][]string
+	for _, rep := range strings.Split(f, string(m.enc.repetition)) {
+		comps := strings.Split(rep, string(m.enc.component))
+		for i, c := range comps {
+			c, _, _ = strings.Cut(c, string(m.enc.subcomponent))
+			comps[i] = m.unescape(c)
+		}
+		out = append(out, comps)
+	}
+	return out
+}
+
+// Type returns MSH-9 as "ADT^A04" (message code ^ trigger event).
+func (m *Message) Type() string {
+	c := m.Components("MSH", 9)
+	switch len(c) {
+	case 0:
+		return ""
+	case 1:
+		return c[0]
+	}
+	return c[0] + "^" + c[1]
+}
+
+// ControlID returns MSH-10.
+func (m *Message) ControlID() string { return m.Field("MSH", 10) }
+
+func (s Segment) field(n int) string {
+	if n < len(s.Fields) {
+		return s.Fields[n]
+	}
+	return ""
+}
+
+// component returns the 1-based component i of comps, or "".
+func component(comps []string, i int) string {
+	if i-1 < len(comps) {
+		return strings.TrimSpace(comps[i-1])
+	}
+	return ""
+}
+
+// unescape resolves the standard escape sequences \F\ \S\ \T\ \R\ \E\.
+func (m *Message) unescape(s string) string {
+	esc := string(m.enc.escape)
+	if !strings.Contains(s, esc) {
+		return s
+	}
+	r := strings.NewReplacer(
+		esc+"F"+esc, string(m.enc.field),
+		esc+"S"+esc, string(m.enc.component),
+		esc+"T"+esc, string(m.enc.subcomponent),
+		esc+"R"+esc, string(m.enc.repetition),
+		esc+"E"+esc, esc,
+	)
+	return r.Replace(s)
+}
+
+// escape is the inverse of unescape for the default encoding.
+func escape(s string) string {
+	if !strings.ContainsAny(s, `|^~\&`) {
+		return s
+	}
+	r := strings.NewReplacer(`\`, `\E\`, `|`, `\F\`, `^`, `\S\`, `&`, `\T\`, `~`, `\R\`)
+	return r.Replace(s)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n] + "..."
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+package hl7
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/thaidate"
+)
+
+const adtA04 = "MSH|^~\\&|HOSxP|HOSP-D|AGNOS|AGNOS|20250105093000||ADT^A04^ADT_A01|MSG00001|P|2.5\r" +
+	"EVN|A04|20250105093000\r" +
+	"PID|1||1103700000001^^^MOI^NI~HN-0042^^^HOSP-D^MR~AA1234567^^^THA^PPN||ใจดี^สมชาย^^^นาย^^L~Jaidee^Somchai^Ken^^Mr^^L||19850505|M|||1 Sukhumvit^^Bangkok||0812345678^PRN^PH~^NET^Internet^somchai@example.com\r" +
+	"PV1|1|O\r"
+
+func TestParse_MessageHeader(t *testing.T) {
+	m, err := Parse([]byte(adtA04))
+	assert.NoError(t, err)
+	assert.Equal(t, "ADT^A04", m.Type())
+	assert.Equal(t, "MSG00001", m.ControlID())
+	assert.Equal(t, "HOSxP", m.Field("MSH", 3))
+	assert.Equal(t, "2.5", m.Field("MSH", 12))
+	assert.Len(t, m.Segments, 4)
+
+	_, err = Parse([]byte("PID|1||X\r"))
+	assert.ErrorIs(t, err, ErrNoMSH)
+}
+
+func TestParse_LineEndingsAndEscapes(t *testing.T) {
+	raw := strings.ReplaceAll(adtA04, "\r", "\r\n")
+	raw = strings.Replace(raw, "1 Sukhumvit", `1\S\2 Sukhumvit \T\ Soi`, 1)
+	m, err := Parse([]byte(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, `1^2 Sukhumvit & Soi`, m.Components("PID", 11)[0])
+}
+
+func TestPatientFromPID(t *testing.T) {
+	m, err := Parse([]byte(adtA04))
+	assert.NoError(t, err)
+
+	p, err := PatientFromPID(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "1103700000001", p.NationalID)
+	assert.Equal(t, "AA1234567", p.PassportID)
+	assert.Equal(t, "HN-0042", p.PatientHN)
+	assert.Equal(t, "สมชาย", p.FirstNameTH)
+	assert.Equal(t, "ใจดี", p.LastNameTH)
+	assert.Equal(t, "Somchai", p.FirstNameEN)
+	assert.Equal(t, "Ken", p.MiddleNameEN)
+	assert.Equal(t, "Jaidee", p.LastNameEN)
+	assert.Equal(t, "M", p.Gender)
+	assert.Equal(t, "0812345678", p.PhoneNumber)
+	assert.Equal(t, "somchai@example.com", p.Email)
+	if assert.NotNil(t, p.DateOfBirth) {
+		assert.Equal(t, "1985-05-05", *p.DateOfBirth)
+	}
+
+	var raw map[string]string
+	assert.NoError(t, json.Unmarshal(p.RawJSON, &raw))
+	assert.Equal(t, "ADT^A04", raw["message_type"])
+	assert.True(t, strings.HasPrefix(raw["pid"], "PID|1||1103700000001"))
+}
+
+func TestPatientFromPID_Fallbacks(t *testing.T) {
+	// CID in PID-19, unformatted phone in XTN-12, DOB with time
+	raw := "MSH|^~\\&|A|B|C|D|20250105||ADT^A08|2|P|2.3\r" +
+		"PID|1||HN-7^^^H^MR||Doe^Jane||198001021230|F|||||^PRN^PH^^^^^^^^^021234567||||||1103700000002\r"
+	m, err := Parse([]byte(raw))
+	assert.NoError(t, err)
+	p, err := PatientFromPID(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "1103700000002", p.NationalID)
+	assert.Equal(t, "HN-7", p.PatientHN)
+	assert.Equal(t, "021234567", p.PhoneNumber)
+	assert.Equal(t, "F", p.Gender)
+	assert.Equal(t, "1980-01-02", *p.DateOfBirth)
+	assert.Equal(t, "Jane", p.FirstNameEN)
+
+	m, _ = Parse([]byte("MSH|^~\\&|A|B|C|D|20250105||ADT^A08|3|P|2.3\rPID|1||HN-8^^^H^MR||Doe^John\r"))
+	_, err = PatientFromPID(m)
+	assert.ErrorIs(t, err, ErrNoIdentifier)
+}
+
+func TestPatientFromPID_DateOfBirth(t *testing.T) {
+	withDOB := func(dob string) *Message {
+		m, err := Parse([]byte(strings.Replace(adtA04, "||19850505|M|", "||"+dob+"|M|", 1)))
+		assert.NoError(t, err)
+		return m
+	}
+
+	p, err := PatientFromPID(withDOB("25280505"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, p.DateOfBirth) {
+		assert.Equal(t, "1985-05-05", *p.DateOfBirth, "Buddhist Era year")
+	}
+
+	_, err = PatientFromPID(withDOB("19851345"))
+	assert.ErrorIs(t, err, thaidate.ErrInvalid)
+	assert.ErrorContains(t, err, "PID-7")
+}
+
+func TestBuildACK(t *testing.T) {
+	m, _ := Parse([]byte(adtA04))
+	ack := string(BuildACK(m, AckAccept, "", time.Date(2025, 1, 5, 9, 30, 1, 0, time.UTC)))
+
+	segs := strings.Split(strings.TrimSuffix(ack, "\r"), "\r")
+	if assert.Len(t, segs, 2) {
+		assert.True(t, strings.HasPrefix(segs[0], "MSH|^~\\&|AGNOS|AGNOS|HOSxP|HOSP-D|20250105093001||ACK^A04^ACK|"))
+		assert.Equal(t, "MSA|AA|MSG00001|", segs[1])
+	}
+
+	// unparseable input still gets a well-formed reject
+	nak := string(BuildACK(nil, AckReject, "bad|message", time.Now()))
+	assert.Contains(t, nak, "\rMSA|AR||bad\\F\\message\r")
+}
@@ -0,0 +1,159 @@
+package hl7
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+	"github.com/haniscreator/agnos-search/internal/thaidate"
+)
+
+// ErrNoIdentifier is returned for a PID without a national id or passport number;
+// such patients cannot be matched on later updates.
+var ErrNoIdentifier = errors.New("hl7: PID has no national id or passport number")
+
+// PatientFromPID maps the PID segment of m to a Patient:
+//
+//	PID-3  identifiers (CX; type NI/CZ national id, PPN passport, MR/PI hospital number)
+//	PID-5  names (XPN; Thai or English chosen by script, legal name "L" preferred)
+//	PID-7  date of birth (CE or Buddhist Era), PID-8 sex, PID-13/14 phone and e-mail (XTN)
+//	PID-19 national id when PID-3 carries none (older Thai HIS put the CID there)
+//
+// RawJSON records the message type, control id and the raw PID segment.
+func PatientFromPID(m *Message) (*repository.Patient, error) {
+	pid, ok := m.Segment("PID")
+	if !ok {
+		return nil, errors.New("hl7: message has no PID segment")
+	}
+
+	p := &repository.Patient{}
+	for _, cx := range m.Repetitions("PID", 3) {
+		id := component(cx, 1)
+		if id == "" {
+			continue
+		}
+		switch strings.ToUpper(component(cx, 5)) {
+		case "NI", "CZ", "NNTHA":
+			setOnce(&p.NationalID, id)
+		case "PPN":
+			setOnce(&p.PassportID, id)
+		case "MR", "PI", "PT":
+			setOnce(&p.PatientHN, id)
+		}
+	}
+	if p.NationalID == "" {
+		p.NationalID = strings.TrimSpace(m.Field("PID", 19))
+	}
+	if p.NationalID == "" && p.PassportID == "" {
+		return nil, ErrNoIdentifier
+	}
+
+	setNames(p, m.Repetitions("PID", 5))
+
+	if dob := digits(component(m.Components("PID", 7), 1)); len(dob) >= 8 {
+		// Thai feeds may send Buddhist Era years; an impossible date is
+		// rejected, resending the message would not fix it
+		d, err := thaidate.Parse(dob[:8])
+		if err != nil {
+			return nil, fmt.Errorf("hl7: PID-7 date of birth: %w", err)
+		}
+		p.DateOfBirth = &d
+	}
+
+	switch strings.ToUpper(m.Field("PID", 8)) {
+	case "M":
+		p.Gender = "M"
+	case "F":
+		p.Gender = "F"
+	}
+
+	for _, n := range []int{13, 14} {
+		for _, xtn := range m.Repetitions("PID", n) {
+			if email := component(xtn, 4); email != "" && (component(xtn, 3) == "Internet" || component(xtn, 2) == "NET") {
+				setOnce(&p.Email, email)
+				continue
+			}
+			setOnce(&p.PhoneNumber, phone(xtn))
+		}
+	}
+
+	raw, err := json.Marshal(map[string]string{
+		"source":       "hl7v2",
+		"message_type": m.Type(),
+		"control_id":   m.ControlID(),
+		"pid":          strings.Join(pid.Fields, string(m.enc.field)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.RawJSON = raw
+	return p, nil
+}
+
+// setNames fills the Thai and English names from XPN repetitions
+// (1 family, 2 given, 3 middle, 7 name type).
+func setNames(p *repository.Patient, names [][]string) {
+	var thSet, enSet bool
+	for pass := 0; pass < 2; pass++ {
+		for _, xpn := range names {
+			legal := component(xpn, 7) == "L"
+			// first pass: legal names only; second pass: anything still missing
+			if pass == 0 && !legal {
+				continue
+			}
+			family, given, middle := component(xpn, 1), component(xpn, 2), component(xpn, 3)
+			if family == "" && given == "" {
+				continue
+			}
+			if isThai(family + given + middle) {
+				if !thSet {
+					p.FirstNameTH, p.MiddleNameTH, p.LastNameTH = given, middle, family
+					thSet = true
+				}
+			} else if !enSet {
+				p.FirstNameEN, p.MiddleNameEN, p.LastNameEN = given, middle, family
+				enSet = true
+			}
+		}
+	}
+}
+
+// phone reads an XTN: 1 (legacy number), else 12 (unformatted), else 6+7 (area + local).
+func phone(xtn []string) string {
+	if v := component(xtn, 1); v != "" {
+		return v
+	}
+	if v := component(xtn, 12); v != "" {
+		return v
+	}
+	return component(xtn, 6) + component(xtn, 7)
+}
+
+func isThai(s string) bool {
+	for _, r := range s {
+		if unicode.Is(unicode.Thai, r) {
+			return true
+		}
+	}
+	return false
+}
+
+func digits(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			break
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func setOnce(dst *string, v string) {
+	if *dst == "" {
+		*dst = v
+	}
+}
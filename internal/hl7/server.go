@@ -0,0 +1,226 @@
+package hl7
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// MLLP framing bytes.
+const (
+	startBlock = 0x0b
+	endBlock   = 0x1c
+	carriageCR = 0x0d
+)
+
+// SupportedTypes are the ADT events that carry patient demographics we store.
+var SupportedTypes = map[string]bool{
+	"ADT^A04": true, // register a patient
+	"ADT^A08": true, // update patient information
+	"ADT^A28": true, // add person information
+}
+
+// Archiver stores raw messages and their processing outcome (repository.HL7MessageRepo).
+type Archiver interface {
+	Archive(ctx context.Context, m *repository.HL7Message) error
+	SetStatus(ctx context.Context, id int64, status, patientID, errMsg string) error
+}
+
+// PatientWriter stores a patient with history and outbox event (service.PatientWriter).
+type PatientWriter interface {
+	Upsert(ctx context.Context, p *repository.Patient) error
+}
+
+// Server is an MLLP listener for one hospital's ADT feed.
+type Server struct {
+	hospitalID string
+	archive    Archiver
+	writer     PatientWriter
+
+	// ReadTimeout closes connections idle for longer than this.
+	ReadTimeout time.Duration
+	// MaxMessageSize rejects larger frames and drops the connection.
+	MaxMessageSize int
+
+	now func() time.Time
+	wg  sync.WaitGroup
+}
+
+// NewServer constructs a Server storing patients under hospitalID.
+func NewServer(hospitalID string, archive Archiver, writer PatientWriter) *Server {
+	return &Server{
+		hospitalID:     hospitalID,
+		archive:        archive,
+		writer:         writer,
+		ReadTimeout:    5 * time.Minute,
+		MaxMessageSize: 1 << 20,
+		now:            time.Now,
+	}
+}
+
+// ListenAndServe listens on addr (e.g. ":2575") until ctx is cancelled.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("hl7 listen %s: %w", addr, err)
+	}
+	return s.Serve(ctx, ln)
+}
+
+// Serve accepts connections on ln until ctx is cancelled, then waits for
+// in-flight messages to be acknowledged.
+func (s *Server) Serve(ctx context.Context, ln net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				s.wg.Wait()
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			s.wg.Wait()
+			return fmt.Errorf("hl7 accept: %w", err)
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	// unblock reads on shutdown
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	for {
+		if s.ReadTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(s.ReadTimeout))
+		}
+		frame, err := readFrame(r, s.MaxMessageSize)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && ctx.Err() == nil {
+				log.Printf("hl7 (hospital=%s, remote=%s): %v", s.hospitalID, conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		// the message is handled to completion even if shutdown starts meanwhile
+		ack := s.Handle(context.WithoutCancel(ctx), frame)
+		if _, err := conn.Write(mllpFrame(ack)); err != nil {
+			log.Printf("hl7 write ack (hospital=%s): %v", s.hospitalID, err)
+			return
+		}
+	}
+}
+
+// Handle archives and processes one message and returns its ACK.
+func (s *Server) Handle(ctx context.Context, raw []byte) []byte {
+	msg, parseErr := Parse(raw)
+
+	rec := &repository.HL7Message{HospitalID: s.hospitalID, Raw: string(raw)}
+	if msg != nil {
+		rec.ControlID, rec.MessageType = msg.ControlID(), msg.Type()
+	}
+	if err := s.archive.Archive(ctx, rec); err != nil {
+		// never accept what we could not keep
+		log.Printf("hl7 archive (hospital=%s, control_id=%s): %v", s.hospitalID, rec.ControlID, err)
+		return BuildACK(msg, AckError, "archive failed", s.now())
+	}
+
+	code, status, text, patientID := s.process(ctx, msg, parseErr)
+	if err := s.archive.SetStatus(ctx, rec.ID, status, patientID, text); err != nil {
+		log.Printf("hl7 set status (id=%d): %v", rec.ID, err)
+	}
+	return BuildACK(msg, code, text, s.now())
+}
+
+// process returns the ACK code, archive status, error text and stored patient id.
+func (s *Server) process(ctx context.Context, msg *Message, parseErr error) (code, status, text, patientID string) {
+	if parseErr != nil {
+		return AckReject, repository.HL7Rejected, parseErr.Error(), ""
+	}
+	if !SupportedTypes[msg.Type()] {
+		return AckReject, repository.HL7Rejected, "unsupported message type " + msg.Type(), ""
+	}
+
+	p, err := PatientFromPID(msg)
+	if err != nil {
+		return AckReject, repository.HL7Rejected, err.Error(), ""
+	}
+	p.ID = uuid.NewString()
+	p.HospitalID = s.hospitalID
+
+	if err := s.writer.Upsert(ctx, p); err != nil {
+		log.Printf("hl7 upsert (hospital=%s, control_id=%s): %v", s.hospitalID, msg.ControlID(), err)
+		return AckError, repository.HL7Error, "could not store patient", ""
+	}
+	return AckAccept, repository.HL7Accepted, "", p.ID
+}
+
+// readFrame reads one <VT>message<FS><CR> frame. Bytes before <VT> are skipped.
+func readFrame(r *bufio.Reader, maxSize int) ([]byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == startBlock {
+			break
+		}
+	}
+
+	var buf []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if b == endBlock {
+			next, err := r.ReadByte()
+			if err != nil {
+				return nil, io.ErrUnexpectedEOF
+			}
+			if next != carriageCR {
+				return nil, errors.New("mllp: end block not followed by CR")
+			}
+			return buf, nil
+		}
+		if maxSize > 0 && len(buf) >= maxSize {
+			return nil, fmt.Errorf("mllp: message larger than %d bytes", maxSize)
+		}
+		buf = append(buf, b)
+	}
+}
+
+// mllpFrame wraps payload in MLLP framing.
+func mllpFrame(payload []byte) []byte {
+	out := make([]byte, 0, len(payload)+3)
+	out = append(out, startBlock)
+	out = append(out, payload...)
+	return append(out, endBlock, carriageCR)
+}
@@ -0,0 +1,167 @@
+package hl7
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+type fakeArchive struct {
+	mu       sync.Mutex
+	messages []*repository.HL7Message
+	statuses map[int64]string
+	err      error
+}
+
+func (f *fakeArchive) Archive(_ context.Context, m *repository.HL7Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.messages = append(f.messages, m)
+	m.ID = int64(len(f.messages))
+	return nil
+}
+
+func (f *fakeArchive) SetStatus(_ context.Context, id int64, status, _, _ string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.statuses == nil {
+		f.statuses = map[int64]string{}
+	}
+	f.statuses[id] = status
+	return nil
+}
+
+type fakeWriter struct {
+	mu      sync.Mutex
+	written []*repository.Patient
+	err     error
+}
+
+func (f *fakeWriter) Upsert(_ context.Context, p *repository.Patient) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.written = append(f.written, p)
+	return nil
+}
+
+func msaCode(ack []byte) string {
+	for _, seg := range strings.Split(string(ack), "\r") {
+		if strings.HasPrefix(seg, "MSA|") {
+			return strings.Split(seg, "|")[1]
+		}
+	}
+	return ""
+}
+
+func TestServer_Handle(t *testing.T) {
+	unsupported := strings.Replace(adtA04, "ADT^A04^ADT_A01", "ORU^R01", 1)
+	noID := "MSH|^~\\&|A|B|C|D|20250105||ADT^A08|3|P|2.3\rPID|1||HN-8^^^H^MR||Doe^John\r"
+	badDOB := strings.Replace(adtA04, "||19850505|M|", "||19851345|M|", 1)
+
+	cases := []struct {
+		name       string
+		raw        string
+		writerErr  error
+		archiveErr error
+		code       string
+		status     string
+	}{
+		{"accepted", adtA04, nil, nil, AckAccept, repository.HL7Accepted},
+		{"unsupported type", unsupported, nil, nil, AckReject, repository.HL7Rejected},
+		{"no identifier", noID, nil, nil, AckReject, repository.HL7Rejected},
+		{"impossible date of birth", badDOB, nil, nil, AckReject, repository.HL7Rejected},
+		{"garbage", "hello", nil, nil, AckReject, repository.HL7Rejected},
+		{"store failure", adtA04, errors.New("db down"), nil, AckError, repository.HL7Error},
+		{"archive failure", adtA04, nil, errors.New("db down"), AckError, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			arch := &fakeArchive{err: tc.archiveErr}
+			w := &fakeWriter{err: tc.writerErr}
+			s := NewServer("HIS-4", arch, w)
+
+			ack := s.Handle(context.Background(), []byte(tc.raw))
+			assert.Equal(t, tc.code, msaCode(ack))
+			if tc.status != "" {
+				assert.Len(t, arch.messages, 1)
+				assert.Equal(t, tc.raw, arch.messages[0].Raw)
+				assert.Equal(t, tc.status, arch.statuses[1])
+			}
+			if tc.code == AckAccept {
+				if assert.Len(t, w.written, 1) {
+					assert.Equal(t, "HIS-4", w.written[0].HospitalID)
+					assert.NotEmpty(t, w.written[0].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestServer_MLLPRoundTrip(t *testing.T) {
+	arch := &fakeArchive{}
+	w := &fakeWriter{}
+	s := NewServer("HIS-4", arch, w)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, ln) }()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	// two messages on one connection, each acknowledged in order
+	for _, ctrl := range []string{"MSG00001", "MSG00002"} {
+		msg := strings.Replace(adtA04, "MSG00001", ctrl, 1)
+		_, err = conn.Write(mllpFrame([]byte(msg)))
+		assert.NoError(t, err)
+
+		ack, err := readFrame(r, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, AckAccept, msaCode(ack))
+		assert.Contains(t, string(ack), "MSA|AA|"+ctrl)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+	assert.Len(t, w.written, 2)
+}
+
+func TestReadFrame(t *testing.T) {
+	in := "noise" + string(mllpFrame([]byte("MSH|a"))) + string(mllpFrame([]byte("MSH|b")))
+	r := bufio.NewReader(strings.NewReader(in))
+
+	f, err := readFrame(r, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "MSH|a", string(f))
+	f, err = readFrame(r, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "MSH|b", string(f))
+
+	_, err = readFrame(bufio.NewReader(strings.NewReader(string(mllpFrame([]byte("MSH|toolong"))))), 4)
+	assert.Error(t, err)
+}
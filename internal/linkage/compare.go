@@ -0,0 +1,226 @@
+package linkage
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Comparator names usable in FieldConfig.
+const (
+	ComparatorExact       = "exact"        // case-insensitive equality
+	ComparatorJaroWinkler = "jaro_winkler" // names, Thai or English
+	ComparatorDOB         = "dob"          // yyyy-mm-dd with day/month swaps and one-digit typos
+	ComparatorPhoneSuffix = "phone_suffix" // trailing digits, ignoring country code and punctuation
+	ComparatorIdentifier  = "identifier"   // national id / passport with one typo or adjacent swap
+)
+
+// compareFunc returns the similarity of two non-empty values in [0, 1].
+type compareFunc func(a, b string) float64
+
+var comparators = map[string]compareFunc{
+	ComparatorExact:       compareExact,
+	ComparatorJaroWinkler: compareNames,
+	ComparatorDOB:         compareDOB,
+	ComparatorPhoneSuffix: comparePhone,
+	ComparatorIdentifier:  compareIdentifier,
+}
+
+func compareExact(a, b string) float64 {
+	if strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) {
+		return 1
+	}
+	return 0
+}
+
+func compareNames(a, b string) float64 {
+	return JaroWinkler(normalizeName(a), normalizeName(b))
+}
+
+// normalizeName lowercases and drops spaces, dots and hyphens so "Mary-Ann"
+// and "mary ann" compare equal. Thai vowels and tone marks are kept.
+func normalizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '.' || r == '-' || r == '\'' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// DOB similarities below an exact match.
+const (
+	dobSwapped = 0.8 // day and month transposed (05/12 vs 12/05)
+	dobTypo    = 0.7 // one digit differs
+)
+
+func compareDOB(a, b string) float64 {
+	ta, errA := time.Parse("2006-01-02", a)
+	tb, errB := time.Parse("2006-01-02", b)
+	if errA != nil || errB != nil {
+		return compareExact(a, b)
+	}
+	switch {
+	case ta.Equal(tb):
+		return 1
+	case ta.Year() == tb.Year() && ta.Day() == int(tb.Month()) && int(ta.Month()) == tb.Day():
+		return dobSwapped
+	case hamming(a, b) == 1:
+		return dobTypo
+	}
+	return 0
+}
+
+// phoneSuffixLen covers a Thai subscriber number without its leading 0 or
+// +66, so 0812345678 and +66 81 234 5678 match.
+const phoneSuffixLen = 9
+
+// phoneShortSuffix is the partial match: same last four digits.
+const phoneShortSuffix = 4
+
+func comparePhone(a, b string) float64 {
+	da, db := digits(a), digits(b)
+	switch {
+	case da == "" || db == "":
+		return 0
+	case suffix(da, phoneSuffixLen) == suffix(db, phoneSuffixLen):
+		return 1
+	case len(da) >= phoneShortSuffix && len(db) >= phoneShortSuffix &&
+		suffix(da, phoneShortSuffix) == suffix(db, phoneShortSuffix):
+		return 0.5
+	}
+	return 0
+}
+
+// identifierTypo is the similarity of identifiers one edit or one adjacent
+// swap apart (AB1234567 vs AB1234576).
+const identifierTypo = 0.8
+
+func compareIdentifier(a, b string) float64 {
+	na := strings.ToUpper(strings.Map(dropSeparators, a))
+	nb := strings.ToUpper(strings.Map(dropSeparators, b))
+	switch {
+	case na == nb:
+		return 1
+	case osaDistance([]rune(na), []rune(nb)) == 1:
+		return identifierTypo
+	}
+	return 0
+}
+
+func dropSeparators(r rune) rune {
+	if r == '-' || r == ' ' {
+		return -1
+	}
+	return r
+}
+
+// JaroWinkler returns the Jaro-Winkler similarity of a and b in [0, 1],
+// comparing runes so Thai names are handled like English ones.
+func JaroWinkler(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0
+	}
+
+	window := max(len(ra), len(rb))/2 - 1
+	window = max(window, 0)
+	matchedA := make([]bool, len(ra))
+	matchedB := make([]bool, len(rb))
+	matches := 0
+	for i, r := range ra {
+		lo, hi := max(0, i-window), min(len(rb), i+window+1)
+		for j := lo; j < hi; j++ {
+			if !matchedB[j] && rb[j] == r {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions, j := 0, 0
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions/2))/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
+
+// osaDistance is the optimal string alignment distance: insertions,
+// deletions, substitutions and adjacent transpositions.
+func osaDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// hamming counts differing bytes of equal-length strings (-1 otherwise).
+func hamming(a, b string) int {
+	if len(a) != len(b) {
+		return -1
+	}
+	n := 0
+	for i := range len(a) {
+		if a[i] != b[i] {
+			n++
+		}
+	}
+	return n
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+func suffix(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
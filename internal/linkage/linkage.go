@@ -0,0 +1,252 @@
+// Package linkage scores whether two patient records describe the same person,
+// Fellegi–Sunter style: each configured field is compared with a comparator,
+// agreement adds log2(m/u) to the score, disagreement adds
+// log2((1-m)/(1-u)), and the total is classified against match and possible
+// thresholds. Fields empty on either side add nothing.
+//
+// The engine is used for duplicate warnings on create, merge candidates and
+// flagging likely duplicates when HIS or HL7 data is ingested.
+package linkage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Decisions of a comparison.
+const (
+	DecisionMatch    = "match"     // same person
+	DecisionPossible = "possible"  // needs review
+	DecisionNonMatch = "non_match" // different people
+)
+
+// FieldConfig configures one compared field.
+type FieldConfig struct {
+	Field      string  `json:"field"`      // e.g. "last_name_th"; see Fields
+	Comparator string  `json:"comparator"` // e.g. "jaro_winkler"
+	M          float64 `json:"m"`          // P(agree | same person)
+	U          float64 `json:"u"`          // P(agree | different people)
+	// Threshold is the similarity from which the field fully agrees
+	// (default 1, or 0.94 for jaro_winkler).
+	Threshold float64 `json:"threshold,omitempty"`
+	// Partial is the similarity from which the field partially agrees; its
+	// weight is interpolated between the disagreement and agreement weights
+	// by similarity. 0 disables partial agreement.
+	Partial float64 `json:"partial,omitempty"`
+}
+
+// Config is the engine configuration.
+type Config struct {
+	Fields   []FieldConfig `json:"fields"`
+	Match    float64       `json:"match"`    // score from which a pair is a match
+	Possible float64       `json:"possible"` // score from which a pair is a possible match
+}
+
+// Fields lists the field names that can be compared: the standard patient fields.
+var Fields = adapter.StandardFields
+
+// DefaultConfig weighs identifiers, names in both scripts, date of birth and
+// contact details. With it, a record sharing names and DOB with a typo'd
+// passport is a match; a sibling sharing surname, phone and gender is
+// possible.
+func DefaultConfig() Config {
+	return Config{
+		Fields: []FieldConfig{
+			{Field: "national_id", Comparator: ComparatorIdentifier, M: 0.98, U: 0.0001, Partial: identifierTypo},
+			{Field: "passport_id", Comparator: ComparatorIdentifier, M: 0.95, U: 0.0001, Partial: identifierTypo},
+			{Field: "first_name_th", Comparator: ComparatorJaroWinkler, M: 0.95, U: 0.01, Partial: 0.85},
+			{Field: "last_name_th", Comparator: ComparatorJaroWinkler, M: 0.95, U: 0.005, Partial: 0.85},
+			{Field: "first_name_en", Comparator: ComparatorJaroWinkler, M: 0.95, U: 0.01, Partial: 0.85},
+			{Field: "last_name_en", Comparator: ComparatorJaroWinkler, M: 0.95, U: 0.005, Partial: 0.85},
+			{Field: "date_of_birth", Comparator: ComparatorDOB, M: 0.97, U: 0.0003, Partial: dobTypo},
+			{Field: "phone_number", Comparator: ComparatorPhoneSuffix, M: 0.9, U: 0.0001, Partial: 0.5},
+			{Field: "email", Comparator: ComparatorExact, M: 0.9, U: 0.0001},
+			{Field: "gender", Comparator: ComparatorExact, M: 0.98, U: 0.5},
+		},
+		Match:    20,
+		Possible: 8,
+	}
+}
+
+// LoadConfig reads a Config from a JSON file.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return Config{}, fmt.Errorf("linkage config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// FieldScore is one field's contribution to a comparison.
+type FieldScore struct {
+	Field      string  `json:"field"`
+	Similarity float64 `json:"similarity"`
+	Weight     float64 `json:"weight"`
+}
+
+// Result is the outcome of comparing two records.
+type Result struct {
+	Score    float64      `json:"score"`
+	Decision string       `json:"decision"`
+	Fields   []FieldScore `json:"fields"` // compared fields; missing ones are left out
+}
+
+// Candidate is a stored patient that may be the same person as a probe record.
+type Candidate struct {
+	*repository.Patient
+	Result
+}
+
+type field struct {
+	FieldConfig
+	compare         compareFunc
+	agree, disagree float64
+}
+
+// Engine compares patient records. It is safe for concurrent use.
+type Engine struct {
+	fields          []field
+	match, possible float64
+}
+
+// New validates cfg and builds an Engine.
+func New(cfg Config) (*Engine, error) {
+	if len(cfg.Fields) == 0 {
+		return nil, errors.New("linkage: no fields configured")
+	}
+	if cfg.Possible > cfg.Match {
+		return nil, fmt.Errorf("linkage: possible threshold %.2f above match threshold %.2f", cfg.Possible, cfg.Match)
+	}
+	e := &Engine{match: cfg.Match, possible: cfg.Possible}
+	for _, fc := range cfg.Fields {
+		if !slices.Contains(Fields, fc.Field) {
+			return nil, fmt.Errorf("linkage: unknown field %q", fc.Field)
+		}
+		compare, ok := comparators[fc.Comparator]
+		if !ok {
+			return nil, fmt.Errorf("linkage: field %s: unknown comparator %q", fc.Field, fc.Comparator)
+		}
+		if fc.M <= 0 || fc.M >= 1 || fc.U <= 0 || fc.U >= 1 {
+			return nil, fmt.Errorf("linkage: field %s: m and u must be between 0 and 1 exclusive", fc.Field)
+		}
+		if fc.Threshold == 0 {
+			fc.Threshold = 1
+			if fc.Comparator == ComparatorJaroWinkler {
+				fc.Threshold = 0.94
+			}
+		}
+		if fc.Partial > fc.Threshold {
+			return nil, fmt.Errorf("linkage: field %s: partial %.2f above threshold %.2f", fc.Field, fc.Partial, fc.Threshold)
+		}
+		e.fields = append(e.fields, field{
+			FieldConfig: fc,
+			compare:     compare,
+			agree:       math.Log2(fc.M / fc.U),
+			disagree:    math.Log2((1 - fc.M) / (1 - fc.U)),
+		})
+	}
+	return e, nil
+}
+
+// Compare scores a against b.
+func (e *Engine) Compare(a, b *repository.Patient) Result {
+	var res Result
+	for _, f := range e.fields {
+		va, vb := a.Field(f.Field), b.Field(f.Field)
+		if va == "" || vb == "" {
+			continue
+		}
+		sim := f.compare(va, vb)
+		w := f.disagree
+		switch {
+		case sim >= f.Threshold:
+			w = f.agree
+		case f.Partial > 0 && sim >= f.Partial:
+			w = sim*f.agree + (1-sim)*f.disagree
+		}
+		res.Score += w
+		res.Fields = append(res.Fields, FieldScore{Field: f.Field, Similarity: round(sim), Weight: round(w)})
+	}
+	res.Score = round(res.Score)
+	res.Decision = e.decide(res.Score)
+	return res
+}
+
+// Rank compares probe with each candidate and returns the matches and
+// possible matches, best first. Candidates with probe's id are skipped.
+func (e *Engine) Rank(probe *repository.Patient, candidates []*repository.Patient) []Candidate {
+	var out []Candidate
+	for _, c := range candidates {
+		if c.ID != "" && c.ID == probe.ID {
+			continue
+		}
+		res := e.Compare(probe, c)
+		if res.Decision == DecisionNonMatch {
+			continue
+		}
+		out = append(out, Candidate{Patient: c, Result: res})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+func (e *Engine) decide(score float64) string {
+	switch {
+	case score >= e.match:
+		return DecisionMatch
+	case score >= e.possible:
+		return DecisionPossible
+	}
+	return DecisionNonMatch
+}
+
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
+
+// blockPrefix is how many leading runes of a last name select candidates.
+const blockPrefix = 3
+
+// BlockingKeys returns the keys stored candidates for p are selected by:
+// its date of birth and the day/month swap of it, phone suffix, last-name
+// prefixes and identifiers. Typo'd identifiers are found through the others.
+func BlockingKeys(p *repository.Patient) repository.LinkageKeys {
+	k := repository.LinkageKeys{
+		NationalID: p.NationalID,
+		PassportID: p.PassportID,
+		PatientHN:  p.PatientHN,
+		LastNameEN: prefix(normalizeName(p.LastNameEN), blockPrefix),
+		LastNameTH: prefix(normalizeName(p.LastNameTH), blockPrefix),
+	}
+	if d := digits(p.PhoneNumber); len(d) >= phoneShortSuffix {
+		k.PhoneSuffix = suffix(d, phoneShortSuffix)
+	}
+	if p.DateOfBirth != nil {
+		k.DatesOfBirth = append(k.DatesOfBirth, *p.DateOfBirth)
+		if t, err := time.Parse("2006-01-02", *p.DateOfBirth); err == nil && t.Day() <= 12 && t.Day() != int(t.Month()) {
+			k.DatesOfBirth = append(k.DatesOfBirth, fmt.Sprintf("%04d-%02d-%02d", t.Year(), t.Day(), int(t.Month())))
+		}
+	}
+	return k
+}
+
+func prefix(s string, n int) string {
+	r := []rune(s)
+	if len(r) < n {
+		return ""
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,119 @@
+package linkage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+func strptr(s string) *string { return &s }
+
+func TestJaroWinkler(t *testing.T) {
+	assert.InDelta(t, 0.961, JaroWinkler("martha", "marhta"), 0.001)
+	assert.InDelta(t, 0.840, JaroWinkler("dwayne", "duane"), 0.001)
+	assert.InDelta(t, 0.813, JaroWinkler("dixon", "dicksonx"), 0.001)
+	assert.Equal(t, 1.0, JaroWinkler("สมชาย", "สมชาย"))
+	assert.Greater(t, JaroWinkler("สมชาย", "สมชัย"), 0.85) // one vowel off
+	assert.Equal(t, 0.0, JaroWinkler("abc", ""))
+}
+
+func TestComparators(t *testing.T) {
+	assert.Equal(t, 1.0, compareDOB("1985-05-12", "1985-05-12"))
+	assert.Equal(t, dobSwapped, compareDOB("1985-05-12", "1985-12-05"))
+	assert.Equal(t, dobTypo, compareDOB("1985-05-12", "1985-05-13"))
+	assert.Equal(t, 0.0, compareDOB("1985-05-12", "1990-01-01"))
+
+	assert.Equal(t, 1.0, comparePhone("081-234-5678", "+66 81 234 5678"))
+	assert.Equal(t, 0.5, comparePhone("0812345678", "0899995678"))
+	assert.Equal(t, 0.0, comparePhone("0812345678", "0812345600"))
+
+	assert.Equal(t, 1.0, compareIdentifier("ab-1234567", "AB1234567"))
+	assert.Equal(t, identifierTypo, compareIdentifier("AB1234567", "AB1234576")) // adjacent swap
+	assert.Equal(t, identifierTypo, compareIdentifier("AB1234567", "AB123456"))  // dropped digit
+	assert.Equal(t, 0.0, compareIdentifier("AB1234567", "XY7654321"))
+}
+
+func TestEngine_Decisions(t *testing.T) {
+	e, err := New(DefaultConfig())
+	require.NoError(t, err)
+
+	stored := &repository.Patient{
+		ID: "p-1", PassportID: "AB1234567",
+		FirstNameTH: "สมชาย", LastNameTH: "ใจดี", FirstNameEN: "Somchai", LastNameEN: "Jaidee",
+		DateOfBirth: strptr("1985-05-12"), PhoneNumber: "0812345678", Gender: "M",
+	}
+
+	// same person, passport typo'd and no national id
+	typo := &repository.Patient{
+		PassportID: "AB1234576", FirstNameEN: "Somchai", LastNameEN: "Jaidee",
+		DateOfBirth: strptr("1985-05-12"), Gender: "M",
+	}
+	assert.Equal(t, DecisionMatch, e.Compare(typo, stored).Decision)
+
+	// same person, Thai names only, DOB day/month swapped
+	swapped := &repository.Patient{
+		FirstNameTH: "สมชาย", LastNameTH: "ใจดี", DateOfBirth: strptr("1985-12-05"), PhoneNumber: "+66 81 234 5678",
+	}
+	assert.Equal(t, DecisionMatch, e.Compare(swapped, stored).Decision)
+
+	// a brother: same surname and phone, different first name and DOB
+	brother := &repository.Patient{
+		FirstNameEN: "Somsak", LastNameEN: "Jaidee", DateOfBirth: strptr("1988-02-20"),
+		PhoneNumber: "0812345678", Gender: "M",
+	}
+	assert.Equal(t, DecisionPossible, e.Compare(brother, stored).Decision)
+
+	// a stranger born the same day
+	stranger := &repository.Patient{
+		PassportID: "XY7654321", FirstNameEN: "John", LastNameEN: "Smith", DateOfBirth: strptr("1985-05-12"), Gender: "M",
+	}
+	res := e.Compare(stranger, stored)
+	assert.Equal(t, DecisionNonMatch, res.Decision)
+	assert.Len(t, res.Fields, 5) // Thai names, phone and email missing on one side
+}
+
+func TestEngine_RankSkipsSelfAndNonMatches(t *testing.T) {
+	e, err := New(DefaultConfig())
+	require.NoError(t, err)
+
+	probe := &repository.Patient{ID: "p-1", FirstNameEN: "Somchai", LastNameEN: "Jaidee", DateOfBirth: strptr("1985-05-12")}
+	got := e.Rank(probe, []*repository.Patient{
+		probe,
+		{ID: "p-2", FirstNameEN: "John", LastNameEN: "Smith", DateOfBirth: strptr("1970-01-01")},
+		{ID: "p-3", FirstNameEN: "Somchay", LastNameEN: "Jaidee", DateOfBirth: strptr("1985-12-05")},
+		{ID: "p-4", FirstNameEN: "Somchai", LastNameEN: "Jaidee", DateOfBirth: strptr("1985-05-12")},
+	})
+	require.Len(t, got, 2)
+	assert.Equal(t, "p-4", got[0].ID)
+	assert.Equal(t, "p-3", got[1].ID)
+	assert.Greater(t, got[0].Score, got[1].Score)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"no fields":  {},
+		"field":      {Fields: []FieldConfig{{Field: "shoe_size", Comparator: ComparatorExact, M: 0.9, U: 0.1}}},
+		"comparator": {Fields: []FieldConfig{{Field: "email", Comparator: "soundex", M: 0.9, U: 0.1}}},
+		"m":          {Fields: []FieldConfig{{Field: "email", Comparator: ComparatorExact, M: 1, U: 0.1}}},
+		"thresholds": {Fields: []FieldConfig{{Field: "email", Comparator: ComparatorExact, M: 0.9, U: 0.1}}, Match: 5, Possible: 10},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestBlockingKeys(t *testing.T) {
+	k := BlockingKeys(&repository.Patient{
+		LastNameEN: "Jai Dee", LastNameTH: "ใจดี", PhoneNumber: "081-234-5678", DateOfBirth: strptr("1985-05-12"),
+	})
+	assert.Equal(t, []string{"1985-05-12", "1985-12-05"}, k.DatesOfBirth)
+	assert.Equal(t, "5678", k.PhoneSuffix)
+	assert.Equal(t, "jai", k.LastNameEN)
+	assert.Equal(t, "ใจด", k.LastNameTH)
+
+	k = BlockingKeys(&repository.Patient{DateOfBirth: strptr("1985-05-25")})
+	assert.Equal(t, []string{"1985-05-25"}, k.DatesOfBirth) // no month 25
+}
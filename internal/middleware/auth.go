@@ -54,8 +54,10 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		}
 
 		// Extract expected claims and put them into Gin context
+		var staffID string
 		if sub, ok := claims["sub"].(string); ok {
 			c.Set("staff_id", sub)
+			staffID = sub
 		}
 		var hospitalID string
 		if hid, ok := claims["hospital_id"].(string); ok {
@@ -69,11 +71,15 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 			c.Set("role", role)
 		}
 
-		// ALSO put hospital_id into the standard request context so services can read it.
+		// ALSO put hospital_id / staff_id into the standard request context so services can read them.
+		ctx := c.Request.Context()
 		if hospitalID != "" {
-			ctx := context.WithValue(c.Request.Context(), "hospital_id", hospitalID)
-			c.Request = c.Request.WithContext(ctx)
+			ctx = context.WithValue(ctx, "hospital_id", hospitalID)
 		}
+		if staffID != "" {
+			ctx = context.WithValue(ctx, "staff_id", staffID)
+		}
+		c.Request = c.Request.WithContext(ctx)
 
 		c.Next()
 	}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole returns a Gin middleware that only lets through requests whose
+// token role (set by AuthMiddleware) is one of roles. Use it after AuthMiddleware.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(roles))
+	for _, r := range roles {
+		allowed[r] = true
+	}
+
+	return func(c *gin.Context) {
+		role, _ := c.Get("role")
+		if r, ok := role.(string); !ok || !allowed[r] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,84 @@
+package outbox
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// brokerSeenEvents is how many recent event ids the broker remembers to
+// drop relay retries; retries follow their first attempt closely.
+const brokerSeenEvents = 4096
+
+// Broker fans events out to in-process subscribers.
+// Events whose EventID was published recently are dropped, so relay retries
+// never reach subscribers twice. Sequences are not used for this: a
+// transaction with a lower outbox id may commit after one with a higher id.
+type Broker struct {
+	mu     sync.Mutex
+	seen   map[string]struct{}
+	recent []string // seen ids, oldest first
+	nextID int
+	subs   map[int]chan Event
+}
+
+func NewBroker() *Broker {
+	return &Broker{seen: make(map[string]struct{}), subs: make(map[int]chan Event)}
+}
+
+func (b *Broker) Name() string { return "broker" }
+
+// Publish delivers e to every subscriber without blocking.
+// A subscriber whose buffer is full is dropped and its channel closed, so it
+// never silently misses an event; it should resubscribe and replay from the
+// outbox by sequence (see Feed.Since).
+func (b *Broker) Publish(_ context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if e.EventID != "" {
+		if _, dup := b.seen[e.EventID]; dup {
+			return nil
+		}
+		b.seen[e.EventID] = struct{}{}
+		b.recent = append(b.recent, e.EventID)
+		if len(b.recent) > brokerSeenEvents {
+			delete(b.seen, b.recent[0])
+			b.recent = b.recent[1:]
+		}
+	}
+
+	for id, ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			log.Printf("outbox broker: subscriber %d is full at seq=%d; dropping subscriber", id, e.Sequence)
+			delete(b.subs, id)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a subscriber with the given buffer size.
+// The returned cancel func unsubscribes and closes the channel.
+func (b *Broker) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = ch
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		// the broker may already have dropped (and closed) a slow subscriber
+		if cur, ok := b.subs[id]; ok && cur == ch {
+			delete(b.subs, id)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Patient event types. There is no merge event: records are never merged,
+// likely duplicates are only flagged for review (see service.Linker).
+const (
+	EventPatientCreated = "patient.created"
+	EventPatientUpdated = "patient.updated"
+)
+
+// Event is what sinks receive. Sequence is the outbox row id and increases
+// monotonically; EventID is stable across redeliveries and should be used to dedupe.
+type Event struct {
+	EventID    string          `json:"event_id"`
+	Sequence   int64           `json:"sequence"`
+	Type       string          `json:"type"`
+	PatientID  string          `json:"patient_id"`
+	HospitalID string          `json:"hospital_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Patient    json.RawMessage `json:"patient"`
+}
+
+// NewPatientEvent builds the outbox row for a patient change.
+func NewPatientEvent(eventType string, p *repository.Patient) (*repository.OutboxEvent, error) {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return nil, fmt.Errorf("marshal patient: %w", err)
+	}
+	return &repository.OutboxEvent{
+		EventID:    uuid.NewString(),
+		EventType:  eventType,
+		PatientID:  p.ID,
+		HospitalID: p.HospitalID,
+		Payload:    payload,
+	}, nil
+}
+
+// FromRow converts an outbox row to the published Event.
+func FromRow(row *repository.OutboxEvent) Event {
+	return Event{
+		EventID:    row.EventID,
+		Sequence:   row.ID,
+		Type:       row.EventType,
+		PatientID:  row.PatientID,
+		HospitalID: row.HospitalID,
+		OccurredAt: row.CreatedAt,
+		Patient:    json.RawMessage(row.Payload),
+	}
+}
@@ -0,0 +1,37 @@
+package outbox
+
+import (
+	"context"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Feed combines live events from the Broker with replay from patient_outbox,
+// for consumers that resume from a known sequence (e.g. SSE Last-Event-ID).
+type Feed struct {
+	broker *Broker
+	repo   *repository.OutboxRepo
+}
+
+func NewFeed(broker *Broker, repo *repository.OutboxRepo) *Feed {
+	return &Feed{broker: broker, repo: repo}
+}
+
+// Subscribe registers a live subscriber on the broker.
+func (f *Feed) Subscribe(buffer int) (<-chan Event, func()) {
+	return f.broker.Subscribe(buffer)
+}
+
+// Since returns up to limit released events of hospitalID with sequence >
+// afterSeq, in order.
+func (f *Feed) Since(ctx context.Context, hospitalID string, afterSeq int64, limit int) ([]Event, error) {
+	rows, err := f.repo.ListByHospitalAfter(ctx, hospitalID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Event, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, FromRow(row))
+	}
+	return out, nil
+}
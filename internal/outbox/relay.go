@@ -0,0 +1,179 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Relay publishes pending outbox events to sinks in sequence order.
+//
+// Delivery is at-least-once: an event is marked published only after every
+// sink accepted it. Sinks that already accepted an event are recorded and
+// skipped when the event is retried. A failing event blocks later events
+// until it succeeds, so consumers always see events in order.
+//
+// Outbox ids are assigned on insert, not on commit, so a lower id may become
+// visible after a higher one. An event is held while an id below it is
+// missing, until gapGrace has passed; a missing id is usually a rolled-back
+// transaction. An event committed later than that is still published, out
+// of order.
+type Relay struct {
+	uow       repository.UnitOfWork
+	sinks     []Sink
+	batchSize int
+	interval  time.Duration
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	gapGrace  time.Duration
+	now       func() time.Time
+
+	gapAt    int64 // event held for missing lower ids, and since when
+	gapSince time.Time
+}
+
+// NewRelay constructs a Relay polling every interval.
+func NewRelay(uow repository.UnitOfWork, interval time.Duration, sinks ...Sink) *Relay {
+	return &Relay{
+		uow:       uow,
+		sinks:     sinks,
+		batchSize: 100,
+		interval:  interval,
+		baseDelay: time.Second,
+		maxDelay:  5 * time.Minute,
+		gapGrace:  30 * time.Second,
+		now:       time.Now,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (r *Relay) Run(ctx context.Context) {
+	t := time.NewTicker(r.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := r.ProcessOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("outbox relay error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ProcessOnce publishes one batch and returns how many events were published.
+func (r *Relay) ProcessOnce(ctx context.Context) (int, error) {
+	published := 0
+	err := r.uow.Do(ctx, func(repos *repository.Repos) error {
+		ok, err := repos.Outbox.TryLock(ctx)
+		if err != nil {
+			return fmt.Errorf("outbox lock: %w", err)
+		}
+		if !ok {
+			// another relay is publishing
+			return nil
+		}
+
+		rows, err := repos.Outbox.FetchPending(ctx, r.batchSize)
+		if err != nil {
+			return fmt.Errorf("fetch pending: %w", err)
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		released, err := repos.Outbox.LastReleased(ctx)
+		if err != nil {
+			return fmt.Errorf("last released: %w", err)
+		}
+
+		for _, row := range rows {
+			if row.NextAttemptAt.After(r.now()) {
+				// head of the queue is backing off; keep order by waiting for it
+				return nil
+			}
+			if row.Attempts == 0 && row.ID > released+1 && r.holdForGap(row.ID) {
+				// a lower id may belong to a transaction that has not committed yet
+				return nil
+			}
+			if row.ID < released {
+				log.Printf("outbox relay: event seq=%d (%s) committed after seq=%d was published", row.ID, row.EventType, released)
+			}
+			released = max(released, row.ID)
+
+			delivered, pubErr := r.publish(ctx, row)
+			if pubErr != nil {
+				next := r.now().Add(r.backoff(row.Attempts + 1))
+				if err := repos.Outbox.MarkFailed(ctx, row.ID, delivered, pubErr.Error(), next); err != nil {
+					return fmt.Errorf("mark failed: %w", err)
+				}
+				log.Printf("outbox relay: event seq=%d (%s) failed attempt %d: %v", row.ID, row.EventType, row.Attempts+1, pubErr)
+				return nil
+			}
+
+			if err := repos.Outbox.MarkPublished(ctx, row.ID); err != nil {
+				return fmt.Errorf("mark published: %w", err)
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
+
+// publish sends the event to every sink that has not accepted it yet and
+// returns the names of sinks that have.
+func (r *Relay) publish(ctx context.Context, row *repository.OutboxEvent) ([]string, error) {
+	done := make(map[string]bool, len(row.DeliveredSinks))
+	for _, name := range row.DeliveredSinks {
+		done[name] = true
+	}
+
+	delivered := append([]string{}, row.DeliveredSinks...)
+	var errs []string
+	e := FromRow(row)
+	for _, s := range r.sinks {
+		if done[s.Name()] {
+			continue
+		}
+		if err := s.Publish(ctx, e); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.Name(), err))
+			continue
+		}
+		delivered = append(delivered, s.Name())
+	}
+	if len(errs) > 0 {
+		return delivered, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return delivered, nil
+}
+
+// holdForGap reports whether the event with id is still held for missing
+// lower ids; they are given up gapGrace after the event was first held.
+func (r *Relay) holdForGap(id int64) bool {
+	if r.gapAt != id {
+		r.gapAt, r.gapSince = id, r.now()
+	}
+	if r.now().Sub(r.gapSince) < r.gapGrace {
+		return true
+	}
+	log.Printf("outbox relay: ids below seq=%d missing for %s; publishing it", id, r.gapGrace)
+	return false
+}
+
+// backoff returns the exponential retry delay for the given attempt (1-based).
+func (r *Relay) backoff(attempt int) time.Duration {
+	d := r.baseDelay
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= r.maxDelay {
+			return r.maxDelay
+		}
+	}
+	return d
+}
@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// recordingSink records published events and fails while err is set.
+type recordingSink struct {
+	name string
+	err  error
+	got  []Event
+}
+
+func (s *recordingSink) Name() string { return s.name }
+
+func (s *recordingSink) Publish(_ context.Context, e Event) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.got = append(s.got, e)
+	return nil
+}
+
+// outboxLockKeyForTest mirrors the advisory lock key used by repository.OutboxRepo.
+const outboxLockKeyForTest = 727001
+
+var outboxCols = []string{
+	"id", "event_id", "event_type", "patient_id", "hospital_id", "payload", "created_at",
+	"attempts", "delivered_sinks", "next_attempt_at",
+}
+
+func expectLastReleased(mock pgxmock.PgxPoolIface, id int64) {
+	mock.ExpectQuery(`SELECT COALESCE\(max\(id\), 0\) FROM patient_outbox`).
+		WillReturnRows(pgxmock.NewRows([]string{"max"}).AddRow(id))
+}
+
+func TestRelay_PublishesInOrder(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	hid := "HIS-1"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+		WithArgs(outboxLockKeyForTest).
+		WillReturnRows(pgxmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectQuery(`FROM patient_outbox`).
+		WithArgs(100).
+		WillReturnRows(pgxmock.NewRows(outboxCols).
+			AddRow(int64(1), "e1", EventPatientCreated, "p1", &hid, []byte(`{}`), now, 0, []string{}, now.Add(-time.Second)).
+			AddRow(int64(2), "e2", EventPatientUpdated, "p1", &hid, []byte(`{}`), now, 0, []string{}, now.Add(-time.Second)))
+	expectLastReleased(mock, 0)
+	mock.ExpectExec(`UPDATE patient_outbox SET published_at`).WithArgs(int64(1)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE patient_outbox SET published_at`).WithArgs(int64(2)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	sink := &recordingSink{name: "rec"}
+	relay := NewRelay(repository.NewUnitOfWork(mock), time.Second, sink)
+
+	n, err := relay.ProcessOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	if assert.Len(t, sink.got, 2) {
+		assert.Equal(t, int64(1), sink.got[0].Sequence)
+		assert.Equal(t, "e2", sink.got[1].EventID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_FailureStopsBatchAndSkipsDeliveredSinks(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	hid := "HIS-1"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+		WithArgs(outboxLockKeyForTest).
+		WillReturnRows(pgxmock.NewRows([]string{"ok"}).AddRow(true))
+	mock.ExpectQuery(`FROM patient_outbox`).
+		WithArgs(100).
+		WillReturnRows(pgxmock.NewRows(outboxCols).
+			AddRow(int64(1), "e1", EventPatientCreated, "p1", &hid, []byte(`{}`), now, 2, []string{"done"}, now.Add(-time.Second)).
+			AddRow(int64(2), "e2", EventPatientUpdated, "p1", &hid, []byte(`{}`), now, 0, []string{}, now.Add(-time.Second)))
+	expectLastReleased(mock, 1)
+	mock.ExpectExec(`UPDATE patient_outbox SET attempts = attempts \+ 1, delivered_sinks`).
+		WithArgs(int64(1), []string{"done", "ok"}, "bad: down", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	done := &recordingSink{name: "done"}
+	ok := &recordingSink{name: "ok"}
+	bad := &recordingSink{name: "bad", err: errors.New("down")}
+	relay := NewRelay(repository.NewUnitOfWork(mock), time.Second, done, ok, bad)
+
+	n, err := relay.ProcessOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Empty(t, done.got, "sink that already accepted the event is skipped")
+	assert.Len(t, ok.got, 1, "event 2 must not be published while event 1 is failing")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_HoldsEventUntilLowerIDsCommitOrGraceEnds(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	hid := "HIS-1"
+	// seq 3 is visible while seq 2 (an open transaction) is not
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT pg_try_advisory_xact_lock`).
+			WithArgs(outboxLockKeyForTest).
+			WillReturnRows(pgxmock.NewRows([]string{"ok"}).AddRow(true))
+		mock.ExpectQuery(`FROM patient_outbox`).
+			WithArgs(100).
+			WillReturnRows(pgxmock.NewRows(outboxCols).
+				AddRow(int64(3), "e3", EventPatientUpdated, "p1", &hid, []byte(`{}`), now, 0, []string{}, now.Add(-time.Second)))
+		expectLastReleased(mock, 1)
+		if i == 1 {
+			mock.ExpectExec(`UPDATE patient_outbox SET published_at`).WithArgs(int64(3)).WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+		}
+		mock.ExpectCommit()
+	}
+
+	sink := &recordingSink{name: "rec"}
+	relay := NewRelay(repository.NewUnitOfWork(mock), time.Second, sink)
+	clock := now
+	relay.now = func() time.Time { return clock }
+
+	n, err := relay.ProcessOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "held while seq 2 may still commit")
+	assert.Empty(t, sink.got)
+
+	clock = clock.Add(relay.gapGrace)
+	n, err = relay.ProcessOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n, "seq 2 given up after the grace period")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRelay_Backoff(t *testing.T) {
+	r := NewRelay(nil, time.Second)
+	assert.Equal(t, time.Second, r.backoff(1))
+	assert.Equal(t, 4*time.Second, r.backoff(3))
+	assert.Equal(t, 5*time.Minute, r.backoff(30))
+}
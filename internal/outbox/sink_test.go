@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookSink_Publish(t *testing.T) {
+	var got Event
+	var idem string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idem = r.Header.Get("Idempotency-Key")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer ts.Close()
+
+	s := NewWebhookSink(ts.URL, time.Second)
+	err := s.Publish(context.Background(), Event{EventID: "e1", Sequence: 7, Type: EventPatientCreated})
+	assert.NoError(t, err)
+	assert.Equal(t, "e1", idem)
+	assert.Equal(t, int64(7), got.Sequence)
+}
+
+func TestWebhookSink_BadStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	s := NewWebhookSink(ts.URL, time.Second)
+	assert.Error(t, s.Publish(context.Background(), Event{EventID: "e1"}))
+}
+
+func TestFileSink_AppendsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	s := NewFileSink(path)
+	assert.NoError(t, s.Publish(context.Background(), Event{EventID: "e1", Sequence: 1}))
+	assert.NoError(t, s.Publish(context.Background(), Event{EventID: "e2", Sequence: 2}))
+
+	b, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[1], `"event_id":"e2"`)
+}
+
+func TestBroker_FanOutAndDedupe(t *testing.T) {
+	b := NewBroker()
+	ch1, cancel1 := b.Subscribe(4)
+	ch2, cancel2 := b.Subscribe(4)
+	defer cancel2()
+
+	assert.NoError(t, b.Publish(context.Background(), Event{EventID: "e1", Sequence: 1}))
+	assert.NoError(t, b.Publish(context.Background(), Event{EventID: "e3", Sequence: 3}))
+	assert.NoError(t, b.Publish(context.Background(), Event{EventID: "e1", Sequence: 1})) // relay retry
+	assert.NoError(t, b.Publish(context.Background(), Event{EventID: "e2", Sequence: 2})) // committed late
+
+	assert.Equal(t, "e1", (<-ch1).EventID)
+	assert.Equal(t, "e1", (<-ch2).EventID)
+	assert.Equal(t, "e3", (<-ch1).EventID)
+	assert.Equal(t, "e2", (<-ch1).EventID, "a lower sequence published later still reaches subscribers")
+	assert.Len(t, ch1, 0)
+
+	cancel1()
+	_, open := <-ch1
+	assert.False(t, open)
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	ch, cancel := b.Subscribe(1)
+	defer cancel()
+
+	assert.NoError(t, b.Publish(context.Background(), Event{Sequence: 1}))
+	assert.NoError(t, b.Publish(context.Background(), Event{Sequence: 2})) // buffer full
+
+	assert.Equal(t, int64(1), (<-ch).Sequence)
+	_, open := <-ch
+	assert.False(t, open, "slow subscriber is closed instead of missing events")
+}
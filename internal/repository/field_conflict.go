@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Field conflict statuses.
+const (
+	ConflictPending   = "pending"
+	ConflictAccepted  = "accepted" // the overridden value was applied by an admin
+	ConflictDismissed = "dismissed"
+)
+
+// FieldConflict is a row of patient_field_conflicts: a write to a field that
+// the hospital's survivorship policy refused.
+type FieldConflict struct {
+	ID               string     `json:"id"`
+	PatientID        string     `json:"patient_id"`
+	HospitalID       string     `json:"hospital_id"`
+	Field            string     `json:"field"`
+	KeptValue        string     `json:"kept_value"`
+	KeptSource       string     `json:"kept_source"`
+	OverriddenValue  string     `json:"overridden_value"`
+	OverriddenSource string     `json:"overridden_source"`
+	Status           string     `json:"status"`
+	Hits             int        `json:"hits"`
+	ReviewedBy       *string    `json:"reviewed_by,omitempty"`
+	ReviewedAt       *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	LastSeenAt       time.Time  `json:"last_seen_at"`
+}
+
+// FieldConflictRepo persists refused field writes for review.
+type FieldConflictRepo struct {
+	pool DBPool
+}
+
+func NewFieldConflictRepo(pool DBPool) *FieldConflictRepo {
+	return &FieldConflictRepo{pool: pool}
+}
+
+// Add stores c as pending. If the same value was already refused for the
+// field and is still pending, that conflict is refreshed instead.
+func (r *FieldConflictRepo) Add(ctx context.Context, c *FieldConflict) error {
+	return r.pool.QueryRow(ctx,
+		`INSERT INTO patient_field_conflicts
+		   (patient_id, hospital_id, field, kept_value, kept_source, overridden_value, overridden_source)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7)
+		 ON CONFLICT (patient_id, field, overridden_value) WHERE status = 'pending' DO UPDATE SET
+		   kept_value = EXCLUDED.kept_value, kept_source = EXCLUDED.kept_source,
+		   overridden_source = EXCLUDED.overridden_source,
+		   hits = patient_field_conflicts.hits + 1, last_seen_at = now()
+		 RETURNING id`,
+		c.PatientID, c.HospitalID, c.Field, c.KeptValue, c.KeptSource, c.OverriddenValue, c.OverriddenSource,
+	).Scan(&c.ID)
+}
+
+const fieldConflictCols = `id, patient_id, hospital_id, field, kept_value, kept_source,
+overridden_value, overridden_source, status, hits, reviewed_by, reviewed_at, created_at, last_seen_at`
+
+// List returns the hospital's conflicts, newest first; status "" means any.
+func (r *FieldConflictRepo) List(ctx context.Context, hospitalID, status string, limit, offset int) ([]*FieldConflict, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+fieldConflictCols+` FROM patient_field_conflicts
+		 WHERE hospital_id = $1 AND ($2 = '' OR status = $2)
+		 ORDER BY last_seen_at DESC LIMIT $3 OFFSET $4`,
+		hospitalID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*FieldConflict
+	for rows.Next() {
+		c, err := scanFieldConflict(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// GetPendingForUpdate locks and returns the hospital's pending conflict id.
+// Returns (nil, nil) if there is none. Call it inside a unit of work.
+func (r *FieldConflictRepo) GetPendingForUpdate(ctx context.Context, hospitalID, id string) (*FieldConflict, error) {
+	row := r.pool.QueryRow(ctx,
+		`SELECT `+fieldConflictCols+` FROM patient_field_conflicts
+		 WHERE id = $1 AND hospital_id = $2 AND status = 'pending' FOR UPDATE`,
+		id, hospitalID)
+	c, err := scanFieldConflict(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+// Resolve records an admin's decision on conflict id.
+func (r *FieldConflictRepo) Resolve(ctx context.Context, id, status, reviewedBy string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE patient_field_conflicts SET status = $2, reviewed_by = $3, reviewed_at = now() WHERE id = $1`,
+		id, status, reviewedBy)
+	return err
+}
+
+func scanFieldConflict(row pgx.Row) (*FieldConflict, error) {
+	var c FieldConflict
+	if err := row.Scan(
+		&c.ID, &c.PatientID, &c.HospitalID, &c.Field, &c.KeptValue, &c.KeptSource,
+		&c.OverriddenValue, &c.OverriddenSource, &c.Status, &c.Hits,
+		&c.ReviewedBy, &c.ReviewedAt, &c.CreatedAt, &c.LastSeenAt,
+	); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import "context"
+
+// HL7 message statuses.
+const (
+	HL7Received = "received"
+	HL7Accepted = "accepted" // ACK AA
+	HL7Rejected = "rejected" // ACK AR: the message will never be processable
+	HL7Error    = "error"    // ACK AE: processing failed, the sender may resend
+)
+
+// HL7Message is a row of hl7_messages.
+type HL7Message struct {
+	ID          int64
+	HospitalID  string
+	ControlID   string
+	MessageType string
+	Raw         string
+}
+
+// HL7MessageRepo archives raw HL7 v2 messages.
+type HL7MessageRepo struct {
+	pool DBPool
+}
+
+func NewHL7MessageRepo(pool DBPool) *HL7MessageRepo {
+	return &HL7MessageRepo{pool: pool}
+}
+
+// Archive stores m with status "received" and sets m.ID.
+func (r *HL7MessageRepo) Archive(ctx context.Context, m *HL7Message) error {
+	return r.pool.QueryRow(ctx,
+		`INSERT INTO hl7_messages (hospital_id, control_id, message_type, raw)
+		 VALUES ($1, $2, $3, $4) RETURNING id`,
+		m.HospitalID, m.ControlID, m.MessageType, m.Raw,
+	).Scan(&m.ID)
+}
+
+// SetStatus records the outcome of processing message id.
+// patientID and errMsg may be empty.
+func (r *HL7MessageRepo) SetStatus(ctx context.Context, id int64, status, patientID, errMsg string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE hl7_messages
+		 SET status = $2, patient_id = NULLIF($3, '')::uuid, error = NULLIF($4, ''), processed_at = now()
+		 WHERE id = $1`,
+		id, status, patientID, errMsg)
+	return err
+}
@@ -0,0 +1,41 @@
+package repository
+
+import "context"
+
+// HospitalSystem is a row of hospital_systems: the raw HIS adapter config of one hospital.
+type HospitalSystem struct {
+	HospitalID string
+	Config     []byte
+}
+
+// HospitalSystemRepo reads HIS adapter configuration.
+type HospitalSystemRepo struct {
+	pool DBPool
+}
+
+func NewHospitalSystemRepo(pool DBPool) *HospitalSystemRepo {
+	return &HospitalSystemRepo{pool: pool}
+}
+
+// ListEnabled returns the config of every enabled hospital.
+func (r *HospitalSystemRepo) ListEnabled(ctx context.Context) ([]*HospitalSystem, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT hospital_id, config FROM hospital_systems WHERE enabled ORDER BY hospital_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*HospitalSystem
+	for rows.Next() {
+		var hs HospitalSystem
+		if err := rows.Scan(&hs.HospitalID, &hs.Config); err != nil {
+			return nil, err
+		}
+		out = append(out, &hs)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
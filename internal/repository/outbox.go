@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// outboxLockKey is the advisory lock that keeps a single relay publishing at a time.
+const outboxLockKey = 727001
+
+// OutboxEvent is a row of patient_outbox.
+type OutboxEvent struct {
+	ID             int64 // sequence; publish order
+	EventID        string
+	EventType      string
+	PatientID      string
+	HospitalID     string
+	Payload        []byte
+	CreatedAt      time.Time
+	Attempts       int
+	DeliveredSinks []string
+	NextAttemptAt  time.Time
+}
+
+// OutboxRepo reads and writes patient_outbox.
+type OutboxRepo struct {
+	pool DBPool
+}
+
+func NewOutboxRepo(pool DBPool) *OutboxRepo {
+	return &OutboxRepo{pool: pool}
+}
+
+// Enqueue appends an event. Call it inside the transaction that changes the patient.
+func (r *OutboxRepo) Enqueue(ctx context.Context, e *OutboxEvent) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO patient_outbox (event_id, event_type, patient_id, hospital_id, payload) VALUES ($1,$2,$3,$4,$5)`,
+		e.EventID, e.EventType, e.PatientID, e.HospitalID, e.Payload,
+	)
+	return err
+}
+
+// TryLock takes the relay advisory lock for the current transaction.
+// Returns false if another relay holds it.
+func (r *OutboxRepo) TryLock(ctx context.Context) (bool, error) {
+	var ok bool
+	err := r.pool.QueryRow(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLockKey).Scan(&ok)
+	return ok, err
+}
+
+// FetchPending returns the oldest unpublished events in id order, due or not.
+// The relay stops at the first event that is not due yet so ordering is kept.
+func (r *OutboxRepo) FetchPending(ctx context.Context, limit int) ([]*OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id, event_id, event_type, patient_id, hospital_id, payload, created_at,
+       attempts, delivered_sinks, next_attempt_at
+FROM patient_outbox
+WHERE published_at IS NULL
+ORDER BY id
+LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*OutboxEvent
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LastReleased returns the highest id the relay has attempted to publish (0 if none).
+func (r *OutboxRepo) LastReleased(ctx context.Context) (int64, error) {
+	var id int64
+	err := r.pool.QueryRow(ctx, `SELECT COALESCE(max(id), 0) FROM patient_outbox WHERE attempts > 0`).Scan(&id)
+	return id, err
+}
+
+// ListByHospitalAfter returns events for a hospital with id > afterID that the
+// relay has released (attempted to publish). Used to replay history to
+// consumers that resume from a known sequence: the relay releases in id
+// order, so unreleased rows arrive live instead.
+func (r *OutboxRepo) ListByHospitalAfter(ctx context.Context, hospitalID string, afterID int64, limit int) ([]*OutboxEvent, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id, event_id, event_type, patient_id, hospital_id, payload, created_at,
+       attempts, delivered_sinks, next_attempt_at
+FROM patient_outbox
+WHERE hospital_id = $1 AND id > $2 AND attempts > 0
+ORDER BY id
+LIMIT $3`, hospitalID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*OutboxEvent
+	for rows.Next() {
+		e, err := scanOutboxEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkPublished records that every sink accepted the event.
+func (r *OutboxRepo) MarkPublished(ctx context.Context, id int64) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE patient_outbox SET published_at = now(), attempts = attempts + 1, last_error = NULL WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+// MarkFailed records a failed attempt, the sinks that already accepted the event and when to retry.
+func (r *OutboxRepo) MarkFailed(ctx context.Context, id int64, deliveredSinks []string, lastErr string, nextAttempt time.Time) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE patient_outbox SET attempts = attempts + 1, delivered_sinks = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1`,
+		id, deliveredSinks, lastErr, nextAttempt,
+	)
+	return err
+}
+
+type outboxScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanOutboxEvent(row outboxScanner) (*OutboxEvent, error) {
+	var e OutboxEvent
+	var hospitalID *string
+	if err := row.Scan(
+		&e.ID,
+		&e.EventID,
+		&e.EventType,
+		&e.PatientID,
+		&hospitalID,
+		&e.Payload,
+		&e.CreatedAt,
+		&e.Attempts,
+		&e.DeliveredSinks,
+		&e.NextAttemptAt,
+	); err != nil {
+		return nil, err
+	}
+	if hospitalID != nil {
+		e.HospitalID = *hospitalID
+	}
+	return &e, nil
+}
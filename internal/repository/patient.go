@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -31,6 +32,80 @@ type Patient struct {
 	HospitalID   string // which hospital the patient belongs to
 }
 
+// Field returns the value of the standard field name (a patients column,
+// e.g. "phone_number"); unknown names and an unset DOB give "".
+func (p *Patient) Field(name string) string {
+	switch name {
+	case "patient_hn":
+		return p.PatientHN
+	case "national_id":
+		return p.NationalID
+	case "passport_id":
+		return p.PassportID
+	case "first_name_th":
+		return p.FirstNameTH
+	case "middle_name_th":
+		return p.MiddleNameTH
+	case "last_name_th":
+		return p.LastNameTH
+	case "first_name_en":
+		return p.FirstNameEN
+	case "middle_name_en":
+		return p.MiddleNameEN
+	case "last_name_en":
+		return p.LastNameEN
+	case "date_of_birth":
+		if p.DateOfBirth == nil {
+			return ""
+		}
+		return *p.DateOfBirth
+	case "phone_number":
+		return p.PhoneNumber
+	case "email":
+		return p.Email
+	case "gender":
+		return p.Gender
+	}
+	return ""
+}
+
+// SetField sets the standard field name; "" clears the DOB. Unknown names
+// are ignored.
+func (p *Patient) SetField(name, v string) {
+	switch name {
+	case "patient_hn":
+		p.PatientHN = v
+	case "national_id":
+		p.NationalID = v
+	case "passport_id":
+		p.PassportID = v
+	case "first_name_th":
+		p.FirstNameTH = v
+	case "middle_name_th":
+		p.MiddleNameTH = v
+	case "last_name_th":
+		p.LastNameTH = v
+	case "first_name_en":
+		p.FirstNameEN = v
+	case "middle_name_en":
+		p.MiddleNameEN = v
+	case "last_name_en":
+		p.LastNameEN = v
+	case "date_of_birth":
+		if v == "" {
+			p.DateOfBirth = nil
+		} else {
+			p.DateOfBirth = &v
+		}
+	case "phone_number":
+		p.PhoneNumber = v
+	case "email":
+		p.Email = v
+	case "gender":
+		p.Gender = v
+	}
+}
+
 // DBPool is a minimal subset of pgxpool.Pool used by the repo.
 type DBPool interface {
 	QueryRow(ctx context.Context, query string, args ...any) pgx.Row
@@ -73,6 +148,24 @@ FROM patients WHERE national_id = $1 OR passport_id = $1 LIMIT 1`, identifier)
 	return scanPatientRow(row)
 }
 
+// GetByIdentifierWithSync is GetByIdentifier plus when the patient was last
+// fetched from its HIS (nil if it never was).
+func (r *PatientRepo) GetByIdentifierWithSync(ctx context.Context, identifier string) (*Patient, *time.Time, error) {
+	row := r.pool.QueryRow(ctx, `
+SELECT id, patient_hn, national_id, passport_id,
+       first_name_th, middle_name_th, last_name_th,
+       first_name_en, middle_name_en, last_name_en,
+       date_of_birth, phone_number, email, gender, raw_json, hospital_id, his_synced_at
+FROM patients WHERE national_id = $1 OR passport_id = $1 LIMIT 1`, identifier)
+
+	var synced *time.Time
+	p, err := scanPatientRow(row, &synced)
+	if err != nil || p == nil {
+		return nil, nil, err
+	}
+	return p, synced, nil
+}
+
 // Create inserts a new patient
 func (r *PatientRepo) Create(ctx context.Context, p *Patient) error {
 	// Treat empty identifiers as NULL in DB
@@ -177,8 +270,101 @@ func (r *PatientRepo) Upsert(ctx context.Context, p *Patient) error {
 	return r.Create(ctx, p)
 }
 
+// MarkHISSynced records that the patient was just fetched from its HIS and
+// releases any reconciler claim on it.
+func (r *PatientRepo) MarkHISSynced(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE patients SET his_synced_at = now(), his_sync_claimed_at = NULL WHERE id = $1`, id)
+	return err
+}
+
+// ClaimStaleHIS claims up to limit patients of hospitalID last synced before
+// syncedBefore (oldest first) and returns them. Rows claimed by another
+// reconciler within lease are skipped; an unreleased claim expires after lease.
+func (r *PatientRepo) ClaimStaleHIS(ctx context.Context, hospitalID string, syncedBefore time.Time, limit int, lease time.Duration) ([]*Patient, error) {
+	rows, err := r.pool.Query(ctx, `
+UPDATE patients SET his_sync_claimed_at = now()
+WHERE id IN (
+  SELECT id FROM patients
+  WHERE hospital_id = $1 AND his_synced_at < $2
+    AND (his_sync_claimed_at IS NULL OR his_sync_claimed_at < now() - $4 * interval '1 second')
+  ORDER BY his_synced_at
+  LIMIT $3
+  FOR UPDATE SKIP LOCKED
+)
+RETURNING id, patient_hn, national_id, passport_id,
+          first_name_th, middle_name_th, last_name_th,
+          first_name_en, middle_name_en, last_name_en,
+          date_of_birth, phone_number, email, gender, raw_json, hospital_id`,
+		hospitalID, syncedBefore, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Patient
+	for rows.Next() {
+		p, err := scanPatientRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// LinkageKeys select the stored patients a record is compared with by
+// record linkage; empty keys select nothing.
+type LinkageKeys struct {
+	DatesOfBirth []string // yyyy-mm-dd
+	PhoneSuffix  string   // last digits of the phone number
+	LastNameEN   string   // lowercase prefix
+	LastNameTH   string   // prefix
+	NationalID   string
+	PassportID   string
+	PatientHN    string
+}
+
+// LinkageCandidates returns up to limit of the hospital's patients, other
+// than excludeID, sharing any key with k.
+func (r *PatientRepo) LinkageCandidates(ctx context.Context, hospitalID, excludeID string, k LinkageKeys, limit int) ([]*Patient, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id, patient_hn, national_id, passport_id,
+       first_name_th, middle_name_th, last_name_th,
+       first_name_en, middle_name_en, last_name_en,
+       date_of_birth, phone_number, email, gender, raw_json, hospital_id
+FROM patients
+WHERE hospital_id = $1 AND id::text <> $2 AND (
+     date_of_birth::text = ANY($3)
+  OR ($4 <> '' AND right(regexp_replace(phone_number, '[^0-9]', '', 'g'), length($4)) = $4)
+  OR ($5 <> '' AND regexp_replace(lower(last_name_en), '[ .''-]', '', 'g') LIKE $5 || '%')
+  OR ($6 <> '' AND regexp_replace(last_name_th, '[ .''-]', '', 'g') LIKE $6 || '%')
+  OR ($7 <> '' AND national_id = $7)
+  OR ($8 <> '' AND passport_id = $8)
+  OR ($9 <> '' AND patient_hn = $9)
+)
+LIMIT $10`,
+		hospitalID, excludeID, k.DatesOfBirth, k.PhoneSuffix, k.LastNameEN, k.LastNameTH,
+		k.NationalID, k.PassportID, k.PatientHN, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*Patient
+	for rows.Next() {
+		p, err := scanPatientRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
 // scanPatientRow scans pgx.Row into Patient (used by GetByIdentifier and others).
-func scanPatientRow(row pgx.Row) (*Patient, error) {
+// extra receives columns selected after hospital_id.
+func scanPatientRow(row pgx.Row, extra ...any) (*Patient, error) {
 	var p Patient
 	var dob sql.NullTime
 	var raw []byte
@@ -186,7 +372,7 @@ func scanPatientRow(row pgx.Row) (*Patient, error) {
 	var middleEN sql.NullString
 	var passport sql.NullString
 
-	err := row.Scan(
+	dest := []any{
 		&p.ID,
 		&p.PatientHN,
 		&p.NationalID,
@@ -203,7 +389,8 @@ func scanPatientRow(row pgx.Row) (*Patient, error) {
 		&p.Gender,
 		&raw,
 		&p.HospitalID,
-	)
+	}
+	err := row.Scan(append(dest, extra...)...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
@@ -237,6 +424,41 @@ type PatientFilters struct {
 	Email       string
 }
 
+// Matches reports whether p satisfies the filters, using the same rules as
+// SearchPatients (exact identifiers and DOB, case-insensitive substring for
+// English names, phone and email). Zero-value filters match every patient.
+func (f PatientFilters) Matches(p *Patient) bool {
+	contains := func(val, sub string) bool {
+		return strings.Contains(strings.ToLower(val), strings.ToLower(sub))
+	}
+	dob := ""
+	if p.DateOfBirth != nil {
+		dob = *p.DateOfBirth
+	}
+
+	switch {
+	case f.PatientHN != "" && p.PatientHN != f.PatientHN:
+		return false
+	case f.NationalID != "" && p.NationalID != f.NationalID:
+		return false
+	case f.PassportID != "" && p.PassportID != f.PassportID:
+		return false
+	case f.FirstName != "" && !contains(p.FirstNameEN, f.FirstName):
+		return false
+	case f.MiddleName != "" && !contains(p.MiddleNameEN, f.MiddleName):
+		return false
+	case f.LastName != "" && !contains(p.LastNameEN, f.LastName):
+		return false
+	case f.DateOfBirth != "" && dob != f.DateOfBirth:
+		return false
+	case f.PhoneNumber != "" && !contains(p.PhoneNumber, f.PhoneNumber):
+		return false
+	case f.Email != "" && !contains(p.Email, f.Email):
+		return false
+	}
+	return true
+}
+
 // SearchPatients searches patients by optional filters and restricts by hospital_id.
 // Returns (results, totalCount, error).
 func (r *PatientRepo) SearchPatients(ctx context.Context, hospitalID string, f PatientFilters, limit, offset int) ([]*Patient, int, error) {
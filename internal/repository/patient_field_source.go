@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// FieldProvenance says who last wrote a patient field and when.
+type FieldProvenance struct {
+	Source    string    `json:"source"`          // staff | his | hl7 | reconciler | quarantine
+	Actor     string    `json:"actor,omitempty"` // staff id; empty for automated writes
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PatientFieldSourceRepo records which source last wrote each patient field
+// (patient_field_sources).
+type PatientFieldSourceRepo struct {
+	pool DBPool
+}
+
+func NewPatientFieldSourceRepo(pool DBPool) *PatientFieldSourceRepo {
+	return &PatientFieldSourceRepo{pool: pool}
+}
+
+// Get returns the patient's field -> source map; fields written before
+// sources were tracked are absent.
+func (r *PatientFieldSourceRepo) Get(ctx context.Context, patientID string) (map[string]string, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT field, source FROM patient_field_sources WHERE patient_id = $1`, patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]string{}
+	for rows.Next() {
+		var field, source string
+		if err := rows.Scan(&field, &source); err != nil {
+			return nil, err
+		}
+		out[field] = source
+	}
+	return out, rows.Err()
+}
+
+// Set records source and actor ("" for automated writes) as the writer of fields.
+func (r *PatientFieldSourceRepo) Set(ctx context.Context, patientID string, fields []string, source, actor string) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO patient_field_sources (patient_id, field, source, actor)
+		 SELECT $1, f, $3, NULLIF($4, '') FROM unnest($2::text[]) AS f
+		 ON CONFLICT (patient_id, field) DO UPDATE SET
+		   source = EXCLUDED.source, actor = EXCLUDED.actor, updated_at = now()`,
+		patientID, fields, source, actor)
+	return err
+}
+
+// Provenance returns the patient's field -> provenance map; fields written
+// before provenance was tracked are absent.
+func (r *PatientFieldSourceRepo) Provenance(ctx context.Context, patientID string) (map[string]FieldProvenance, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT field, source, COALESCE(actor, ''), updated_at FROM patient_field_sources WHERE patient_id = $1`,
+		patientID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]FieldProvenance{}
+	for rows.Next() {
+		var field string
+		var fp FieldProvenance
+		if err := rows.Scan(&field, &fp.Source, &fp.Actor, &fp.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out[field] = fp
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Sources recorded in patient_versions.
+const (
+	SourceHIS   = "his"   // hospital adapter back-fill
+	SourceStaff = "staff" // staff entry via POST /v1/patients
+	SourceHL7   = "hl7"   // HL7 v2 ADT message pushed over MLLP
+
+	SourceReconciler = "reconciler" // scheduled refresh from the HIS
+	SourceQuarantine = "quarantine" // HIS record released from quarantine by an admin
+)
+
+// PatientHistoryRepo appends patient snapshots to patient_versions.
+type PatientHistoryRepo struct {
+	pool DBPool
+}
+
+func NewPatientHistoryRepo(pool DBPool) *PatientHistoryRepo {
+	return &PatientHistoryRepo{pool: pool}
+}
+
+// Record stores the patient as written by source.
+func (r *PatientHistoryRepo) Record(ctx context.Context, p *Patient, source string) error {
+	snapshot, err := json.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+	_, err = r.pool.Exec(ctx,
+		`INSERT INTO patient_versions (patient_id, hospital_id, source, snapshot) VALUES ($1,$2,$3,$4)`,
+		p.ID, p.HospitalID, source, snapshot,
+	)
+	return err
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// PatientLink is a row of patient_links: an ingested patient that record
+// linkage found likely to be the same person as a stored one.
+type PatientLink struct {
+	PatientID   string    `json:"patient_id"`
+	CandidateID string    `json:"candidate_id"`
+	HospitalID  string    `json:"hospital_id"`
+	Score       float64   `json:"score"`
+	Decision    string    `json:"decision"`
+	Source      string    `json:"source"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PatientLinkRepo persists likely duplicates flagged during ingestion.
+type PatientLinkRepo struct {
+	pool DBPool
+}
+
+func NewPatientLinkRepo(pool DBPool) *PatientLinkRepo {
+	return &PatientLinkRepo{pool: pool}
+}
+
+// Record stores l; a pair flagged again gets the new score and decision.
+func (r *PatientLinkRepo) Record(ctx context.Context, l PatientLink) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO patient_links (patient_id, candidate_id, hospital_id, score, decision, source)
+		 VALUES ($1,$2,$3,$4,$5,$6)
+		 ON CONFLICT (patient_id, candidate_id) DO UPDATE SET
+		   score = EXCLUDED.score, decision = EXCLUDED.decision, source = EXCLUDED.source, created_at = now()`,
+		l.PatientID, l.CandidateID, l.HospitalID, l.Score, l.Decision, l.Source)
+	return err
+}
+
+// List returns the hospital's flagged pairs, newest first.
+func (r *PatientLinkRepo) List(ctx context.Context, hospitalID string, limit, offset int) ([]PatientLink, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT patient_id, candidate_id, hospital_id, score, decision, source, created_at
+		 FROM patient_links WHERE hospital_id = $1
+		 ORDER BY created_at DESC LIMIT $2 OFFSET $3`,
+		hospitalID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []PatientLink
+	for rows.Next() {
+		var l PatientLink
+		if err := rows.Scan(&l.PatientID, &l.CandidateID, &l.HospitalID, &l.Score, &l.Decision, &l.Source, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
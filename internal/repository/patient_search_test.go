@@ -51,3 +51,15 @@ func TestSearchPatients_ByNationalID(t *testing.T) {
 	}
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestPatientFilters_Matches(t *testing.T) {
+	dob := "1990-01-01"
+	p := &Patient{NationalID: "N-1", FirstNameEN: "Somchai", PhoneNumber: "0812345678", DateOfBirth: &dob}
+
+	assert.True(t, PatientFilters{}.Matches(p))
+	assert.True(t, PatientFilters{NationalID: "N-1", FirstName: "somc", PhoneNumber: "5678"}.Matches(p))
+	assert.True(t, PatientFilters{DateOfBirth: "1990-01-01"}.Matches(p))
+	assert.False(t, PatientFilters{NationalID: "N-2"}.Matches(p))
+	assert.False(t, PatientFilters{LastName: "Jaidee"}.Matches(p))
+	assert.False(t, PatientFilters{DateOfBirth: "1990-01-02"}.Matches(p))
+}
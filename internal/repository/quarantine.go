@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Quarantine statuses.
+const (
+	QuarantinePending  = "pending"
+	QuarantineReleased = "released" // stored as a patient by an admin
+	QuarantineRejected = "rejected"
+)
+
+// QuarantinedRecord is a row of his_quarantine.
+type QuarantinedRecord struct {
+	ID         string     `json:"id"`
+	HospitalID string     `json:"hospital_id"`
+	Identifier string     `json:"identifier"`
+	Reasons    []string   `json:"reasons"`
+	Patient    *Patient   `json:"patient"`
+	RawBody    string     `json:"raw_body"`
+	Status     string     `json:"status"`
+	Hits       int        `json:"hits"`
+	PatientID  *string    `json:"patient_id,omitempty"`
+	ReviewedBy *string    `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+}
+
+// QuarantineRepo persists HIS records that failed validation.
+type QuarantineRepo struct {
+	pool DBPool
+}
+
+func NewQuarantineRepo(pool DBPool) *QuarantineRepo {
+	return &QuarantineRepo{pool: pool}
+}
+
+// Add stores q as pending and sets q.ID. If the identifier already has a
+// pending record, that record is refreshed with q's contents instead.
+func (r *QuarantineRepo) Add(ctx context.Context, q *QuarantinedRecord) error {
+	snapshot, err := json.Marshal(q.Patient)
+	if err != nil {
+		return fmt.Errorf("marshal patient: %w", err)
+	}
+	return r.pool.QueryRow(ctx,
+		`INSERT INTO his_quarantine (hospital_id, identifier, reasons, patient, raw_body)
+		 VALUES ($1,$2,$3,$4,$5)
+		 ON CONFLICT (hospital_id, identifier) WHERE status = 'pending' DO UPDATE SET
+		   reasons = EXCLUDED.reasons, patient = EXCLUDED.patient, raw_body = EXCLUDED.raw_body,
+		   hits = his_quarantine.hits + 1, last_seen_at = now()
+		 RETURNING id`,
+		q.HospitalID, q.Identifier, q.Reasons, snapshot, q.RawBody,
+	).Scan(&q.ID)
+}
+
+const quarantineCols = `id, hospital_id, identifier, reasons, patient, raw_body, status, hits,
+patient_id, reviewed_by, reviewed_at, created_at, last_seen_at`
+
+// List returns the hospital's records, newest first; status "" means any.
+func (r *QuarantineRepo) List(ctx context.Context, hospitalID, status string, limit, offset int) ([]*QuarantinedRecord, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+quarantineCols+` FROM his_quarantine
+		 WHERE hospital_id = $1 AND ($2 = '' OR status = $2)
+		 ORDER BY created_at DESC LIMIT $3 OFFSET $4`,
+		hospitalID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*QuarantinedRecord
+	for rows.Next() {
+		q, err := scanQuarantined(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, q)
+	}
+	return out, rows.Err()
+}
+
+// GetPendingForUpdate locks and returns the hospital's pending record id.
+// Returns (nil, nil) if there is none. Call it inside a unit of work.
+func (r *QuarantineRepo) GetPendingForUpdate(ctx context.Context, hospitalID, id string) (*QuarantinedRecord, error) {
+	row := r.pool.QueryRow(ctx,
+		`SELECT `+quarantineCols+` FROM his_quarantine
+		 WHERE id = $1 AND hospital_id = $2 AND status = 'pending' FOR UPDATE`,
+		id, hospitalID)
+	q, err := scanQuarantined(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return q, err
+}
+
+// Resolve records an admin's decision on record id.
+// patientID is the stored patient (release) or "" (reject).
+func (r *QuarantineRepo) Resolve(ctx context.Context, id, status, reviewedBy, patientID string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE his_quarantine
+		 SET status = $2, reviewed_by = $3, patient_id = NULLIF($4, '')::uuid, reviewed_at = now()
+		 WHERE id = $1`,
+		id, status, reviewedBy, patientID)
+	return err
+}
+
+func scanQuarantined(row pgx.Row) (*QuarantinedRecord, error) {
+	var q QuarantinedRecord
+	var snapshot []byte
+	var raw *string
+	if err := row.Scan(
+		&q.ID, &q.HospitalID, &q.Identifier, &q.Reasons, &snapshot, &raw, &q.Status, &q.Hits,
+		&q.PatientID, &q.ReviewedBy, &q.ReviewedAt, &q.CreatedAt, &q.LastSeenAt,
+	); err != nil {
+		return nil, err
+	}
+	if raw != nil {
+		q.RawBody = *raw
+	}
+	q.Patient = &Patient{}
+	if err := json.Unmarshal(snapshot, q.Patient); err != nil {
+		return nil, fmt.Errorf("decode quarantined patient: %w", err)
+	}
+	return &q, nil
+}
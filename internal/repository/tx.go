@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// TxBeginner is the subset of pgxpool.Pool needed to open a transaction.
+// pgxmock pools satisfy it too, so units of work can be tested without a DB.
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Repos groups the repositories bound to the same DBPool.
+// The DBPool may be the shared pool or a single pgx.Tx.
+type Repos struct {
+	Patients   *PatientRepo
+	History    *PatientHistoryRepo
+	Analytics  AnalyticsRepo
+	Outbox     *OutboxRepo
+	Webhooks   *WebhookRepo
+	Quarantine *QuarantineRepo
+	Links      *PatientLinkRepo
+	Sources    *PatientFieldSourceRepo
+	Conflicts  *FieldConflictRepo
+}
+
+// NewRepos builds every repository on top of db (pool or transaction).
+func NewRepos(db DBPool) *Repos {
+	return &Repos{
+		Patients:   NewPatientRepo(db),
+		History:    NewPatientHistoryRepo(db),
+		Analytics:  NewAnalyticsRepo(db),
+		Outbox:     NewOutboxRepo(db),
+		Webhooks:   NewWebhookRepo(db),
+		Quarantine: NewQuarantineRepo(db),
+		Links:      NewPatientLinkRepo(db),
+		Sources:    NewPatientFieldSourceRepo(db),
+		Conflicts:  NewFieldConflictRepo(db),
+	}
+}
+
+// UnitOfWork runs several repository calls atomically.
+type UnitOfWork interface {
+	// Do calls fn with repositories bound to one transaction.
+	// The transaction is committed if fn returns nil and rolled back otherwise.
+	Do(ctx context.Context, fn func(r *Repos) error) error
+}
+
+// pgUnitOfWork is the Postgres-backed UnitOfWork.
+type pgUnitOfWork struct {
+	db TxBeginner
+}
+
+func NewUnitOfWork(db TxBeginner) UnitOfWork {
+	return &pgUnitOfWork{db: db}
+}
+
+func (u *pgUnitOfWork) Do(ctx context.Context, fn func(r *Repos) error) error {
+	tx, err := u.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	// releases the connection (and any xact advisory lock) if fn panics;
+	// a no-op once the transaction is committed or rolled back below
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(NewRepos(tx)); err != nil {
+		if rbErr := tx.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("%w (rollback: %v)", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
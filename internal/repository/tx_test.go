@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnitOfWork_Commit(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p1", "HIS-1", SourceStaff, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO search_events`).
+		WithArgs("staff-1", "HIS-1", pgxmock.AnyArg(), 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectCommit()
+
+	uow := NewUnitOfWork(mock)
+	err = uow.Do(context.Background(), func(r *Repos) error {
+		if err := r.History.Record(context.Background(), &Patient{ID: "p1", HospitalID: "HIS-1"}, SourceStaff); err != nil {
+			return err
+		}
+		return r.Analytics.LogSearch(context.Background(), "staff-1", "HIS-1", PatientFilters{NationalID: "N-1"}, 1)
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitOfWork_RollbackOnError(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	boom := errors.New("boom")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p1", "", SourceHIS, pgxmock.AnyArg()).
+		WillReturnError(boom)
+	mock.ExpectRollback()
+
+	uow := NewUnitOfWork(mock)
+	err = uow.Do(context.Background(), func(r *Repos) error {
+		return r.History.Record(context.Background(), &Patient{ID: "p1"}, SourceHIS)
+	})
+	assert.ErrorIs(t, err, boom)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUnitOfWork_RollbackOnPanic(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	uow := NewUnitOfWork(mock)
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = uow.Do(context.Background(), func(r *Repos) error { panic("boom") })
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookSubscription is a hospital-owned endpoint receiving patient events.
+type WebhookSubscription struct {
+	ID         string    `json:"id"`
+	HospitalID string    `json:"hospital_id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"event_types"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one attempt series of an event to a subscription.
+type WebhookDelivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	HospitalID     string     `json:"hospital_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        []byte     `json:"-"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int       `json:"last_status_code,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// WebhookRepo persists webhook subscriptions and deliveries.
+type WebhookRepo struct {
+	pool DBPool
+}
+
+func NewWebhookRepo(pool DBPool) *WebhookRepo {
+	return &WebhookRepo{pool: pool}
+}
+
+// CreateSubscription inserts s and fills its ID and CreatedAt.
+func (r *WebhookRepo) CreateSubscription(ctx context.Context, s *WebhookSubscription) error {
+	return r.pool.QueryRow(ctx,
+		`INSERT INTO webhook_subscriptions (hospital_id, url, secret, event_types, active)
+		 VALUES ($1,$2,$3,$4,$5) RETURNING id, created_at`,
+		s.HospitalID, s.URL, s.Secret, s.EventTypes, s.Active,
+	).Scan(&s.ID, &s.CreatedAt)
+}
+
+// ListSubscriptions returns a hospital's subscriptions, newest first.
+func (r *WebhookRepo) ListSubscriptions(ctx context.Context, hospitalID string) ([]*WebhookSubscription, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id, hospital_id, url, secret, event_types, active, created_at
+FROM webhook_subscriptions WHERE hospital_id = $1 ORDER BY created_at DESC`, hospitalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSubscriptions(rows)
+}
+
+// ActiveSubscriptionsFor returns active subscriptions of a hospital that want eventType.
+func (r *WebhookRepo) ActiveSubscriptionsFor(ctx context.Context, hospitalID, eventType string) ([]*WebhookSubscription, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id, hospital_id, url, secret, event_types, active, created_at
+FROM webhook_subscriptions
+WHERE hospital_id = $1 AND active AND $2 = ANY(event_types)`, hospitalID, eventType)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanSubscriptions(rows)
+}
+
+// DeleteSubscription removes a subscription owned by hospitalID.
+// Returns false if no such subscription exists for that hospital.
+func (r *WebhookRepo) DeleteSubscription(ctx context.Context, hospitalID, id string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`DELETE FROM webhook_subscriptions WHERE id = $1 AND hospital_id = $2`, id, hospitalID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// EnqueueDelivery creates a pending delivery; a repeated (subscription, event) pair is ignored.
+func (r *WebhookRepo) EnqueueDelivery(ctx context.Context, d *WebhookDelivery) error {
+	_, err := r.pool.Exec(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, hospital_id, event_id, event_type, payload)
+		 VALUES ($1,$2,$3,$4,$5) ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		d.SubscriptionID, d.HospitalID, d.EventID, d.EventType, d.Payload,
+	)
+	return err
+}
+
+// DueDelivery is a pending delivery joined with its subscription endpoint.
+type DueDelivery struct {
+	Delivery WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// FetchDueDeliveries locks up to limit pending deliveries of active
+// subscriptions whose retry time has come.
+// Must run inside a transaction; concurrent workers skip locked rows.
+func (r *WebhookRepo) FetchDueDeliveries(ctx context.Context, limit int) ([]*DueDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT d.id, d.subscription_id, d.hospital_id, d.event_id, d.event_type, d.payload,
+       d.attempts, s.url, s.secret
+FROM webhook_deliveries d
+JOIN webhook_subscriptions s ON s.id = d.subscription_id
+WHERE d.status = 'pending' AND d.next_attempt_at <= now() AND s.active
+ORDER BY d.created_at
+LIMIT $1
+FOR UPDATE OF d SKIP LOCKED`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*DueDelivery
+	for rows.Next() {
+		var dd DueDelivery
+		d := &dd.Delivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.HospitalID, &d.EventID, &d.EventType, &d.Payload,
+			&d.Attempts, &dd.URL, &dd.Secret); err != nil {
+			return nil, err
+		}
+		d.Status = DeliveryPending
+		out = append(out, &dd)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MarkDelivered records a successful attempt.
+func (r *WebhookRepo) MarkDelivered(ctx context.Context, id string, statusCode int) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE webhook_deliveries SET status = 'delivered', attempts = attempts + 1,
+		 last_status_code = $2, last_error = NULL, delivered_at = now() WHERE id = $1`,
+		id, statusCode,
+	)
+	return err
+}
+
+// MarkFailed records a failed attempt. status is DeliveryPending (retry at
+// nextAttempt) or DeliveryDead (no more retries). statusCode is 0 if no response was received.
+func (r *WebhookRepo) MarkFailed(ctx context.Context, id, status string, statusCode int, lastErr string, nextAttempt time.Time) error {
+	var code any
+	if statusCode != 0 {
+		code = statusCode
+	}
+	_, err := r.pool.Exec(ctx,
+		`UPDATE webhook_deliveries SET status = $2, attempts = attempts + 1,
+		 last_status_code = $3, last_error = $4, next_attempt_at = $5 WHERE id = $1`,
+		id, status, code, lastErr, nextAttempt,
+	)
+	return err
+}
+
+// ListDeliveries returns a hospital's deliveries, optionally filtered by status, newest first.
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, hospitalID, status string, limit, offset int) ([]*WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx, `
+SELECT id, subscription_id, hospital_id, event_id, event_type, payload, status, attempts,
+       next_attempt_at, last_status_code, last_error, created_at, delivered_at
+FROM webhook_deliveries
+WHERE hospital_id = $1 AND ($2 = '' OR status = $2)
+ORDER BY created_at DESC
+LIMIT $3 OFFSET $4`, hospitalID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []*WebhookDelivery
+	for rows.Next() {
+		var d WebhookDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.HospitalID, &d.EventID, &d.EventType, &d.Payload,
+			&d.Status, &d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Redeliver resets a hospital's delivery to pending with a fresh attempt budget.
+// Returns (nil, nil) if the delivery does not exist for that hospital.
+func (r *WebhookRepo) Redeliver(ctx context.Context, hospitalID, id string) (*WebhookDelivery, error) {
+	var d WebhookDelivery
+	err := r.pool.QueryRow(ctx, `
+UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = now(), delivered_at = NULL
+WHERE id = $1 AND hospital_id = $2
+RETURNING id, subscription_id, hospital_id, event_id, event_type, status, attempts, next_attempt_at, created_at`,
+		id, hospitalID,
+	).Scan(&d.ID, &d.SubscriptionID, &d.HospitalID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+func scanSubscriptions(rows pgx.Rows) ([]*WebhookSubscription, error) {
+	var out []*WebhookSubscription
+	for rows.Next() {
+		var s WebhookSubscription
+		if err := rows.Scan(&s.ID, &s.HospitalID, &s.URL, &s.Secret, &s.EventTypes, &s.Active, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, &s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// policyResolver is a fakeResolver with a freshness policy.
+type policyResolver struct {
+	fakeResolver
+	cfg adapter.FreshnessConfig
+}
+
+func (p policyResolver) FreshnessConfig(string) adapter.FreshnessConfig { return p.cfg }
+
+// resolveWithSync runs Resolve against a stored HIS-1 patient last synced age ago.
+func resolveWithSync(t *testing.T, his adapter.HospitalClient, resolver adapter.ClientResolver, age time.Duration) (*ResolvedPatient, error) {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	t.Cleanup(mock.Close)
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	synced := now.Add(-age)
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols).AddRow(
+			"p1", "HN-1", "N-1", nil,
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil, "HIS-1", &synced,
+		))
+
+	if resolver == nil {
+		resolver = fakeResolver{"HIS-1": his}
+	}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, resolver).(*patientServiceImpl)
+	svc.now = func() time.Time { return now }
+
+	p, err := svc.Resolve(context.Background(), "HIS-1", "N-1")
+	svc.background.Wait()
+	return p, err
+}
+
+func TestResolve_FreshWithinSoftTTL(t *testing.T) {
+	his := &fakeHospital{}
+	p, err := resolveWithSync(t, his, nil, 10*time.Minute)
+
+	assert.NoError(t, err)
+	assert.Equal(t, FreshnessFresh, p.Freshness)
+	assert.Equal(t, time.Date(2025, 6, 1, 11, 50, 0, 0, time.UTC), p.FetchedAt.UTC())
+	assert.Equal(t, 0, his.calls)
+}
+
+func TestResolve_StaleServedAndRefreshedInBackground(t *testing.T) {
+	his := &fakeHospital{} // the refresh finds nothing, so nothing is written
+	p, err := resolveWithSync(t, his, nil, 2*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", p.ID)
+	assert.Equal(t, FreshnessStale, p.Freshness)
+	assert.Equal(t, 1, his.calls, "background refresh asked the HIS")
+}
+
+func TestResolve_PastHardTTLFallsBackWhenHISDown(t *testing.T) {
+	his := &fakeHospital{err: adapter.ErrHISUnavailable}
+	p, err := resolveWithSync(t, his, nil, 48*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", p.ID)
+	assert.Equal(t, FreshnessExpired, p.Freshness)
+	assert.Equal(t, 1, his.calls)
+}
+
+func TestResolve_DisabledPolicyNeverRefreshes(t *testing.T) {
+	his := &fakeHospital{}
+	resolver := policyResolver{fakeResolver: fakeResolver{"HIS-1": his}, cfg: adapter.FreshnessConfig{
+		SoftTTL: adapter.Duration(time.Minute), HardTTL: adapter.Duration(time.Hour), Disabled: true,
+	}}
+	p, err := resolveWithSync(t, his, resolver, 48*time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, FreshnessStale, p.Freshness)
+	assert.Equal(t, 0, his.calls)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ErrQuarantined is returned by Get when the HIS answered with a record that
+// failed validation; it was quarantined for review instead of being stored.
+var ErrQuarantined = errors.New("HIS record failed validation and was quarantined")
+
+// validateHISPatient checks a record returned by a HIS lookup for identifier
+// and returns why it must not be stored (nil if it may).
+func validateHISPatient(p *repository.Patient, identifier string, now time.Time) []string {
+	var reasons []string
+
+	if p.NationalID != identifier && p.PassportID != identifier {
+		reasons = append(reasons, "identifier mismatch: neither national_id nor passport_id is the requested identifier")
+	}
+	if adapter.IsThaiCitizenID(p.NationalID) && !validThaiCitizenID(p.NationalID) {
+		reasons = append(reasons, "national_id: checksum digit is wrong")
+	}
+
+	hasTH := strings.TrimSpace(p.FirstNameTH) != "" && strings.TrimSpace(p.LastNameTH) != ""
+	hasEN := strings.TrimSpace(p.FirstNameEN) != "" && strings.TrimSpace(p.LastNameEN) != ""
+	if !hasTH && !hasEN {
+		reasons = append(reasons, "name: no first and last name in Thai or English")
+	}
+
+	if p.DateOfBirth != nil {
+		dob, err := time.Parse("2006-01-02", *p.DateOfBirth)
+		switch {
+		case err != nil:
+			reasons = append(reasons, fmt.Sprintf("date_of_birth: %q is not a valid yyyy-mm-dd date", *p.DateOfBirth))
+		case dob.After(now):
+			reasons = append(reasons, "date_of_birth: in the future")
+		case dob.Year() < 1900:
+			reasons = append(reasons, "date_of_birth: before 1900")
+		}
+	}
+
+	switch p.Gender {
+	case "", "M", "F":
+	default:
+		reasons = append(reasons, fmt.Sprintf("gender: %q is not M or F", p.Gender))
+	}
+	if p.Email != "" && !strings.Contains(p.Email, "@") {
+		reasons = append(reasons, "email: not an e-mail address")
+	}
+	return reasons
+}
+
+// validThaiCitizenID checks the mod-11 check digit of a 13-digit citizen id.
+func validThaiCitizenID(id string) bool {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		sum += int(id[i]-'0') * (13 - i)
+	}
+	return (11-sum%11)%10 == int(id[12]-'0')
+}
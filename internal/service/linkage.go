@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/haniscreator/agnos-search/internal/linkage"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ErrPatientNotFound is returned for a patient id unknown in the caller's hospital.
+var ErrPatientNotFound = errors.New("patient not found")
+
+// linkageCandidateLimit bounds how many stored patients one record is
+// compared with; blocking keys are selective enough that more means a
+// common surname prefix or DOB, not more duplicates.
+const linkageCandidateLimit = 200
+
+// Linker finds stored patients that are probably the same person as a
+// record, within one hospital, using a linkage.Engine.
+type Linker struct {
+	repo   *repository.PatientRepo
+	links  *repository.PatientLinkRepo
+	engine *linkage.Engine
+}
+
+// NewLinker constructs a Linker; repo and links serve reads.
+func NewLinker(repo *repository.PatientRepo, links *repository.PatientLinkRepo, engine *linkage.Engine) *Linker {
+	return &Linker{repo: repo, links: links, engine: engine}
+}
+
+// Candidates returns the hospital's patients that match or possibly match
+// p, best first. p itself (by id) is never a candidate.
+func (l *Linker) Candidates(ctx context.Context, hospitalID string, p *repository.Patient) ([]linkage.Candidate, error) {
+	return l.candidates(ctx, l.repo, hospitalID, p)
+}
+
+// MergeCandidates returns the candidates for the hospital's patient id.
+func (l *Linker) MergeCandidates(ctx context.Context, hospitalID, patientID string) (*repository.Patient, []linkage.Candidate, error) {
+	p, err := l.repo.GetByID(ctx, patientID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get patient: %w", err)
+	}
+	if p == nil || p.HospitalID != hospitalID {
+		return nil, nil, ErrPatientNotFound
+	}
+	cands, err := l.Candidates(ctx, hospitalID, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	return p, cands, nil
+}
+
+// Flagged returns the likely duplicates recorded during ingestion.
+func (l *Linker) Flagged(ctx context.Context, hospitalID string, limit, offset int) ([]repository.PatientLink, error) {
+	return l.links.List(ctx, hospitalID, limit, offset)
+}
+
+// flag records the candidates of stored, written by source, inside the
+// caller's unit of work. Ingestion does not merge: admins review the flags.
+func (l *Linker) flag(ctx context.Context, r *repository.Repos, stored *repository.Patient, source string) error {
+	cands, err := l.candidates(ctx, r.Patients, stored.HospitalID, stored)
+	if err != nil {
+		return err
+	}
+	for _, c := range cands {
+		err := r.Links.Record(ctx, repository.PatientLink{
+			PatientID:   stored.ID,
+			CandidateID: c.ID,
+			HospitalID:  stored.HospitalID,
+			Score:       c.Score,
+			Decision:    c.Decision,
+			Source:      source,
+		})
+		if err != nil {
+			return fmt.Errorf("record link: %w", err)
+		}
+	}
+	if len(cands) > 0 {
+		log.Printf("record linkage: %d likely duplicate(s) of patient %s (hospital=%s, source=%s, best=%s %.2f)",
+			len(cands), stored.ID, stored.HospitalID, source, cands[0].Decision, cands[0].Score)
+	}
+	return nil
+}
+
+func (l *Linker) candidates(ctx context.Context, repo *repository.PatientRepo, hospitalID string, p *repository.Patient) ([]linkage.Candidate, error) {
+	stored, err := repo.LinkageCandidates(ctx, hospitalID, p.ID, linkage.BlockingKeys(p), linkageCandidateLimit)
+	if err != nil {
+		return nil, fmt.Errorf("linkage candidates: %w", err)
+	}
+	return l.engine.Rank(p, stored), nil
+}
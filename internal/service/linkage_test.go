@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/haniscreator/agnos-search/internal/linkage"
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+func newTestLinker(t *testing.T, mock pgxmock.PgxPoolIface) *Linker {
+	engine, err := linkage.New(linkage.DefaultConfig())
+	require.NoError(t, err)
+	return NewLinker(repository.NewPatientRepo(mock), repository.NewPatientLinkRepo(mock), engine)
+}
+
+func TestIngestWriter_FlagsLikelyDuplicates(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	// an HL7 feed sends a typo'd passport for a patient already stored
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("AB1234576").
+		WillReturnRows(pgxmock.NewRows(patientCols))
+	mock.ExpectExec(`INSERT INTO patients`).
+		WithArgs(anyArgs(16)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("AB1234576").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(
+			"p-new", "", "", "AB1234576",
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			dob("1985-05-12"), "", "", "M", nil, "HIS-1",
+		))
+	mock.ExpectExec(`INSERT INTO patient_field_sources`).
+		WithArgs(anyArgs(4)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p-new", "HIS-1", repository.SourceHL7, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientCreated, "p-new", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM patients\s+WHERE hospital_id = \$1 AND id::text <> \$2`).
+		WithArgs(anyArgs(10)...).
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(
+			"p-old", "HN-1", "", "AB1234567",
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			dob("1985-05-12"), "0812345678", "", "M", nil, "HIS-1",
+		))
+	mock.ExpectExec(`INSERT INTO patient_links`).
+		WithArgs("p-new", "p-old", "HIS-1", pgxmock.AnyArg(), linkage.DecisionMatch, repository.SourceHL7).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	w := NewIngestWriter(&fakeUnitOfWork{db: mock}, nil, repository.SourceHL7, newTestLinker(t, mock), nil)
+	p := &repository.Patient{ID: "p-new", PassportID: "AB1234576", FirstNameEN: "Somchai", LastNameEN: "Jaidee", HospitalID: "HIS-1"}
+	assert.NoError(t, w.Upsert(context.Background(), p))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLinker_MergeCandidates_OtherHospitalIsNotFound(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE id = \$1`).
+		WithArgs("p-1").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(
+			"p-1", "HN-1", "N-1", nil,
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil, "HIS-2",
+		))
+
+	_, _, err = newTestLinker(t, mock).MergeCandidates(context.Background(), "HIS-1", "p-1")
+	assert.ErrorIs(t, err, ErrPatientNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func dob(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
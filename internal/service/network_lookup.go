@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// HospitalDirectory lists the hospitals with a HIS and resolves their clients
+// (adapter.Registry).
+type HospitalDirectory interface {
+	adapter.ClientResolver
+	Hospitals() []string
+}
+
+// Per-hospital statuses of a network lookup.
+const (
+	NetworkFound       = "found"
+	NetworkNotFound    = "not_found"
+	NetworkAmbiguous   = "ambiguous"
+	NetworkTimeout     = "timeout"     // no answer before the shared deadline
+	NetworkUnavailable = "unavailable" // the HIS failed or its circuit breaker is open
+)
+
+// HospitalLookup is one hospital's answer to a network lookup.
+type HospitalLookup struct {
+	HospitalID string              `json:"hospital_id"`
+	Status     string              `json:"status"`
+	Patient    *repository.Patient `json:"patient,omitempty"`
+	ElapsedMS  int64               `json:"elapsed_ms"`
+}
+
+// NetworkResult is the outcome of NetworkLookup.Lookup, one entry per
+// configured hospital in Hospitals() order. Complete is false when at least
+// one hospital did not answer in time.
+type NetworkResult struct {
+	Hospitals []HospitalLookup
+	Found     int
+	Complete  bool
+}
+
+// AuditFilters describes the lookup of identifier in audit filters, classified
+// like the single-hospital identifier lookups from the first patient found.
+func (r *NetworkResult) AuditFilters(identifier string) repository.PatientFilters {
+	for _, h := range r.Hospitals {
+		if h.Patient != nil {
+			return identifierFilters(h.Patient, identifier)
+		}
+	}
+	return repository.PatientFilters{NationalID: identifier}
+}
+
+// NetworkLookup finds a patient in every hospital of the network, e.g. for
+// transfers and emergencies. Results are returned, never stored: the patient
+// belongs to the hospital that answered, not to the caller's.
+type NetworkLookup struct {
+	dir     HospitalDirectory
+	timeout time.Duration
+}
+
+// DefaultNetworkTimeout is the shared deadline of a network lookup.
+const DefaultNetworkTimeout = 3 * time.Second
+
+// NewNetworkLookup constructs a NetworkLookup; every hospital must answer
+// within timeout (DefaultNetworkTimeout if <= 0).
+func NewNetworkLookup(dir HospitalDirectory, timeout time.Duration) *NetworkLookup {
+	if timeout <= 0 {
+		timeout = DefaultNetworkTimeout
+	}
+	return &NetworkLookup{dir: dir, timeout: timeout}
+}
+
+// Lookup queries every hospital concurrently and returns what arrived before
+// the deadline; hospitals still running are reported as NetworkTimeout.
+func (n *NetworkLookup) Lookup(ctx context.Context, identifier string) *NetworkResult {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	hospitals := n.dir.Hospitals()
+	type answer struct {
+		i int
+		HospitalLookup
+	}
+	// buffered so late workers never block after we stop waiting
+	answers := make(chan answer, len(hospitals))
+	start := time.Now()
+	for i, hid := range hospitals {
+		go func() {
+			st, p := n.lookupOne(ctx, hid, identifier)
+			answers <- answer{i, HospitalLookup{HospitalID: hid, Status: st, Patient: p, ElapsedMS: time.Since(start).Milliseconds()}}
+		}()
+	}
+
+	res := &NetworkResult{Hospitals: make([]HospitalLookup, len(hospitals)), Complete: true}
+	done := make([]bool, len(hospitals))
+wait:
+	for range hospitals {
+		select {
+		case a := <-answers:
+			res.Hospitals[a.i], done[a.i] = a.HospitalLookup, true
+		case <-ctx.Done():
+			break wait
+		}
+	}
+	for i, hid := range hospitals {
+		if !done[i] {
+			res.Hospitals[i] = HospitalLookup{HospitalID: hid, Status: NetworkTimeout, ElapsedMS: time.Since(start).Milliseconds()}
+		}
+		switch res.Hospitals[i].Status {
+		case NetworkFound:
+			res.Found++
+		case NetworkTimeout:
+			res.Complete = false
+		}
+	}
+	return res
+}
+
+func (n *NetworkLookup) lookupOne(ctx context.Context, hid, identifier string) (string, *repository.Patient) {
+	client, err := n.dir.ClientFor(hid)
+	if err != nil {
+		log.Printf("network lookup resolve (hospital=%s): %v", hid, err)
+		return NetworkUnavailable, nil
+	}
+	p, err := client.LookupByIdentifier(ctx, identifier)
+	switch {
+	case err == nil && p == nil:
+		return NetworkNotFound, nil
+	case err == nil:
+		p.HospitalID = hid
+		return NetworkFound, p
+	case errors.Is(err, ErrAmbiguousMatch):
+		return NetworkAmbiguous, nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return NetworkTimeout, nil
+	}
+	log.Printf("network lookup (hospital=%s): %v", hid, err)
+	return NetworkUnavailable, nil
+}
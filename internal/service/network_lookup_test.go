@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// fakeDirectory implements HospitalDirectory over fakeResolver.
+type fakeDirectory struct {
+	fakeResolver
+	order []string
+}
+
+func (d fakeDirectory) Hospitals() []string { return d.order }
+
+func TestNetworkLookup_ReportsPerHospitalStatus(t *testing.T) {
+	slow := &blockingHospital{release: make(chan struct{})}
+	defer close(slow.release)
+
+	dir := fakeDirectory{
+		fakeResolver: fakeResolver{
+			"HIS-1": &fakeHospital{out: &repository.Patient{NationalID: "N-1", FirstNameEN: "Somchai"}},
+			"HIS-2": &fakeHospital{},
+			"HIS-3": &fakeHospital{err: errors.New("connection refused")},
+			"HIS-4": slow,
+			"HIS-5": &fakeHospital{err: adapter.ErrAmbiguousMatch},
+		},
+		order: []string{"HIS-1", "HIS-2", "HIS-3", "HIS-4", "HIS-5"},
+	}
+
+	start := time.Now()
+	res := NewNetworkLookup(dir, 50*time.Millisecond).Lookup(context.Background(), "N-1")
+	assert.Less(t, time.Since(start), time.Second)
+
+	assert.False(t, res.Complete)
+	assert.Equal(t, 1, res.Found)
+	statuses := make([]string, len(res.Hospitals))
+	for i, h := range res.Hospitals {
+		statuses[i] = h.Status
+	}
+	assert.Equal(t, []string{NetworkFound, NetworkNotFound, NetworkUnavailable, NetworkTimeout, NetworkAmbiguous}, statuses)
+	if assert.NotNil(t, res.Hospitals[0].Patient) {
+		assert.Equal(t, "HIS-1", res.Hospitals[0].Patient.HospitalID)
+	}
+}
@@ -2,43 +2,298 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/outbox"
 	"github.com/haniscreator/agnos-search/internal/repository"
 )
 
 // PatientService defines high-level behavior used by HTTP handlers.
 type PatientService interface {
-	Get(ctx context.Context, identifier string) (*repository.Patient, error)
+	// Resolve finds the hospital's patient by national_id or passport_id: the
+	// local DB first, then the hospital's HIS, whose answer is stored under
+	// hospitalID. Stored HIS patients are refreshed per the hospital's
+	// freshness policy. It returns (nil, nil) when neither knows the patient.
+	Resolve(ctx context.Context, hospitalID, identifier string) (*ResolvedPatient, error)
 	// Search with hospital constraint
 	Search(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) ([]*repository.Patient, int, error)
+	// SearchFederated is the opt-in federated mode of Search: when the first
+	// page of local results is not full, the hospital's HIS is asked too.
+	SearchFederated(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) (*FederatedResult, error)
+	// Provenance returns who last wrote each of the patient's fields.
+	Provenance(ctx context.Context, patientID string) (map[string]repository.FieldProvenance, error)
 }
 
+// Sources of a federated search hit.
+const (
+	HitSourceLocal = "local" // patients table
+	HitSourceHIS   = "his"   // the hospital's HIS; not stored until fetched by id
+)
+
+// HIS statuses of a federated search.
+const (
+	HISStatusOK            = "ok"
+	HISStatusNotQueried    = "not_queried" // enough local results, a later page, or filters too broad to send
+	HISStatusNotConfigured = "not_configured"
+	HISStatusUnavailable   = "unavailable"
+	HISStatusAmbiguous     = "ambiguous" // the HIS knows several patients with the identifier
+)
+
+// SearchHit is a search result marked with where it came from.
+type SearchHit struct {
+	*repository.Patient
+	Source string `json:"source"`
+}
+
+// FederatedResult is the outcome of SearchFederated. Local rows come first;
+// Total counts local matches plus the HIS patients not already stored.
+type FederatedResult struct {
+	Results   []SearchHit
+	Total     int
+	HISStatus string
+}
+
+// Freshness of a resolved patient.
+const (
+	FreshnessFresh   = "fresh"   // fetched from the HIS within the soft TTL (or just now)
+	FreshnessStale   = "stale"   // past the soft TTL; refreshed in the background unless the policy is disabled
+	FreshnessExpired = "expired" // past the hard TTL and the refresh failed; stored copy served
+	FreshnessLocal   = "local"   // never fetched from a HIS (created locally, HL7, ...)
+)
+
+// ResolvedPatient is a patient returned by Resolve with how current it is.
+type ResolvedPatient struct {
+	*repository.Patient
+	Freshness string     `json:"freshness"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"` // last fetched from the HIS
+	// Provenance maps field names to who last wrote them; only set on request.
+	Provenance map[string]repository.FieldProvenance `json:"provenance,omitempty"`
+}
+
+// FreshnessPolicy gives a hospital's freshness settings. A HIS resolver that
+// implements it (like adapter.Registry) sets the policy; otherwise
+// adapter.DefaultFreshnessConfig applies.
+type FreshnessPolicy interface {
+	FreshnessConfig(hospitalID string) adapter.FreshnessConfig
+}
+
+// ErrNoHIS is returned by Resolve when the caller's hospital has no HIS configured
+// and the patient is not in the local DB.
+var ErrNoHIS = adapter.ErrNoHIS
+
+// ErrHISUnavailable is returned by Resolve when the patient is not in the local DB
+// and the caller's HIS failed or its circuit breaker is open.
+var ErrHISUnavailable = adapter.ErrHISUnavailable
+
+// ErrAmbiguousMatch is returned by Resolve when the HIS knows several patients
+// with the identifier; nothing is stored.
+var ErrAmbiguousMatch = adapter.ErrAmbiguousMatch
+
 // patientServiceImpl implements PatientService
 type patientServiceImpl struct {
-	repo    *repository.PatientRepo
-	adapter adapter.HospitalClient
+	repo *repository.PatientRepo
+	uow  repository.UnitOfWork
+	his  adapter.ClientResolver
+
+	inflight      singleflight.Group
+	lookupTimeout time.Duration
+
+	now        func() time.Time
+	background sync.WaitGroup // background refreshes, for tests
+
+	linker  *Linker                            // flags likely duplicates of fetched patients; may be nil
+	sources *repository.PatientFieldSourceRepo // serves Provenance; may be nil
 }
 
-func NewPatientService(repo *repository.PatientRepo, adapter adapter.HospitalClient) PatientService {
-	return &patientServiceImpl{repo: repo, adapter: adapter}
+// PatientServiceOption configures NewPatientService.
+type PatientServiceOption func(*patientServiceImpl)
+
+// WithLinker records likely duplicates (see Linker) of every patient stored
+// from a HIS lookup, in the same transaction.
+func WithLinker(l *Linker) PatientServiceOption {
+	return func(s *patientServiceImpl) { s.linker = l }
+}
+
+// WithFieldSources serves Provenance from sources.
+func WithFieldSources(sources *repository.PatientFieldSourceRepo) PatientServiceOption {
+	return func(s *patientServiceImpl) { s.sources = sources }
+}
+
+// defaultLookupTimeout bounds a shared HIS lookup + upsert once it no longer
+// follows any single caller's context.
+const defaultLookupTimeout = 10 * time.Second
+
+// NewPatientService constructs a PatientService.
+// repo serves reads; uow is used for every write so the patient row, its
+// version history, its outbox event and the audit event are committed together.
+// his picks the HospitalClient of the caller's hospital.
+func NewPatientService(repo *repository.PatientRepo, uow repository.UnitOfWork, his adapter.ClientResolver, opts ...PatientServiceOption) PatientService {
+	s := &patientServiceImpl{repo: repo, uow: uow, his: his, lookupTimeout: defaultLookupTimeout, now: time.Now}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-func (s *patientServiceImpl) Get(ctx context.Context, identifier string) (*repository.Patient, error) {
-	// 1) Try DB
-	p, err := s.repo.GetByIdentifier(ctx, identifier)
+func (s *patientServiceImpl) Resolve(ctx context.Context, hid, identifier string) (*ResolvedPatient, error) {
+	staffID, _ := ctx.Value("staff_id").(string)
+
+	// 1) Try DB. Identifiers are unique across hospitals: a patient stored for
+	// another hospital is neither shown to this one nor overwritten with its HIS data.
+	p, syncedAt, err := s.repo.GetByIdentifierWithSync(ctx, identifier)
 	if err != nil {
 		return nil, fmt.Errorf("repo get: %w", err)
 	}
 	if p != nil {
-		return p, nil
+		if p.HospitalID != "" && p.HospitalID != hid {
+			return nil, nil
+		}
+		return s.serveStored(ctx, hid, staffID, identifier, p, syncedAt)
+	}
+
+	// 2) Query the caller's hospital adapter.
+	stored, err := s.fetchShared(ctx, hid, staffID, identifier)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+	now := s.now()
+	return &ResolvedPatient{Patient: stored, Freshness: FreshnessFresh, FetchedAt: &now}, nil
+}
+
+// serveStored answers with a stored patient per the hospital's freshness policy:
+// as is within the soft TTL, with a background refresh past it, and after a
+// synchronous refresh past the hard TTL (falling back to the stored copy if
+// the HIS cannot give a usable answer).
+func (s *patientServiceImpl) serveStored(ctx context.Context, hid, staffID, identifier string, p *repository.Patient, syncedAt *time.Time) (*ResolvedPatient, error) {
+	if syncedAt == nil {
+		if err := s.logLookup(ctx, staffID, p, identifier); err != nil {
+			return nil, err
+		}
+		return &ResolvedPatient{Patient: p, Freshness: FreshnessLocal}, nil
+	}
+
+	cfg := adapter.DefaultFreshnessConfig
+	if fp, ok := s.his.(FreshnessPolicy); ok {
+		cfg = fp.FreshnessConfig(hid)
+	}
+	age := s.now().Sub(*syncedAt)
+
+	if age <= time.Duration(cfg.SoftTTL) || cfg.Disabled || age <= time.Duration(cfg.HardTTL) {
+		if err := s.logLookup(ctx, staffID, p, identifier); err != nil {
+			return nil, err
+		}
+		if age <= time.Duration(cfg.SoftTTL) {
+			return &ResolvedPatient{Patient: p, Freshness: FreshnessFresh, FetchedAt: syncedAt}, nil
+		}
+		if !cfg.Disabled {
+			s.refreshInBackground(ctx, hid, identifier)
+		}
+		return &ResolvedPatient{Patient: p, Freshness: FreshnessStale, FetchedAt: syncedAt}, nil
+	}
+
+	// a cached HIS answer may be as old as the stored copy
+	stored, err := s.fetchShared(adapter.BypassCache(ctx), hid, staffID, identifier)
+	if err == nil && stored != nil {
+		now := s.now()
+		return &ResolvedPatient{Patient: stored, Freshness: FreshnessFresh, FetchedAt: &now}, nil
+	}
+	if err != nil && !errors.Is(err, ErrHISUnavailable) && !errors.Is(err, ErrQuarantined) &&
+		!errors.Is(err, ErrAmbiguousMatch) && !errors.Is(err, ErrNoHIS) {
+		return nil, err
+	}
+	// the HIS is down or gone, no longer knows the patient, or sent something unusable
+	log.Printf("patient resolve: serving expired copy (hospital=%s, identifier=%s, synced_at=%s): %v",
+		hid, identifier, syncedAt.Format(time.RFC3339), err)
+	if err := s.logLookup(ctx, staffID, p, identifier); err != nil {
+		return nil, err
+	}
+	return &ResolvedPatient{Patient: p, Freshness: FreshnessExpired, FetchedAt: syncedAt}, nil
+}
+
+// refreshInBackground re-fetches a stale patient without holding up the caller.
+// It shares the in-flight lookup of the same patient, if any, and is not audited.
+func (s *patientServiceImpl) refreshInBackground(ctx context.Context, hid, identifier string) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		// fetchShared bounds the work by lookupTimeout; the cached HIS
+		// answer is skipped, it may be as old as the stored copy
+		if _, err := s.fetchShared(adapter.BypassCache(context.WithoutCancel(ctx)), hid, "", identifier); err != nil {
+			log.Printf("patient background refresh failed (hospital=%s, identifier=%s): %v", hid, identifier, err)
+		}
+	}()
+}
+
+// fetchShared fetches identifier from hid's HIS and stores it. Concurrent
+// callers for the same hospital + identifier share one HIS call and one upsert.
+func (s *patientServiceImpl) fetchShared(ctx context.Context, hid, staffID, identifier string) (*repository.Patient, error) {
+	led := false
+	ch := s.inflight.DoChan(hid+"|"+identifier, func() (any, error) {
+		led = true
+		// detach from the leader's cancellation: followers still need the result.
+		// Values (staff_id) are kept; the work is bounded by lookupTimeout.
+		wctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.lookupTimeout)
+		defer cancel()
+		return s.fetchAndStore(wctx, hid, staffID, identifier)
+	})
+
+	var res singleflight.Result
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res = <-ch:
+	}
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	stored, _ := res.Val.(*repository.Patient)
+	if stored == nil {
+		return nil, nil
+	}
+
+	// the leader's audit event was committed with the upsert; followers log their own
+	if !led {
+		if err := s.logLookup(ctx, staffID, stored, identifier); err != nil {
+			return nil, err
+		}
 	}
 
-	// 2) Query hospital adapter
-	p, err = s.adapter.LookupByIdentifier(ctx, identifier)
+	// every caller gets its own copy of the shared result
+	out := *stored
+	return &out, nil
+}
+
+// logLookup records a staff member's identifier lookup in the search audit.
+func (s *patientServiceImpl) logLookup(ctx context.Context, staffID string, p *repository.Patient, identifier string) error {
+	if staffID == "" {
+		return nil
+	}
+	err := s.uow.Do(ctx, func(r *repository.Repos) error {
+		return r.Analytics.LogSearch(ctx, staffID, p.HospitalID, identifierFilters(p, identifier), 1)
+	})
+	if err != nil {
+		return fmt.Errorf("log search: %w", err)
+	}
+	return nil
+}
+
+// fetchAndStore looks identifier up in hid's HIS and persists the result.
+// It returns (nil, nil) when the HIS does not know the patient.
+func (s *patientServiceImpl) fetchAndStore(ctx context.Context, hid, staffID, identifier string) (*repository.Patient, error) {
+	client, err := s.his.ClientFor(hid)
+	if err != nil {
+		return nil, fmt.Errorf("adapter resolve: %w", err)
+	}
+	p, err := client.LookupByIdentifier(ctx, identifier)
 	if err != nil {
 		return nil, fmt.Errorf("adapter lookup: %w", err)
 	}
@@ -47,22 +302,64 @@ func (s *patientServiceImpl) Get(ctx context.Context, identifier string) (*repos
 		return nil, nil
 	}
 
-	// 3) Persist to DB (set HospitalID from context if available) -- prefer context value "hospital_id"
+	// 3) Persist to DB under the caller's hospital
 	if p.HospitalID == "" {
-		if hid, ok := ctx.Value("hospital_id").(string); ok && hid != "" {
-			p.HospitalID = hid
-		}
+		p.HospitalID = hid
 	}
 	if p.ID == "" {
 		p.ID = uuid.NewString()
 	}
 
-	// use Upsert so adapter results update existing rows instead of inserting duplicates
-	if err := s.repo.Upsert(ctx, p); err != nil {
-		return nil, fmt.Errorf("repo upsert: %w", err)
+	// malformed records are held for review instead of being stored
+	if reasons := validateHISPatient(p, identifier, s.now()); len(reasons) > 0 {
+		if err := quarantine(ctx, s.uow, p, identifier, reasons); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", ErrQuarantined, strings.Join(reasons, "; "))
 	}
 
-	return p, nil
+	// upsert + version history + outbox event + audit event in one transaction
+	var stored *repository.Patient
+	err = s.uow.Do(ctx, func(r *repository.Repos) error {
+		var err error
+		stored, err = persistPatient(ctx, r, p, repository.SourceHIS, survivorshipFor(s.his, hid))
+		if err != nil {
+			return err
+		}
+		// the reconciler refreshes it once the hospital's TTL has passed
+		if err := r.Patients.MarkHISSynced(ctx, stored.ID); err != nil {
+			return fmt.Errorf("mark synced: %w", err)
+		}
+		if s.linker != nil {
+			if err := s.linker.flag(ctx, r, stored, repository.SourceHIS); err != nil {
+				return err
+			}
+		}
+		if staffID != "" {
+			if err := r.Analytics.LogSearch(ctx, staffID, stored.HospitalID, identifierFilters(stored, identifier), 1); err != nil {
+				return fmt.Errorf("log search: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stored, nil
+}
+
+// Provenance returns the source, actor and time of the last write of each of
+// the patient's fields (empty without WithFieldSources).
+func (s *patientServiceImpl) Provenance(ctx context.Context, patientID string) (map[string]repository.FieldProvenance, error) {
+	if s.sources == nil {
+		return map[string]repository.FieldProvenance{}, nil
+	}
+	prov, err := s.sources.Provenance(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("field provenance: %w", err)
+	}
+	return prov, nil
 }
 
 func (s *patientServiceImpl) Search(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) ([]*repository.Patient, int, error) {
@@ -72,3 +369,210 @@ func (s *patientServiceImpl) Search(ctx context.Context, hospitalID string, filt
 	}
 	return results, total, nil
 }
+
+func (s *patientServiceImpl) SearchFederated(ctx context.Context, hospitalID string, filters repository.PatientFilters, limit, offset int) (*FederatedResult, error) {
+	local, total, err := s.Search(ctx, hospitalID, filters, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	res := &FederatedResult{Total: total, HISStatus: HISStatusNotQueried}
+	for _, p := range local {
+		res.Results = append(res.Results, SearchHit{Patient: p, Source: HitSourceLocal})
+	}
+	// only the first page is extended: HIS results have no stable order to page through
+	if offset > 0 || len(local) >= limit || !federable(filters) {
+		return res, nil
+	}
+
+	hctx, cancel := context.WithTimeout(ctx, s.lookupTimeout)
+	defer cancel()
+	remote, err := s.searchHIS(hctx, hospitalID, filters)
+	switch {
+	case errors.Is(err, ErrNoHIS):
+		res.HISStatus = HISStatusNotConfigured
+		return res, nil
+	case errors.Is(err, ErrAmbiguousMatch):
+		res.HISStatus = HISStatusAmbiguous
+		return res, nil
+	case err != nil:
+		// the local results still answer the search
+		log.Printf("federated search HIS error (hospital=%s): %v", hospitalID, err)
+		res.HISStatus = HISStatusUnavailable
+		return res, nil
+	}
+	res.HISStatus = HISStatusOK
+
+	seen := make(map[string]bool)
+	for _, p := range local {
+		markSeen(seen, p)
+	}
+	for _, p := range remote {
+		// keep the local search semantics (e.g. English-name substring match)
+		if !filters.Matches(p) || isSeen(seen, p) {
+			continue
+		}
+		markSeen(seen, p)
+		res.Total++
+		if len(res.Results) < limit {
+			p.HospitalID = hospitalID
+			res.Results = append(res.Results, SearchHit{Patient: p, Source: HitSourceHIS})
+		}
+	}
+	return res, nil
+}
+
+// searchHIS asks hospitalID's HIS: an identifier lookup when the filters carry
+// one, a demographic search otherwise.
+func (s *patientServiceImpl) searchHIS(ctx context.Context, hospitalID string, f repository.PatientFilters) ([]*repository.Patient, error) {
+	client, err := s.his.ClientFor(hospitalID)
+	if err != nil {
+		return nil, err
+	}
+	if id := firstNonEmpty(f.NationalID, f.PassportID); id != "" {
+		p, err := client.LookupByIdentifier(ctx, id)
+		if err != nil || p == nil {
+			return nil, err
+		}
+		return []*repository.Patient{p}, nil
+	}
+	return client.SearchDemographics(ctx, f)
+}
+
+// federable reports whether filters are specific enough to send to a HIS:
+// an identifier, an HN, or a last name with a first name or date of birth.
+func federable(f repository.PatientFilters) bool {
+	return f.NationalID != "" || f.PassportID != "" || f.PatientHN != "" ||
+		(f.LastName != "" && (f.FirstName != "" || f.DateOfBirth != ""))
+}
+
+// patientKeys are the identifiers that make two records the same patient.
+func patientKeys(p *repository.Patient) []string {
+	var keys []string
+	if p.NationalID != "" {
+		keys = append(keys, "nid:"+p.NationalID)
+	}
+	if p.PassportID != "" {
+		keys = append(keys, "pp:"+p.PassportID)
+	}
+	if p.PatientHN != "" {
+		keys = append(keys, "hn:"+p.PatientHN)
+	}
+	return keys
+}
+
+func markSeen(seen map[string]bool, p *repository.Patient) {
+	for _, k := range patientKeys(p) {
+		seen[k] = true
+	}
+}
+
+func isSeen(seen map[string]bool, p *repository.Patient) bool {
+	for _, k := range patientKeys(p) {
+		if seen[k] {
+			return true
+		}
+	}
+	return false
+}
+
+func firstNonEmpty(vals ...string) string {
+	for _, v := range vals {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// persistPatient upserts p, reloads the stored row (Upsert may have updated
+// an existing patient with a different id), records a version and enqueues
+// a patient.created / patient.updated outbox event. Call it inside a unit of work.
+// When p updates a stored patient, policy decides per field whether source
+// may overwrite it; refused values are stored as conflicts for review. The
+// source, actor and time of every written field are recorded (provenance).
+func persistPatient(ctx context.Context, r *repository.Repos, p *repository.Patient, source string, policy adapter.SurvivorshipConfig) (*repository.Patient, error) {
+	key := primaryIdentifier(p)
+
+	var existing *repository.Patient
+	if key != "" {
+		var err error
+		existing, err = r.Patients.GetByIdentifier(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("repo get existing: %w", err)
+		}
+	}
+
+	var refused []*repository.FieldConflict
+	if existing != nil {
+		var err error
+		if refused, err = applySurvivorship(ctx, r, existing, p, source, policy); err != nil {
+			return nil, err
+		}
+	}
+
+	// use Upsert so adapter results update existing rows instead of inserting duplicates
+	if err := r.Patients.Upsert(ctx, p); err != nil {
+		return nil, fmt.Errorf("repo upsert: %w", err)
+	}
+
+	stored := p
+	if key != "" {
+		got, err := r.Patients.GetByIdentifier(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("repo reload: %w", err)
+		}
+		if got != nil {
+			stored = got
+		}
+	}
+
+	if written := writtenFields(existing, stored); len(written) > 0 {
+		// provenance: the staff member behind the write, if any (HIS lookups keep the caller's)
+		actor, _ := ctx.Value("staff_id").(string)
+		if err := r.Sources.Set(ctx, stored.ID, written, source, actor); err != nil {
+			return nil, fmt.Errorf("record field sources: %w", err)
+		}
+	}
+	for _, c := range refused {
+		c.PatientID = stored.ID
+		if err := r.Conflicts.Add(ctx, c); err != nil {
+			return nil, fmt.Errorf("record field conflict: %w", err)
+		}
+		// values are not logged: they are patient data
+		log.Printf("survivorship: %s write to %s of patient %s refused (hospital=%s, kept %s value)",
+			source, c.Field, stored.ID, stored.HospitalID, c.KeptSource)
+	}
+
+	if err := r.History.Record(ctx, stored, source); err != nil {
+		return nil, fmt.Errorf("record history: %w", err)
+	}
+
+	eventType := outbox.EventPatientCreated
+	if existing != nil {
+		eventType = outbox.EventPatientUpdated
+	}
+	ev, err := outbox.NewPatientEvent(eventType, stored)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.Outbox.Enqueue(ctx, ev); err != nil {
+		return nil, fmt.Errorf("enqueue outbox: %w", err)
+	}
+	return stored, nil
+}
+
+// primaryIdentifier returns the identifier Upsert conflicts on.
+func primaryIdentifier(p *repository.Patient) string {
+	if p.NationalID != "" {
+		return p.NationalID
+	}
+	return p.PassportID
+}
+
+// identifierFilters describes an identifier lookup in audit filters.
+func identifierFilters(p *repository.Patient, identifier string) repository.PatientFilters {
+	if p.PassportID == identifier && p.NationalID != identifier {
+		return repository.PatientFilters{PassportID: identifier}
+	}
+	return repository.PatientFilters{NationalID: identifier}
+}
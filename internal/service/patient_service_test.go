@@ -0,0 +1,391 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// fakeUnitOfWork runs fn against repos built on a pgxmock pool, without Begin/Commit.
+type fakeUnitOfWork struct {
+	db    repository.DBPool
+	calls int
+}
+
+func (f *fakeUnitOfWork) Do(ctx context.Context, fn func(r *repository.Repos) error) error {
+	f.calls++
+	return fn(repository.NewRepos(f.db))
+}
+
+// fakeHospital implements adapter.HospitalClient.
+type fakeHospital struct {
+	out   *repository.Patient
+	found []*repository.Patient
+	err   error
+	calls int
+}
+
+func (f *fakeHospital) LookupByIdentifier(_ context.Context, _ string) (*repository.Patient, error) {
+	f.calls++
+	return f.out, f.err
+}
+
+func (f *fakeHospital) SearchDemographics(_ context.Context, _ repository.PatientFilters) ([]*repository.Patient, error) {
+	f.calls++
+	return f.found, f.err
+}
+
+// fakeResolver implements adapter.ClientResolver.
+type fakeResolver map[string]adapter.HospitalClient
+
+func (f fakeResolver) ClientFor(hospitalID string) (adapter.HospitalClient, error) {
+	c, ok := f[hospitalID]
+	if !ok {
+		return nil, adapter.ErrNoHIS
+	}
+	return c, nil
+}
+
+var patientCols = []string{
+	"id", "patient_hn", "national_id", "passport_id",
+	"first_name_th", "middle_name_th", "last_name_th",
+	"first_name_en", "middle_name_en", "last_name_en",
+	"date_of_birth", "phone_number", "email", "gender", "raw_json", "hospital_id",
+}
+
+// resolveCols are the columns of Resolve's DB lookup.
+var resolveCols = append(append([]string{}, patientCols...), "his_synced_at")
+
+// anyArgs matches n arguments of any value.
+func anyArgs(n int) []any {
+	args := make([]any, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
+
+func TestPatientService_Resolve_BackfillsInOneUnitOfWork(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	dob, _ := time.Parse("2006-01-02", "1985-05-05")
+
+	// 1) DB miss
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols))
+	// 2) inside the unit of work: existing check, upsert, reload, history, outbox, audit
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(patientCols))
+	mock.ExpectExec(`ON CONFLICT \(national_id\)`).
+		WithArgs(anyArgs(16)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(
+			"stored-id", "HN-9", "N-1", nil,
+			"มานพ", nil, "สุขใจ",
+			"Manop", nil, "Sukjai",
+			dob, "0811112222", "manop@example.com", "M", []byte(`{}`), "HIS-1",
+		))
+	mock.ExpectExec(`INSERT INTO patient_field_sources`).
+		WithArgs("stored-id", pgxmock.AnyArg(), repository.SourceHIS, "staff-1").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("stored-id", "HIS-1", repository.SourceHIS, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientCreated, "stored-id", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE patients SET his_synced_at = now\(\)`).
+		WithArgs("stored-id").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`INSERT INTO search_events`).
+		WithArgs("staff-1", "HIS-1", pgxmock.AnyArg(), 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	uow := &fakeUnitOfWork{db: mock}
+	his := &fakeHospital{out: &repository.Patient{NationalID: "N-1", PatientHN: "HN-9", FirstNameEN: "Manop", LastNameEN: "Sukjai"}}
+	svc := NewPatientService(repository.NewPatientRepo(mock), uow, fakeResolver{"HIS-1": his})
+
+	ctx := context.WithValue(context.Background(), "staff_id", "staff-1")
+
+	p, err := svc.Resolve(ctx, "HIS-1", "N-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "stored-id", p.ID)
+		assert.Equal(t, "HIS-1", p.HospitalID)
+	}
+	assert.Equal(t, 1, uow.calls)
+	assert.Equal(t, 1, his.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_Resolve_DBHitSkipsAdapter(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols).AddRow(
+			"p1", "HN-1", "N-1", nil,
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil, "HIS-1", nil,
+		))
+
+	uow := &fakeUnitOfWork{db: mock}
+	his := &fakeHospital{}
+	svc := NewPatientService(repository.NewPatientRepo(mock), uow, fakeResolver{"HIS-1": his})
+
+	p, err := svc.Resolve(context.Background(), "HIS-1", "N-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "p1", p.ID)
+	assert.Equal(t, 0, uow.calls)
+	assert.Equal(t, 0, his.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_Resolve_HospitalWithoutHIS(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols))
+
+	his := &fakeHospital{}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{"HIS-1": his})
+
+	p, err := svc.Resolve(context.Background(), "HIS-9", "N-1")
+	assert.ErrorIs(t, err, ErrNoHIS)
+	assert.Nil(t, p)
+	assert.Equal(t, 0, his.calls, "another hospital's HIS must not be used")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_Resolve_OtherHospitalsPatientIsHidden(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols).AddRow(
+			"p1", "HN-1", "N-1", nil,
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil, "HIS-2", nil,
+		))
+
+	uow := &fakeUnitOfWork{db: mock}
+	his := &fakeHospital{out: &repository.Patient{NationalID: "N-1"}}
+	svc := NewPatientService(repository.NewPatientRepo(mock), uow, fakeResolver{"HIS-1": his})
+
+	p, err := svc.Resolve(context.Background(), "HIS-1", "N-1")
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+	assert.Equal(t, 0, his.calls, "HIS data must not overwrite another hospital's patient")
+	assert.Equal(t, 0, uow.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_Resolve_DBHitIsAudited(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("P-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols).AddRow(
+			"p1", "HN-1", nil, "P-1",
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil, "HIS-1", nil,
+		))
+	mock.ExpectExec(`INSERT INTO search_events`).
+		WithArgs("staff-1", "HIS-1", pgxmock.AnyArg(), 1).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{})
+
+	ctx := context.WithValue(context.Background(), "staff_id", "staff-1")
+	p, err := svc.Resolve(ctx, "HIS-1", "P-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "p1", p.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// blockingHospital blocks every lookup until release is closed.
+type blockingHospital struct {
+	release chan struct{}
+	calls   int32
+	ctxErr  error
+}
+
+func (b *blockingHospital) LookupByIdentifier(ctx context.Context, _ string) (*repository.Patient, error) {
+	atomic.AddInt32(&b.calls, 1)
+	<-b.release
+	b.ctxErr = ctx.Err()
+	return nil, nil
+}
+
+func (b *blockingHospital) SearchDemographics(_ context.Context, _ repository.PatientFilters) ([]*repository.Patient, error) {
+	return nil, nil
+}
+
+func TestPatientService_Resolve_CoalescesConcurrentLookups(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	const callers = 5
+	for i := 0; i < callers; i++ {
+		mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+			WithArgs("N-1").
+			WillReturnRows(pgxmock.NewRows(resolveCols))
+	}
+
+	his := &blockingHospital{release: make(chan struct{})}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{"HIS-1": his})
+
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	followerCtx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		ctx := followerCtx
+		if i == 0 {
+			ctx = leaderCtx
+		}
+		wg.Add(1)
+		go func(i int, ctx context.Context) {
+			defer wg.Done()
+			_, errs[i] = svc.Resolve(ctx, "HIS-1", "N-1")
+		}(i, ctx)
+		if i == 0 {
+			// let the first caller start the shared lookup
+			for atomic.LoadInt32(&his.calls) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// the first caller leaving must not cancel the lookup the others wait on
+	cancelLeader()
+	close(his.release)
+	wg.Wait()
+
+	assert.EqualValues(t, 1, atomic.LoadInt32(&his.calls))
+	assert.NoError(t, his.ctxErr)
+	assert.ErrorIs(t, errs[0], context.Canceled)
+	for _, err := range errs[1:] {
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// searchCols are the columns of SearchPatients' select.
+var searchCols = patientCols[:len(patientCols)-1]
+
+func TestPatientService_SearchFederated_MergesAndMarksSources(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(1\) FROM patients`).
+		WithArgs(anyArgs(3)...).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(`SELECT id, patient_hn`).
+		WithArgs(anyArgs(5)...).
+		WillReturnRows(pgxmock.NewRows(searchCols).AddRow(
+			"p1", "HN-1", "N-1", nil,
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil,
+		))
+
+	his := &fakeHospital{found: []*repository.Patient{
+		{NationalID: "N-1", FirstNameEN: "Somchai", LastNameEN: "Jaidee"}, // already stored
+		{NationalID: "N-2", FirstNameEN: "Somchai", LastNameEN: "Jaideeworn"},
+		{NationalID: "N-3", FirstNameEN: "Somsak", LastNameEN: "Jaidee"}, // does not match first_name
+	}}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{"HIS-1": his})
+
+	res, err := svc.SearchFederated(context.Background(), "HIS-1",
+		repository.PatientFilters{FirstName: "Somchai", LastName: "Jaidee"}, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, HISStatusOK, res.HISStatus)
+	assert.Equal(t, 2, res.Total)
+	if assert.Len(t, res.Results, 2) {
+		assert.Equal(t, "p1", res.Results[0].ID)
+		assert.Equal(t, HitSourceLocal, res.Results[0].Source)
+		assert.Equal(t, "N-2", res.Results[1].NationalID)
+		assert.Equal(t, HitSourceHIS, res.Results[1].Source)
+		assert.Equal(t, "HIS-1", res.Results[1].HospitalID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_SearchFederated_DegradesWhenHISFails(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(1\) FROM patients`).
+		WithArgs(anyArgs(3)...).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT id, patient_hn`).
+		WithArgs(anyArgs(5)...).
+		WillReturnRows(pgxmock.NewRows(searchCols))
+
+	his := &fakeHospital{err: fmt.Errorf("%w: timeout", ErrHISUnavailable)}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{"HIS-1": his})
+
+	res, err := svc.SearchFederated(context.Background(), "HIS-1",
+		repository.PatientFilters{LastName: "Jaidee", DateOfBirth: "1990-01-01"}, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, HISStatusUnavailable, res.HISStatus)
+	assert.Empty(t, res.Results)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_SearchFederated_SkipsHISForBroadFilters(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(1\) FROM patients`).
+		WithArgs(anyArgs(2)...).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT id, patient_hn`).
+		WithArgs(anyArgs(4)...).
+		WillReturnRows(pgxmock.NewRows(searchCols))
+
+	his := &fakeHospital{}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{"HIS-1": his})
+
+	res, err := svc.SearchFederated(context.Background(), "HIS-1", repository.PatientFilters{LastName: "Jaidee"}, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, HISStatusNotQueried, res.HISStatus)
+	assert.Equal(t, 0, his.calls)
+}
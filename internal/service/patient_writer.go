@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// PatientWriter handles staff-entered patient writes (POST /v1/patients).
+type PatientWriter interface {
+	// Upsert stores p, its version history and its outbox event atomically.
+	// On success p reflects the stored row (including its id).
+	Upsert(ctx context.Context, p *repository.Patient) error
+}
+
+type patientWriterImpl struct {
+	uow    repository.UnitOfWork
+	cache  adapter.Invalidator
+	source string
+	linker *Linker
+	policy SurvivorshipPolicy
+}
+
+// NewPatientWriter constructs a PatientWriter. cache (may be nil) drops cached
+// HIS lookups of the written identifiers once the write has committed.
+// policy (may be nil) keeps fields the hospital lets another source own.
+func NewPatientWriter(uow repository.UnitOfWork, cache adapter.Invalidator, policy SurvivorshipPolicy) PatientWriter {
+	return NewIngestWriter(uow, cache, repository.SourceStaff, nil, policy)
+}
+
+// NewIngestWriter is NewPatientWriter for data pushed by other systems
+// (e.g. HL7 ADT feeds); versions are recorded with the given source.
+// linker (may be nil) records likely duplicates of each written patient.
+func NewIngestWriter(uow repository.UnitOfWork, cache adapter.Invalidator, source string, linker *Linker, policy SurvivorshipPolicy) PatientWriter {
+	return &patientWriterImpl{uow: uow, cache: cache, source: source, linker: linker, policy: policy}
+}
+
+func (w *patientWriterImpl) Upsert(ctx context.Context, p *repository.Patient) error {
+	// invalidate the identifiers as sent too, in case the stored row differs
+	natID, passID := p.NationalID, p.PassportID
+
+	err := w.uow.Do(ctx, func(r *repository.Repos) error {
+		stored, err := persistPatient(ctx, r, p, w.source, survivorshipFor(w.policy, p.HospitalID))
+		if err != nil {
+			return err
+		}
+		*p = *stored
+		if w.linker != nil {
+			return w.linker.flag(ctx, r, stored, w.source)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if w.cache != nil {
+		w.cache.Invalidate(p.HospitalID, natID, passID, p.NationalID, p.PassportID)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// fakeInvalidator implements adapter.Invalidator.
+type fakeInvalidator struct {
+	hospitalID  string
+	identifiers []string
+}
+
+func (f *fakeInvalidator) Invalidate(hospitalID string, identifiers ...string) {
+	f.hospitalID = hospitalID
+	f.identifiers = append(f.identifiers, identifiers...)
+}
+
+func TestPatientWriter_Upsert_InvalidatesCachedLookups(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(patientCols))
+	mock.ExpectExec(`ON CONFLICT \(national_id\)`).
+		WithArgs(anyArgs(16)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(
+			"p1", "HN-1", "N-1", "P-1",
+			"", nil, "",
+			"Somchai", nil, "Jaidee",
+			nil, "", "", "M", nil, "HIS-1",
+		))
+	mock.ExpectExec(`INSERT INTO patient_field_sources`).
+		WithArgs(anyArgs(4)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p1", "HIS-1", repository.SourceStaff, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientCreated, "p1", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	inv := &fakeInvalidator{}
+	w := NewPatientWriter(&fakeUnitOfWork{db: mock}, inv, nil)
+
+	p := &repository.Patient{ID: "p1", NationalID: "N-1", FirstNameEN: "Somchai", HospitalID: "HIS-1"}
+	assert.NoError(t, w.Upsert(context.Background(), p))
+
+	assert.Equal(t, "HIS-1", inv.hospitalID)
+	assert.Contains(t, inv.identifiers, "N-1")
+	assert.Contains(t, inv.identifiers, "P-1")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ErrQuarantineNotFound is returned for an unknown or already reviewed record.
+var ErrQuarantineNotFound = errors.New("no pending quarantined record with this id")
+
+// quarantine stores p (returned by a HIS lookup for identifier) for review.
+func quarantine(ctx context.Context, uow repository.UnitOfWork, p *repository.Patient, identifier string, reasons []string) error {
+	q := &repository.QuarantinedRecord{
+		HospitalID: p.HospitalID,
+		Identifier: identifier,
+		Reasons:    reasons,
+		Patient:    p,
+		RawBody:    string(p.RawJSON),
+	}
+	err := uow.Do(ctx, func(r *repository.Repos) error {
+		return r.Quarantine.Add(ctx, q)
+	})
+	if err != nil {
+		return fmt.Errorf("quarantine: %w", err)
+	}
+	// reasons name fields, not values, so they are safe to log
+	log.Printf("HIS record quarantined (hospital=%s, id=%s): %v", p.HospitalID, q.ID, reasons)
+	return nil
+}
+
+// QuarantineReview lets a hospital's admins review quarantined HIS records.
+type QuarantineReview struct {
+	uow    repository.UnitOfWork
+	repo   *repository.QuarantineRepo
+	policy SurvivorshipPolicy
+}
+
+// NewQuarantineReview constructs a QuarantineReview; repo serves reads.
+// policy (may be nil) applies to released records like to any HIS write.
+func NewQuarantineReview(repo *repository.QuarantineRepo, uow repository.UnitOfWork, policy SurvivorshipPolicy) *QuarantineReview {
+	return &QuarantineReview{uow: uow, repo: repo, policy: policy}
+}
+
+// List returns the hospital's records with status ("" for any).
+func (q *QuarantineReview) List(ctx context.Context, hospitalID, status string, limit, offset int) ([]*repository.QuarantinedRecord, error) {
+	return q.repo.List(ctx, hospitalID, status, limit, offset)
+}
+
+// Release stores the record as it is, like a HIS back-fill (version history,
+// outbox event), and returns the stored patient.
+func (q *QuarantineReview) Release(ctx context.Context, hospitalID, id, staffID string) (*repository.Patient, error) {
+	var stored *repository.Patient
+	err := q.uow.Do(ctx, func(r *repository.Repos) error {
+		rec, err := r.Quarantine.GetPendingForUpdate(ctx, hospitalID, id)
+		if err != nil {
+			return fmt.Errorf("get quarantined: %w", err)
+		}
+		if rec == nil {
+			return ErrQuarantineNotFound
+		}
+		p := rec.Patient
+		p.HospitalID = hospitalID
+		if p.ID == "" {
+			p.ID = uuid.NewString()
+		}
+		stored, err = persistPatient(ctx, r, p, repository.SourceQuarantine, survivorshipFor(q.policy, hospitalID))
+		if err != nil {
+			return err
+		}
+		if err := r.Patients.MarkHISSynced(ctx, stored.ID); err != nil {
+			return fmt.Errorf("mark synced: %w", err)
+		}
+		return r.Quarantine.Resolve(ctx, rec.ID, repository.QuarantineReleased, staffID, stored.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// Reject closes the record without storing anything.
+func (q *QuarantineReview) Reject(ctx context.Context, hospitalID, id, staffID string) error {
+	return q.uow.Do(ctx, func(r *repository.Repos) error {
+		rec, err := r.Quarantine.GetPendingForUpdate(ctx, hospitalID, id)
+		if err != nil {
+			return fmt.Errorf("get quarantined: %w", err)
+		}
+		if rec == nil {
+			return ErrQuarantineNotFound
+		}
+		return r.Quarantine.Resolve(ctx, rec.ID, repository.QuarantineRejected, staffID, "")
+	})
+}
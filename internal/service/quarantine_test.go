@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+func TestValidateHISPatient(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	dob := func(s string) *string { return &s }
+	valid := repository.Patient{NationalID: "1103700000003", FirstNameEN: "Somchai", LastNameEN: "Jaidee", DateOfBirth: dob("1985-05-05"), Gender: "M"}
+
+	assert.Empty(t, validateHISPatient(&valid, "1103700000003", now))
+
+	cases := map[string]func(p *repository.Patient){
+		"identifier mismatch": func(p *repository.Patient) { p.NationalID = "1103700000011" },
+		"checksum":            func(p *repository.Patient) { p.NationalID = "1103700000001" },
+		"name":                func(p *repository.Patient) { p.LastNameEN = " " },
+		"not a valid":         func(p *repository.Patient) { p.DateOfBirth = dob("1985-13-45") },
+		"in the future":       func(p *repository.Patient) { p.DateOfBirth = dob("2030-01-01") },
+		"gender":              func(p *repository.Patient) { p.Gender = "X" },
+	}
+	for want, mutate := range cases {
+		p := valid
+		mutate(&p)
+		reasons := validateHISPatient(&p, "1103700000003", now)
+		if assert.NotEmpty(t, reasons, want) {
+			assert.Contains(t, reasons[len(reasons)-1], want)
+		}
+	}
+}
+
+func TestPatientService_Resolve_QuarantinesInvalidRecord(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols))
+	mock.ExpectQuery(`INSERT INTO his_quarantine`).
+		WithArgs("HIS-1", "N-1", pgxmock.AnyArg(), pgxmock.AnyArg(), `{"dob":"1985-13-45"}`).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("q-1"))
+
+	dob := "1985-13-45"
+	his := &fakeHospital{out: &repository.Patient{
+		NationalID: "N-1", FirstNameEN: "Manop", LastNameEN: "Sukjai", DateOfBirth: &dob,
+		RawJSON: []byte(`{"dob":"1985-13-45"}`),
+	}}
+	uow := &fakeUnitOfWork{db: mock}
+	svc := NewPatientService(repository.NewPatientRepo(mock), uow, fakeResolver{"HIS-1": his})
+
+	p, err := svc.Resolve(context.Background(), "HIS-1", "N-1")
+	assert.Nil(t, p)
+	assert.True(t, errors.Is(err, ErrQuarantined))
+	assert.Equal(t, 1, uow.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientService_Resolve_ValidatesAgainstServiceClock(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(resolveCols))
+	mock.ExpectQuery(`INSERT INTO his_quarantine`).
+		WithArgs("HIS-1", "N-1", pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg()).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("q-1"))
+
+	// born "tomorrow" by the service's clock
+	dob := "2024-03-02"
+	his := &fakeHospital{out: &repository.Patient{NationalID: "N-1", FirstNameEN: "Manop", LastNameEN: "Sukjai", DateOfBirth: &dob}}
+	svc := NewPatientService(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, fakeResolver{"HIS-1": his}).(*patientServiceImpl)
+	svc.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	_, err = svc.Resolve(context.Background(), "HIS-1", "N-1")
+	assert.ErrorIs(t, err, ErrQuarantined)
+	assert.ErrorContains(t, err, "in the future")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+var quarantineRowCols = []string{
+	"id", "hospital_id", "identifier", "reasons", "patient", "raw_body", "status", "hits",
+	"patient_id", "reviewed_by", "reviewed_at", "created_at", "last_seen_at",
+}
+
+func TestQuarantineReview_ReleaseStoresPatient(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM his_quarantine\s+WHERE id = \$1 AND hospital_id = \$2 AND status = 'pending' FOR UPDATE`).
+		WithArgs("q-1", "HIS-1").
+		WillReturnRows(pgxmock.NewRows(quarantineRowCols).AddRow(
+			"q-1", "HIS-1", "N-1", []string{"name: no first and last name in Thai or English"},
+			[]byte(`{"NationalID":"N-1","FirstNameEN":"Madonna"}`), nil, "pending", 2,
+			nil, nil, nil, now, now,
+		))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(patientCols))
+	mock.ExpectExec(`ON CONFLICT \(national_id\)`).
+		WithArgs(anyArgs(16)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(
+			"stored-id", "", "N-1", nil, "", nil, "", "Madonna", nil, "", nil, "", "", "", nil, "HIS-1",
+		))
+	mock.ExpectExec(`INSERT INTO patient_field_sources`).
+		WithArgs(anyArgs(4)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("stored-id", "HIS-1", repository.SourceQuarantine, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientCreated, "stored-id", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE patients SET his_synced_at = now\(\)`).
+		WithArgs("stored-id").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectExec(`UPDATE his_quarantine`).
+		WithArgs("q-1", repository.QuarantineReleased, "admin-1", "stored-id").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	q := NewQuarantineReview(repository.NewQuarantineRepo(mock), &fakeUnitOfWork{db: mock}, nil)
+	p, err := q.Release(context.Background(), "HIS-1", "q-1", "admin-1")
+	assert.NoError(t, err)
+	if assert.NotNil(t, p) {
+		assert.Equal(t, "stored-id", p.ID)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQuarantineReview_RejectUnknown(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`FROM his_quarantine`).
+		WithArgs("q-9", "HIS-1").
+		WillReturnRows(pgxmock.NewRows(quarantineRowCols))
+
+	q := NewQuarantineReview(repository.NewQuarantineRepo(mock), &fakeUnitOfWork{db: mock}, nil)
+	err = q.Reject(context.Background(), "HIS-1", "q-9", "admin-1")
+	assert.True(t, errors.Is(err, ErrQuarantineNotFound))
+}
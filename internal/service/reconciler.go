@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// ReconcileSource lists the hospitals to reconcile with their settings (adapter.Registry).
+type ReconcileSource interface {
+	HospitalDirectory
+	ReconcileConfig(hospitalID string) adapter.ReconcileConfig
+}
+
+// ReconcileStats describes one reconciler run for one hospital.
+type ReconcileStats struct {
+	HospitalID  string         `json:"hospital_id"`
+	StartedAt   time.Time      `json:"started_at"`
+	DurationMS  int64          `json:"duration_ms"`
+	Checked     int            `json:"checked"`
+	Unchanged   int            `json:"unchanged"`
+	Updated     int            `json:"updated"`
+	NotFound    int            `json:"not_found"`   // the HIS no longer knows the identifier
+	Failed      int            `json:"failed"`      // left stale, retried on a later run
+	Conflicts   int            `json:"conflicts"`   // the HIS reports other identifiers; not applied
+	Quarantined int            `json:"quarantined"` // the HIS record failed validation
+	Drift       map[string]int `json:"drift"`       // updated patients per applied field
+	Aborted     string         `json:"aborted,omitempty"`
+}
+
+// Reconciler refreshes stored HIS patients whose data is older than their
+// hospital's TTL. Changes are written like a back-fill (version history with
+// source "reconciler", outbox patient.updated event); unchanged rows only get
+// a new his_synced_at.
+type Reconciler struct {
+	uow   repository.UnitOfWork
+	repo  *repository.PatientRepo
+	his   ReconcileSource
+	lease time.Duration
+	now   func() time.Time
+	wait  func(ctx context.Context, d time.Duration) error
+
+	mu   sync.Mutex
+	last map[string]ReconcileStats
+}
+
+// NewReconciler constructs a Reconciler.
+func NewReconciler(repo *repository.PatientRepo, uow repository.UnitOfWork, his ReconcileSource) *Reconciler {
+	return &Reconciler{
+		uow:   uow,
+		repo:  repo,
+		his:   his,
+		lease: 10 * time.Minute,
+		now:   time.Now,
+		wait:  sleepCtx,
+		last:  make(map[string]ReconcileStats),
+	}
+}
+
+// Run reconciles every interval until ctx is cancelled.
+func (r *Reconciler) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		for _, st := range r.RunOnce(ctx) {
+			if st.Checked > 0 || st.Aborted != "" {
+				log.Printf("reconciler (hospital=%s): checked=%d updated=%d unchanged=%d not_found=%d failed=%d conflicts=%d quarantined=%d drift=%v aborted=%q",
+					st.HospitalID, st.Checked, st.Updated, st.Unchanged, st.NotFound, st.Failed, st.Conflicts, st.Quarantined, st.Drift, st.Aborted)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce reconciles one batch per hospital, hospitals in parallel, and
+// returns the run's statistics.
+func (r *Reconciler) RunOnce(ctx context.Context) []ReconcileStats {
+	hospitals := r.his.Hospitals()
+	out := make([]ReconcileStats, len(hospitals))
+	var wg sync.WaitGroup
+	for i, hid := range hospitals {
+		cfg := r.his.ReconcileConfig(hid)
+		if cfg.Disabled {
+			out[i] = ReconcileStats{HospitalID: hid, StartedAt: r.now(), Aborted: "disabled"}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out[i] = r.reconcileHospital(ctx, hid, cfg)
+		}()
+	}
+	wg.Wait()
+
+	r.mu.Lock()
+	for _, st := range out {
+		r.last[st.HospitalID] = st
+	}
+	r.mu.Unlock()
+	return out
+}
+
+// Stats returns the last run of every hospital, sorted by hospital.
+func (r *Reconciler) Stats() []ReconcileStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]ReconcileStats, 0, len(r.last))
+	for _, st := range r.last {
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].HospitalID < out[j].HospitalID })
+	return out
+}
+
+func (r *Reconciler) reconcileHospital(ctx context.Context, hid string, cfg adapter.ReconcileConfig) ReconcileStats {
+	start := r.now()
+	st := ReconcileStats{HospitalID: hid, StartedAt: start, Drift: map[string]int{}}
+	defer func() { st.DurationMS = r.now().Sub(start).Milliseconds() }()
+
+	client, err := r.his.ClientFor(hid)
+	if err != nil {
+		st.Aborted = err.Error()
+		return st
+	}
+	stale, err := r.repo.ClaimStaleHIS(ctx, hid, start.Add(-time.Duration(cfg.TTL)), cfg.BatchSize, r.lease)
+	if err != nil {
+		st.Aborted = fmt.Sprintf("claim stale: %v", err)
+		return st
+	}
+
+	interval := time.Duration(float64(time.Second) / cfg.RatePerSecond)
+	for i, old := range stale {
+		if i > 0 {
+			if err := r.wait(ctx, interval); err != nil {
+				st.Aborted = err.Error()
+				return st
+			}
+		}
+		st.Checked++
+		if err := r.reconcileOne(ctx, client, old, &st); err != nil {
+			st.Failed++
+			if errors.Is(err, ErrHISUnavailable) {
+				// the HIS is down or its breaker is open; the rest stays claimed until the lease expires
+				st.Aborted = err.Error()
+				return st
+			}
+			log.Printf("reconciler (hospital=%s, patient=%s): %v", hid, old.ID, err)
+		}
+	}
+	return st
+}
+
+func (r *Reconciler) reconcileOne(ctx context.Context, client adapter.HospitalClient, old *repository.Patient, st *ReconcileStats) error {
+	// a cached answer would be marked synced without asking the HIS
+	fresh, err := client.LookupByIdentifier(adapter.BypassCache(ctx), primaryIdentifier(old))
+	if err != nil {
+		return err
+	}
+	if fresh == nil {
+		// keep our copy; it is checked again after the next TTL
+		st.NotFound++
+		return r.repo.MarkHISSynced(ctx, old.ID)
+	}
+
+	fresh.HospitalID = old.HospitalID
+	if reasons := validateHISPatient(fresh, primaryIdentifier(old), r.now()); len(reasons) > 0 {
+		// keep our copy; an admin decides whether the HIS version wins
+		st.Quarantined++
+		if err := quarantine(ctx, r.uow, fresh, primaryIdentifier(old), reasons); err != nil {
+			return err
+		}
+		return r.repo.MarkHISSynced(ctx, old.ID)
+	}
+
+	changed := patientDrift(old, fresh)
+	for _, f := range changed {
+		if f == "national_id" || f == "passport_id" {
+			// re-keying a patient is a merge decision, not a refresh
+			log.Printf("reconciler (hospital=%s, patient=%s): HIS reports a different %s; not applied", old.HospitalID, old.ID, f)
+			st.Conflicts++
+			st.Drift[f]++
+			return r.repo.MarkHISSynced(ctx, old.ID)
+		}
+	}
+	if len(changed) == 0 {
+		st.Unchanged++
+		return r.repo.MarkHISSynced(ctx, old.ID)
+	}
+
+	fresh.ID, fresh.HospitalID = old.ID, old.HospitalID
+	var applied []string
+	err = r.uow.Do(ctx, func(repos *repository.Repos) error {
+		stored, err := persistPatient(ctx, repos, fresh, repository.SourceReconciler, survivorshipFor(r.his, old.HospitalID))
+		if err != nil {
+			return err
+		}
+		// survivorship may have kept stored values; only the rest changed
+		applied = patientDrift(old, stored)
+		return repos.Patients.MarkHISSynced(ctx, stored.ID)
+	})
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		st.Unchanged++
+		return nil
+	}
+	st.Updated++
+	for _, f := range applied {
+		st.Drift[f]++
+	}
+	return nil
+}
+
+// patientDrift lists the standard fields that differ between the stored and
+// the fetched patient. An identifier the HIS omits is not a change: Upsert keeps ours.
+func patientDrift(old, fresh *repository.Patient) []string {
+	var changed []string
+	for _, f := range adapter.StandardFields {
+		v := fresh.Field(f)
+		if (f == "national_id" || f == "passport_id") && v == "" {
+			continue
+		}
+		if v != old.Field(f) {
+			changed = append(changed, f)
+		}
+	}
+	return changed
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+	}
+	return nil
+}
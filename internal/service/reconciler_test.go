@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// recordHospital answers lookups from a map keyed by identifier.
+type recordHospital map[string]*repository.Patient
+
+func (h recordHospital) LookupByIdentifier(_ context.Context, id string) (*repository.Patient, error) {
+	p, ok := h[id]
+	if !ok {
+		return nil, nil
+	}
+	cp := *p
+	return &cp, nil
+}
+
+func (h recordHospital) SearchDemographics(_ context.Context, _ repository.PatientFilters) ([]*repository.Patient, error) {
+	return nil, nil
+}
+
+type fakeReconcileSource struct {
+	fakeDirectory
+	cfg adapter.ReconcileConfig
+}
+
+func (s fakeReconcileSource) ReconcileConfig(string) adapter.ReconcileConfig { return s.cfg }
+
+func TestReconciler_RefreshesChangedPatients(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	dob, _ := time.Parse("2006-01-02", "1985-05-05")
+	row := func(id, nid, phone string) []any {
+		return []any{id, "HN-" + id, nid, nil, "", nil, "", "Manop", nil, "Sukjai", dob, phone, "", "M", []byte(`{}`), "HIS-1"}
+	}
+
+	mock.ExpectQuery(`UPDATE patients SET his_sync_claimed_at = now\(\)`).
+		WithArgs("HIS-1", pgxmock.AnyArg(), 10, (10 * time.Minute).Seconds()).
+		WillReturnRows(pgxmock.NewRows(patientCols).
+			AddRow(row("p1", "N-1", "0811112222")...).
+			AddRow(row("p2", "N-2", "0811112222")...).
+			AddRow(row("p3", "N-3", "0811112222")...))
+
+	// p1 unchanged
+	mock.ExpectExec(`UPDATE patients SET his_synced_at = now\(\)`).WithArgs("p1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// p2 changed phone: written with provenance, then marked synced
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).WithArgs("N-2").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(row("p2", "N-2", "0811112222")...))
+	mock.ExpectExec(`ON CONFLICT \(national_id\)`).WithArgs(anyArgs(16)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).WithArgs("N-2").
+		WillReturnRows(pgxmock.NewRows(patientCols).AddRow(row("p2", "N-2", "0899999999")...))
+	mock.ExpectExec(`INSERT INTO patient_field_sources`).
+		WithArgs(anyArgs(4)...).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p2", "HIS-1", repository.SourceReconciler, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientUpdated, "p2", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE patients SET his_synced_at = now\(\)`).WithArgs("p2").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	// p3 unknown to the HIS
+	mock.ExpectExec(`UPDATE patients SET his_synced_at = now\(\)`).WithArgs("p3").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	d := "1985-05-05"
+	his := recordHospital{
+		"N-1": {NationalID: "N-1", PatientHN: "HN-p1", FirstNameEN: "Manop", LastNameEN: "Sukjai", DateOfBirth: &d, PhoneNumber: "0811112222", Gender: "M"},
+		"N-2": {NationalID: "N-2", PatientHN: "HN-p2", FirstNameEN: "Manop", LastNameEN: "Sukjai", DateOfBirth: &d, PhoneNumber: "0899999999", Gender: "M"},
+	}
+	src := fakeReconcileSource{
+		fakeDirectory: fakeDirectory{fakeResolver: fakeResolver{"HIS-1": his}, order: []string{"HIS-1"}},
+		cfg:           adapter.ReconcileConfig{TTL: adapter.Duration(time.Hour), RatePerSecond: 5, BatchSize: 10},
+	}
+	rec := NewReconciler(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, src)
+	var waits []time.Duration
+	rec.wait = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	stats := rec.RunOnce(context.Background())
+	if assert.Len(t, stats, 1) {
+		st := stats[0]
+		assert.Equal(t, 3, st.Checked)
+		assert.Equal(t, 1, st.Unchanged)
+		assert.Equal(t, 1, st.Updated)
+		assert.Equal(t, 1, st.NotFound)
+		assert.Equal(t, map[string]int{"phone_number": 1}, st.Drift)
+	}
+	// rate limited: one pause between consecutive lookups
+	assert.Equal(t, []time.Duration{200 * time.Millisecond, 200 * time.Millisecond}, waits)
+	assert.Equal(t, stats, rec.Stats())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciler_CountsOnlyAppliedFields(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	stored := func() *pgxmock.Rows {
+		return pgxmock.NewRows(patientCols).
+			AddRow("p1", "HN-1", "N-1", nil, "", nil, "", "Manop", nil, "Sukjai", nil, "0811111111", "", "M", nil, "HIS-1")
+	}
+	mock.ExpectQuery(`UPDATE patients SET his_sync_claimed_at = now\(\)`).
+		WithArgs(anyArgs(4)...).
+		WillReturnRows(stored())
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).WithArgs("N-1").
+		WillReturnRows(stored())
+	// staff corrected the phone: the HIS value is kept for review, nothing is written
+	mock.ExpectQuery(`SELECT field, source FROM patient_field_sources`).WithArgs("p1").
+		WillReturnRows(pgxmock.NewRows([]string{"field", "source"}).AddRow("phone_number", repository.SourceStaff))
+	mock.ExpectExec(`ON CONFLICT \(national_id\)`).WithArgs(anyArgs(16)...).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).WithArgs("N-1").
+		WillReturnRows(stored())
+	mock.ExpectQuery(`INSERT INTO patient_field_conflicts`).
+		WithArgs("p1", "HIS-1", "phone_number", "0811111111", repository.SourceStaff, "0899999999", repository.SourceReconciler).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("c1"))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p1", "HIS-1", repository.SourceReconciler, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientUpdated, "p1", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`UPDATE patients SET his_synced_at = now\(\)`).WithArgs("p1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	his := recordHospital{
+		"N-1": {NationalID: "N-1", PatientHN: "HN-1", FirstNameEN: "Manop", LastNameEN: "Sukjai", PhoneNumber: "0899999999", Gender: "M"},
+	}
+	src := struct {
+		fakeReconcileSource
+		fakeSurvivorship
+	}{
+		fakeReconcileSource{
+			fakeDirectory: fakeDirectory{fakeResolver: fakeResolver{"HIS-1": his}, order: []string{"HIS-1"}},
+			cfg:           adapter.DefaultReconcileConfig,
+		},
+		fakeSurvivorship{"HIS-1": {"phone_number": adapter.SurvivorLocal}},
+	}
+	rec := NewReconciler(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, src)
+	rec.wait = func(context.Context, time.Duration) error { return nil }
+
+	st := rec.RunOnce(context.Background())[0]
+	assert.Equal(t, 1, st.Unchanged)
+	assert.Equal(t, 0, st.Updated)
+	assert.Empty(t, st.Drift)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReconciler_StopsWhenHISUnavailable(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`UPDATE patients SET his_sync_claimed_at = now\(\)`).
+		WithArgs(anyArgs(4)...).
+		WillReturnRows(pgxmock.NewRows(patientCols).
+			AddRow("p1", "", "N-1", nil, "", nil, "", "", nil, "", nil, "", "", "M", nil, "HIS-1").
+			AddRow("p2", "", "N-2", nil, "", nil, "", "", nil, "", nil, "", "", "M", nil, "HIS-1"))
+
+	his := &fakeHospital{err: fmt.Errorf("%w: circuit open", ErrHISUnavailable)}
+	src := fakeReconcileSource{
+		fakeDirectory: fakeDirectory{fakeResolver: fakeResolver{"HIS-1": his}, order: []string{"HIS-1"}},
+		cfg:           adapter.DefaultReconcileConfig,
+	}
+	rec := NewReconciler(repository.NewPatientRepo(mock), &fakeUnitOfWork{db: mock}, src)
+	rec.wait = func(context.Context, time.Duration) error { return nil }
+
+	st := rec.RunOnce(context.Background())[0]
+	assert.Equal(t, 1, st.Checked)
+	assert.Equal(t, 1, st.Failed)
+	assert.NotEmpty(t, st.Aborted)
+	assert.Equal(t, 1, his.calls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPatientDrift_IgnoresOmittedIdentifiers(t *testing.T) {
+	old := &repository.Patient{NationalID: "N-1", PassportID: "P-1", Email: "a@example.com"}
+	fresh := &repository.Patient{NationalID: "N-1", Email: "b@example.com"}
+	assert.Equal(t, []string{"email"}, patientDrift(old, fresh))
+}
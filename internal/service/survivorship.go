@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// SurvivorshipPolicy gives a hospital's field-level survivorship rules
+// (adapter.Registry). Without one, the last write wins for every field.
+type SurvivorshipPolicy interface {
+	SurvivorshipConfig(hospitalID string) adapter.SurvivorshipConfig
+}
+
+// ErrConflictNotFound is returned for an unknown or already reviewed conflict.
+var ErrConflictNotFound = errors.New("no pending field conflict with this id")
+
+// survivorshipFor returns hid's rules if v implements SurvivorshipPolicy.
+func survivorshipFor(v any, hid string) adapter.SurvivorshipConfig {
+	if p, ok := v.(SurvivorshipPolicy); ok && p != nil {
+		return p.SurvivorshipConfig(hid)
+	}
+	return nil
+}
+
+// sourceClass maps a write source to the survivorship rule it wins under:
+// staff entry is local, everything fetched from or pushed by a HIS is his.
+func sourceClass(source string) string {
+	switch source {
+	case repository.SourceStaff:
+		return adapter.SurvivorLocal
+	case repository.SourceHIS, repository.SourceHL7, repository.SourceReconciler, repository.SourceQuarantine:
+		return adapter.SurvivorHIS
+	}
+	return ""
+}
+
+// applySurvivorship keeps existing's value in p for every field whose rule
+// names a source other than source's, when the stored value was written by
+// the winning source. It returns the refused non-empty values as conflicts
+// (without patient id).
+func applySurvivorship(ctx context.Context, r *repository.Repos, existing, p *repository.Patient, source string, policy adapter.SurvivorshipConfig) ([]*repository.FieldConflict, error) {
+	fields := make([]string, 0, len(policy))
+	for f, rule := range policy {
+		if rule != adapter.SurvivorLatest && rule != sourceClass(source) {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	sort.Strings(fields)
+
+	sources, err := r.Sources.Get(ctx, existing.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get field sources: %w", err)
+	}
+	var refused []*repository.FieldConflict
+	for _, f := range fields {
+		kept, incoming := existing.Field(f), p.Field(f)
+		if kept == incoming || kept == "" || sourceClass(sources[f]) != policy.Rule(f) {
+			continue
+		}
+		p.SetField(f, kept)
+		if incoming == "" {
+			// a source that does not send the field has nothing to review
+			continue
+		}
+		refused = append(refused, &repository.FieldConflict{
+			HospitalID:       existing.HospitalID,
+			Field:            f,
+			KeptValue:        kept,
+			KeptSource:       sources[f],
+			OverriddenValue:  incoming,
+			OverriddenSource: source,
+		})
+	}
+	return refused, nil
+}
+
+// writtenFields lists the standard fields stored now has a new value in
+// (every non-empty field for a new patient).
+func writtenFields(existing, stored *repository.Patient) []string {
+	var out []string
+	for _, f := range adapter.StandardFields {
+		v := stored.Field(f)
+		if existing == nil && v != "" || existing != nil && existing.Field(f) != v {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// ConflictReview lets a hospital's admins review field writes refused by
+// its survivorship policy.
+type ConflictReview struct {
+	uow  repository.UnitOfWork
+	repo *repository.FieldConflictRepo
+}
+
+// NewConflictReview constructs a ConflictReview; repo serves reads.
+func NewConflictReview(repo *repository.FieldConflictRepo, uow repository.UnitOfWork) *ConflictReview {
+	return &ConflictReview{uow: uow, repo: repo}
+}
+
+// List returns the hospital's conflicts with status ("" for any).
+func (c *ConflictReview) List(ctx context.Context, hospitalID, status string, limit, offset int) ([]*repository.FieldConflict, error) {
+	return c.repo.List(ctx, hospitalID, status, limit, offset)
+}
+
+// Accept applies the refused value once, bypassing the policy, as a write
+// by its original source (version history, outbox event), and returns the
+// stored patient. Later writes are judged by the policy as usual.
+func (c *ConflictReview) Accept(ctx context.Context, hospitalID, id, staffID string) (*repository.Patient, error) {
+	var stored *repository.Patient
+	err := c.uow.Do(ctx, func(r *repository.Repos) error {
+		fc, err := r.Conflicts.GetPendingForUpdate(ctx, hospitalID, id)
+		if err != nil {
+			return fmt.Errorf("get conflict: %w", err)
+		}
+		if fc == nil {
+			return ErrConflictNotFound
+		}
+		p, err := r.Patients.GetByID(ctx, fc.PatientID)
+		if err != nil {
+			return fmt.Errorf("get patient: %w", err)
+		}
+		if p == nil {
+			return ErrConflictNotFound
+		}
+		p.SetField(fc.Field, fc.OverriddenValue)
+		stored, err = persistPatient(ctx, r, p, fc.OverriddenSource, nil)
+		if err != nil {
+			return err
+		}
+		return r.Conflicts.Resolve(ctx, fc.ID, repository.ConflictAccepted, staffID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("field conflict accepted (hospital=%s, id=%s, by=%s)", hospitalID, id, staffID)
+	return stored, nil
+}
+
+// Dismiss keeps the stored value and closes the conflict.
+func (c *ConflictReview) Dismiss(ctx context.Context, hospitalID, id, staffID string) error {
+	return c.uow.Do(ctx, func(r *repository.Repos) error {
+		fc, err := r.Conflicts.GetPendingForUpdate(ctx, hospitalID, id)
+		if err != nil {
+			return fmt.Errorf("get conflict: %w", err)
+		}
+		if fc == nil {
+			return ErrConflictNotFound
+		}
+		return r.Conflicts.Resolve(ctx, fc.ID, repository.ConflictDismissed, staffID)
+	})
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/adapter"
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// fakeSurvivorship implements SurvivorshipPolicy.
+type fakeSurvivorship map[string]adapter.SurvivorshipConfig
+
+func (f fakeSurvivorship) SurvivorshipConfig(hospitalID string) adapter.SurvivorshipConfig {
+	return f[hospitalID]
+}
+
+func TestIngestWriter_KeepsLocallyOwnedFieldAndRecordsConflict(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	stored := func(phone, lastName string) *pgxmock.Rows {
+		return pgxmock.NewRows(patientCols).AddRow(
+			"p1", "HN-1", "N-1", nil,
+			"", nil, "",
+			"Somchai", nil, lastName,
+			nil, phone, "", "M", nil, "HIS-1",
+		)
+	}
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(stored("0811111111", "Jaidee"))
+	// staff corrected the phone; the HIS wrote the name
+	mock.ExpectQuery(`SELECT field, source FROM patient_field_sources`).
+		WithArgs("p1").
+		WillReturnRows(pgxmock.NewRows([]string{"field", "source"}).
+			AddRow("phone_number", repository.SourceStaff).
+			AddRow("last_name_en", repository.SourceHIS))
+	args := anyArgs(16)
+	args[11] = "0811111111" // phone_number kept
+	mock.ExpectExec(`ON CONFLICT \(national_id\)`).
+		WithArgs(args...).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectQuery(`FROM patients WHERE national_id = \$1 OR passport_id = \$1`).
+		WithArgs("N-1").
+		WillReturnRows(stored("0811111111", "Jaidee-Smith"))
+	mock.ExpectExec(`INSERT INTO patient_field_sources`).
+		WithArgs("p1", []string{"last_name_en"}, repository.SourceHL7, "").
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectQuery(`INSERT INTO patient_field_conflicts`).
+		WithArgs("p1", "HIS-1", "phone_number", "0811111111", repository.SourceStaff, "0899999999", repository.SourceHL7).
+		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("c1"))
+	mock.ExpectExec(`INSERT INTO patient_versions`).
+		WithArgs("p1", "HIS-1", repository.SourceHL7, pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+	mock.ExpectExec(`INSERT INTO patient_outbox`).
+		WithArgs(pgxmock.AnyArg(), outbox.EventPatientUpdated, "p1", "HIS-1", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("INSERT", 1))
+
+	policy := fakeSurvivorship{"HIS-1": {"phone_number": adapter.SurvivorLocal, "last_name_en": adapter.SurvivorHIS}}
+	w := NewIngestWriter(&fakeUnitOfWork{db: mock}, nil, repository.SourceHL7, nil, policy)
+
+	p := &repository.Patient{ID: "new-id", PatientHN: "HN-1", NationalID: "N-1", FirstNameEN: "Somchai", LastNameEN: "Jaidee-Smith",
+		PhoneNumber: "0899999999", Gender: "M", HospitalID: "HIS-1"}
+	assert.NoError(t, w.Upsert(context.Background(), p))
+	assert.Equal(t, "0811111111", p.PhoneNumber)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplySurvivorship(t *testing.T) {
+	policy := adapter.SurvivorshipConfig{"phone_number": adapter.SurvivorLocal, "last_name_th": adapter.SurvivorHIS}
+	existing := &repository.Patient{ID: "p1", PhoneNumber: "0811111111", LastNameTH: "ใจดี", HospitalID: "HIS-1"}
+
+	cases := []struct {
+		name      string
+		source    string
+		sources   map[string]string
+		in        repository.Patient
+		wantPhone string
+		wantName  string
+		conflicts int
+	}{
+		{"winner writes", repository.SourceStaff, map[string]string{"phone_number": "staff"},
+			repository.Patient{PhoneNumber: "0822222222", LastNameTH: "ใจดี"}, "0822222222", "ใจดี", 0},
+		{"stored value not from the winner", repository.SourceHIS, map[string]string{"phone_number": "his"},
+			repository.Patient{PhoneNumber: "0822222222", LastNameTH: "ใจดี"}, "0822222222", "ใจดี", 0},
+		{"untracked stored value", repository.SourceHIS, map[string]string{},
+			repository.Patient{PhoneNumber: "0822222222", LastNameTH: "ใจดี"}, "0822222222", "ใจดี", 0},
+		{"loser refused", repository.SourceHIS, map[string]string{"phone_number": "staff"},
+			repository.Patient{PhoneNumber: "0822222222", LastNameTH: "ใจดี"}, "0811111111", "ใจดี", 1},
+		{"loser omits the field", repository.SourceReconciler, map[string]string{"phone_number": "staff"},
+			repository.Patient{LastNameTH: "ใจดี"}, "0811111111", "ใจดี", 0},
+		{"staff cannot rename", repository.SourceStaff, map[string]string{"last_name_th": "hl7"},
+			repository.Patient{PhoneNumber: "0811111111", LastNameTH: "ใจดีมาก"}, "0811111111", "ใจดี", 1},
+	}
+	for _, tc := range cases {
+		mock, err := pgxmock.NewPool()
+		assert.NoError(t, err)
+		rows := pgxmock.NewRows([]string{"field", "source"})
+		for f, s := range tc.sources {
+			rows.AddRow(f, s)
+		}
+		mock.ExpectQuery(`SELECT field, source FROM patient_field_sources`).WithArgs("p1").WillReturnRows(rows)
+
+		p := tc.in
+		refused, err := applySurvivorship(context.Background(), repository.NewRepos(mock), existing, &p, tc.source, policy)
+		assert.NoError(t, err, tc.name)
+		assert.Equal(t, tc.wantPhone, p.PhoneNumber, tc.name)
+		assert.Equal(t, tc.wantName, p.LastNameTH, tc.name)
+		assert.Len(t, refused, tc.conflicts, tc.name)
+		mock.Close()
+	}
+}
@@ -0,0 +1,209 @@
+// Package thaidate parses and formats dates of birth as Thai systems and
+// staff write them: Buddhist Era (BE) or Common Era (CE) years, dd/mm/yyyy,
+// Thai or English month names and Thai digits. Dates are stored as CE
+// yyyy-mm-dd.
+package thaidate
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// BEOffset is the difference between Buddhist Era and Common Era years (2528 BE = 1985 CE).
+const BEOffset = 543
+
+// minBEYear is where a year is taken to be BE: nobody alive was born in
+// 2400 CE, and 2400 BE is 1857 CE.
+const minBEYear = 2400
+
+// ErrInvalid is returned for values that are not a recognisable calendar date.
+var ErrInvalid = errors.New("invalid date")
+
+// Parse reads s in any supported form and returns the CE date as yyyy-mm-dd.
+// Years from 2400 are Buddhist Era. Supported forms:
+//
+//	1985-05-05, 2528-05-05, 1985/05/05, 19850505, 1985-05-05T00:00:00Z
+//	05/05/2528, 5/5/1985, 05-05-2528, 05.05.2528 (day first)
+//	5 พ.ค. 2528, 5 พฤษภาคม พ.ศ. 2528, 5 May 1985, 05-May-1985
+func Parse(s string) (string, error) {
+	orig := s
+	s = strings.TrimSpace(thaiDigits.Replace(s))
+	if len(s) > 10 && s[4] == '-' && (s[10] == 'T' || s[10] == ' ') {
+		s = s[:10] // timestamp
+	}
+	if len(s) == 8 && isDigits(s) {
+		return date(s[0:4], s[4:6], s[6:8], orig)
+	}
+
+	s = eraMarkers.Replace(s)
+	hasLetters := strings.IndexFunc(s, unicode.IsLetter) >= 0
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		// "." separates numeric dates but belongs to Thai abbreviations (พ.ค.)
+		return r == '/' || r == '-' || r == ' ' || r == ',' || (r == '.' && !hasLetters)
+	})
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, orig)
+	}
+
+	if len(parts[0]) == 4 && isDigits(parts[0]) {
+		return date(parts[0], parts[1], parts[2], orig)
+	}
+	month := parts[1]
+	if !isDigits(month) {
+		m, ok := monthNumber(month)
+		if !ok {
+			return "", fmt.Errorf("%w: unknown month %q", ErrInvalid, month)
+		}
+		month = strconv.Itoa(m)
+	}
+	return date(parts[2], month, parts[0], orig)
+}
+
+// ParseFormat reads s laid out as format, made of the tokens yyyy, mm, dd and
+// MMM (month name) between literal separators, e.g. "dd/mm/yyyy" or "yyyymmdd".
+// The year may be BE or CE; the result is the CE date as yyyy-mm-dd.
+func ParseFormat(format, s string) (string, error) {
+	orig, layout := s, format
+	s = strings.TrimSpace(thaiDigits.Replace(s))
+	var y, m, d string
+	for format != "" {
+		var tok string
+		for _, t := range []string{"yyyy", "YYYY", "MMM", "mm", "MM", "dd", "DD"} {
+			if strings.HasPrefix(format, t) {
+				tok = t
+				break
+			}
+		}
+		if tok == "" {
+			if s == "" || s[0] != format[0] {
+				return "", fmt.Errorf("%w: %q does not match %q", ErrInvalid, orig, layout)
+			}
+			format, s = format[1:], s[1:]
+			continue
+		}
+		format = format[len(tok):]
+		switch tok {
+		case "yyyy", "YYYY":
+			y, s = take(s, 4, 4)
+		case "mm", "MM":
+			m, s = take(s, 1, 2)
+		case "dd", "DD":
+			d, s = take(s, 1, 2)
+		case "MMM":
+			i := strings.IndexFunc(s, func(r rune) bool { return unicode.IsDigit(r) || r == ' ' || r == '/' || r == '-' })
+			if i < 0 {
+				i = len(s)
+			}
+			n, ok := monthNumber(s[:i])
+			if !ok {
+				return "", fmt.Errorf("%w: unknown month %q", ErrInvalid, s[:i])
+			}
+			m, s = strconv.Itoa(n), s[i:]
+		}
+	}
+	if s != "" || y == "" || m == "" || d == "" {
+		return "", fmt.Errorf("%w: %q does not match %q", ErrInvalid, orig, layout)
+	}
+	return date(y, m, d, orig)
+}
+
+// IsFormat reports whether format is written with ParseFormat's tokens
+// (rather than as a Go time layout).
+func IsFormat(format string) bool {
+	return strings.Contains(format, "yyyy") || strings.Contains(format, "YYYY")
+}
+
+// FromYear converts a year that may be BE to CE.
+func FromYear(y int) int {
+	if y >= minBEYear {
+		return y - BEOffset
+	}
+	return y
+}
+
+// FormatBE turns a CE yyyy-mm-dd date into dd/mm/yyyy with a BE year.
+// Values that are not yyyy-mm-dd are returned unchanged.
+func FormatBE(ce string) string {
+	t, err := time.Parse("2006-01-02", ce)
+	if err != nil {
+		return ce
+	}
+	return fmt.Sprintf("%02d/%02d/%04d", t.Day(), int(t.Month()), t.Year()+BEOffset)
+}
+
+// date validates the components and returns the CE yyyy-mm-dd date.
+func date(ys, ms, ds, orig string) (string, error) {
+	y, err1 := strconv.Atoi(ys)
+	m, err2 := strconv.Atoi(ms)
+	d, err3 := strconv.Atoi(ds)
+	if err1 != nil || err2 != nil || err3 != nil || len(ys) != 4 {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, orig)
+	}
+	// convert before validating: 29/02/2527 BE exists (1984 CE), 2527 CE is no leap year
+	y = FromYear(y)
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	if t.Year() != y || int(t.Month()) != m || t.Day() != d {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, orig)
+	}
+	return t.Format("2006-01-02"), nil
+}
+
+// take splits off between min and max leading ASCII digits.
+func take(s string, min, max int) (string, string) {
+	n := 0
+	for n < len(s) && n < max && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	if n < min {
+		return "", s
+	}
+	return s[:n], s[n:]
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+var thaiDigits = strings.NewReplacer("๐", "0", "๑", "1", "๒", "2", "๓", "3", "๔", "4", "๕", "5", "๖", "6", "๗", "7", "๘", "8", "๙", "9")
+
+// eraMarkers are dropped before splitting; the year itself decides the era.
+var eraMarkers = strings.NewReplacer("พ.ศ.", " ", "ค.ศ.", " ", "พ.ศ", " ", "ค.ศ", " ", " BE", " ", " CE", " ", "B.E.", " ", "A.D.", " ")
+
+// months lists full and abbreviated month names, Thai then English, per month.
+var months = [12][]string{
+	{"มกราคม", "ม.ค.", "january", "jan"},
+	{"กุมภาพันธ์", "ก.พ.", "february", "feb"},
+	{"มีนาคม", "มี.ค.", "march", "mar"},
+	{"เมษายน", "เม.ย.", "april", "apr"},
+	{"พฤษภาคม", "พ.ค.", "may"},
+	{"มิถุนายน", "มิ.ย.", "june", "jun"},
+	{"กรกฎาคม", "ก.ค.", "july", "jul"},
+	{"สิงหาคม", "ส.ค.", "august", "aug"},
+	{"กันยายน", "ก.ย.", "september", "sep", "sept"},
+	{"ตุลาคม", "ต.ค.", "october", "oct"},
+	{"พฤศจิกายน", "พ.ย.", "november", "nov"},
+	{"ธันวาคม", "ธ.ค.", "december", "dec"},
+}
+
+// monthNumber matches a month name case-insensitively, with or without the
+// dots of Thai abbreviations ("พ.ค." or "พค").
+func monthNumber(name string) (int, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, names := range months {
+		for _, n := range names {
+			if name == n || strings.ReplaceAll(name, ".", "") == strings.ReplaceAll(n, ".", "") {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
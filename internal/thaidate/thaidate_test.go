@@ -0,0 +1,62 @@
+package thaidate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]string{
+		"1985-05-05":           "1985-05-05",
+		"2528-05-05":           "1985-05-05",
+		"1985/05/05":           "1985-05-05",
+		"19850505":             "1985-05-05",
+		"25280505":             "1985-05-05",
+		"1985-05-05T00:00:00Z": "1985-05-05",
+		"05/05/2528":           "1985-05-05",
+		"5/5/1985":             "1985-05-05",
+		"05-05-2528":           "1985-05-05",
+		"05.05.2528":           "1985-05-05",
+		"๐๕/๐๕/๒๕๒๘":           "1985-05-05",
+		"5 พ.ค. 2528":          "1985-05-05",
+		"5 พค 2528":            "1985-05-05",
+		"5 พฤษภาคม พ.ศ. 2528":  "1985-05-05",
+		"5 May 1985":           "1985-05-05",
+		"05-may-1985":          "1985-05-05",
+		"29/02/2527":           "1984-02-29", // leap year in CE, not in BE arithmetic
+	}
+	for in, want := range cases {
+		got, err := Parse(in)
+		if assert.NoError(t, err, in) {
+			assert.Equal(t, want, got, in)
+		}
+	}
+
+	for _, in := range []string{"", "05/2528", "31/02/2528", "29/02/2528", "5 Foo 1985", "85-05-05", "tomorrow"} {
+		_, err := Parse(in)
+		assert.ErrorIs(t, err, ErrInvalid, in)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	got, err := ParseFormat("dd/mm/yyyy", "29/02/2527")
+	assert.NoError(t, err)
+	assert.Equal(t, "1984-02-29", got)
+
+	got, err = ParseFormat("yyyymmdd", "19850505")
+	assert.NoError(t, err)
+	assert.Equal(t, "1985-05-05", got)
+
+	got, err = ParseFormat("dd MMM yyyy", "05 ม.ค. 2530")
+	assert.NoError(t, err)
+	assert.Equal(t, "1987-01-05", got)
+
+	_, err = ParseFormat("dd/mm/yyyy", "1985-05-05")
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestFormatBE(t *testing.T) {
+	assert.Equal(t, "05/05/2528", FormatBE("1985-05-05"))
+	assert.Equal(t, "garbage", FormatBE("garbage"))
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned for endpoints on loopback, link-local,
+// private or otherwise internal addresses.
+var ErrBlockedAddress = errors.New("webhook url resolves to a non-public address")
+
+// sharedAddressSpace is 100.64.0.0/10 (carrier-grade NAT), not covered by net.IP.IsPrivate.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedIP reports whether ip is not a public unicast address: loopback,
+// link-local (169.254.169.254 metadata endpoints), private and ULA
+// (fc00::/7), shared, unspecified and multicast addresses.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// checkHost resolves host and fails if any of its addresses is blocked.
+func checkHost(ctx context.Context, resolver *net.Resolver, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if blockedIP(ip) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, ip)
+		}
+		return nil
+	}
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: resolve %s: %v", ErrInvalidURL, host, err)
+	}
+	for _, a := range addrs {
+		if blockedIP(a.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, a.IP)
+		}
+	}
+	return nil
+}
+
+// newHTTPClient returns the delivery client. Unless allowPrivate, every
+// connection (redirects included) is checked again at dial time, after DNS
+// resolution, so a host re-pointed after registration cannot reach
+// internal services. Proxies are not used for the same reason.
+func newHTTPClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Deliverer sends pending webhook deliveries with signed payloads.
+// Failed deliveries are retried with exponential backoff; after maxAttempts
+// they are marked dead and only manual redelivery sends them again.
+type Deliverer struct {
+	uow         repository.UnitOfWork
+	httpClient  *http.Client
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	now         func() time.Time
+}
+
+// NewDeliverer constructs a Deliverer polling every interval. Unless
+// allowPrivate, endpoints on non-public addresses are refused at dial time.
+func NewDeliverer(uow repository.UnitOfWork, interval, timeout time.Duration, maxAttempts int, allowPrivate bool) *Deliverer {
+	return &Deliverer{
+		uow:         uow,
+		httpClient:  newHTTPClient(timeout, allowPrivate),
+		batchSize:   50,
+		interval:    interval,
+		maxAttempts: maxAttempts,
+		baseDelay:   10 * time.Second,
+		maxDelay:    time.Hour,
+		now:         time.Now,
+	}
+}
+
+// Run polls until ctx is cancelled.
+func (d *Deliverer) Run(ctx context.Context) {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+
+	for {
+		if _, err := d.ProcessOnce(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("webhook deliverer error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// ProcessOnce attempts one batch of due deliveries and returns how many succeeded.
+func (d *Deliverer) ProcessOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	err := d.uow.Do(ctx, func(r *repository.Repos) error {
+		due, err := r.Webhooks.FetchDueDeliveries(ctx, d.batchSize)
+		if err != nil {
+			return fmt.Errorf("fetch due deliveries: %w", err)
+		}
+
+		for _, dd := range due {
+			code, sendErr := d.send(ctx, dd)
+			if sendErr == nil {
+				if err := r.Webhooks.MarkDelivered(ctx, dd.Delivery.ID, code); err != nil {
+					return fmt.Errorf("mark delivered: %w", err)
+				}
+				delivered++
+				continue
+			}
+
+			attempt := dd.Delivery.Attempts + 1
+			status := repository.DeliveryPending
+			if attempt >= d.maxAttempts {
+				status = repository.DeliveryDead
+			}
+			next := d.now().Add(d.backoff(attempt))
+			if err := r.Webhooks.MarkFailed(ctx, dd.Delivery.ID, status, code, sendErr.Error(), next); err != nil {
+				return fmt.Errorf("mark failed: %w", err)
+			}
+			log.Printf("webhook delivery %s (%s) attempt %d failed, status=%s: %v",
+				dd.Delivery.ID, dd.Delivery.EventType, attempt, status, sendErr)
+		}
+		return nil
+	})
+	return delivered, err
+}
+
+// send POSTs the signed payload. It returns the response status code (0 if none).
+func (d *Deliverer) send(ctx context.Context, dd *repository.DueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dd.URL, bytes.NewReader(dd.Delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, dd.Delivery.EventType)
+	req.Header.Set(HeaderDelivery, dd.Delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(dd.Secret, d.now(), dd.Delivery.Payload))
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("http request: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the exponential retry delay for the given attempt (1-based).
+func (d *Deliverer) backoff(attempt int) time.Duration {
+	delay := d.baseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.maxDelay {
+			return d.maxDelay
+		}
+	}
+	return delay
+}
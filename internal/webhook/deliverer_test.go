@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+var dueCols = []string{
+	"id", "subscription_id", "hospital_id", "event_id", "event_type", "payload", "attempts", "url", "secret",
+}
+
+func TestDeliverer_SignsAndMarksDelivered(t *testing.T) {
+	payload := []byte(`{"event_id":"e1","type":"patient.created"}`)
+
+	// local receiver verifying the signature like a hospital would
+	var verifyErr error
+	var gotEvent string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = Verify("whsec_test", r.Header.Get(HeaderSignature), body, time.Minute, time.Now())
+		gotEvent = r.Header.Get(HeaderEvent)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM webhook_deliveries d[\s\S]*AND s\.active`).
+		WithArgs(50).
+		WillReturnRows(pgxmock.NewRows(dueCols).
+			AddRow("d1", "s1", "HIS-1", "e1", "patient.created", payload, 0, receiver.URL, "whsec_test"))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = 'delivered'`).
+		WithArgs("d1", 200).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	d := NewDeliverer(repository.NewUnitOfWork(mock), time.Second, time.Second, 3, true)
+	n, err := d.ProcessOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.NoError(t, verifyErr)
+	assert.Equal(t, "patient.created", gotEvent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliverer_LastAttemptGoesDead(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer receiver.Close()
+
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM webhook_deliveries d[\s\S]*AND s\.active`).
+		WithArgs(50).
+		WillReturnRows(pgxmock.NewRows(dueCols).
+			AddRow("d1", "s1", "HIS-1", "e1", "patient.updated", []byte(`{}`), 2, receiver.URL, "whsec_test"))
+	mock.ExpectExec(`UPDATE webhook_deliveries SET status = \$2`).
+		WithArgs("d1", repository.DeliveryDead, 502, "endpoint status 502", pgxmock.AnyArg()).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	d := NewDeliverer(repository.NewUnitOfWork(mock), time.Second, time.Second, 3, true)
+	n, err := d.ProcessOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeliverer_Backoff(t *testing.T) {
+	d := NewDeliverer(nil, time.Second, time.Second, 3, true)
+	assert.Equal(t, 10*time.Second, d.backoff(1))
+	assert.Equal(t, 40*time.Second, d.backoff(3))
+	assert.Equal(t, time.Hour, d.backoff(20))
+}
+
+func TestDeliverer_RefusesPrivateAddressAtDialTime(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback receiver")
+	}))
+	defer receiver.Close()
+
+	d := NewDeliverer(nil, time.Second, time.Second, 3, false)
+	_, err := d.send(context.Background(), &repository.DueDelivery{
+		Delivery: repository.WebhookDelivery{ID: "d1", EventType: "patient.created"},
+		URL:      receiver.URL,
+		Secret:   "whsec_test",
+	})
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+// Dispatcher is an outbox.Sink that fans each event out to the matching
+// subscriptions of the event's hospital by creating pending deliveries.
+// The Deliverer sends them.
+type Dispatcher struct {
+	uow repository.UnitOfWork
+}
+
+func NewDispatcher(uow repository.UnitOfWork) *Dispatcher {
+	return &Dispatcher{uow: uow}
+}
+
+func (d *Dispatcher) Name() string { return "webhooks" }
+
+func (d *Dispatcher) Publish(ctx context.Context, e outbox.Event) error {
+	if e.HospitalID == "" {
+		return nil
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	return d.uow.Do(ctx, func(r *repository.Repos) error {
+		subs, err := r.Webhooks.ActiveSubscriptionsFor(ctx, e.HospitalID, e.Type)
+		if err != nil {
+			return fmt.Errorf("list subscriptions: %w", err)
+		}
+		for _, s := range subs {
+			if err := r.Webhooks.EnqueueDelivery(ctx, &repository.WebhookDelivery{
+				SubscriptionID: s.ID,
+				HospitalID:     e.HospitalID,
+				EventID:        e.EventID,
+				EventType:      e.Type,
+				Payload:        body,
+			}); err != nil {
+				return fmt.Errorf("enqueue delivery: %w", err)
+			}
+		}
+		return nil
+	})
+}
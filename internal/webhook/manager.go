@@ -0,0 +1,138 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/haniscreator/agnos-search/internal/outbox"
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+var (
+	ErrInvalidURL       = errors.New("webhook url must be an absolute https url")
+	ErrInvalidEventType = errors.New("unsupported event type")
+	ErrNotFound         = errors.New("not found")
+)
+
+// SupportedEvents lists the event types a subscription may register for:
+// the ones the outbox emits.
+var SupportedEvents = []string{
+	outbox.EventPatientCreated,
+	outbox.EventPatientUpdated,
+}
+
+// Manager handles subscription management and the dead-letter view.
+// Every operation is scoped to the caller's hospital.
+type Manager struct {
+	repo         *repository.WebhookRepo
+	allowHTTP    bool // permit plain http endpoints (local receivers in dev/tests)
+	allowPrivate bool // permit loopback/private endpoints (local receivers in dev/tests)
+	resolver     *net.Resolver
+}
+
+func NewManager(repo *repository.WebhookRepo, allowHTTP, allowPrivate bool) *Manager {
+	return &Manager{repo: repo, allowHTTP: allowHTTP, allowPrivate: allowPrivate, resolver: net.DefaultResolver}
+}
+
+// Subscribe registers an endpoint for hospitalID and returns it together with
+// its signing secret. The secret is only returned here.
+// An empty eventTypes subscribes to every supported event.
+func (m *Manager) Subscribe(ctx context.Context, hospitalID, rawURL string, eventTypes []string) (*repository.WebhookSubscription, string, error) {
+	if len(eventTypes) == 0 {
+		eventTypes = SupportedEvents
+	}
+	for _, et := range eventTypes {
+		if !supported(et) {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidEventType, et)
+		}
+	}
+	if err := m.validateURL(ctx, rawURL); err != nil {
+		return nil, "", err
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	s := &repository.WebhookSubscription{
+		HospitalID: hospitalID,
+		URL:        rawURL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+		Active:     true,
+	}
+	if err := m.repo.CreateSubscription(ctx, s); err != nil {
+		return nil, "", fmt.Errorf("create subscription: %w", err)
+	}
+	return s, secret, nil
+}
+
+func (m *Manager) List(ctx context.Context, hospitalID string) ([]*repository.WebhookSubscription, error) {
+	return m.repo.ListSubscriptions(ctx, hospitalID)
+}
+
+func (m *Manager) Unsubscribe(ctx context.Context, hospitalID, id string) error {
+	ok, err := m.repo.DeleteSubscription(ctx, hospitalID, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Deliveries lists deliveries; status "dead" is the dead-letter view.
+func (m *Manager) Deliveries(ctx context.Context, hospitalID, status string, limit, offset int) ([]*repository.WebhookDelivery, error) {
+	return m.repo.ListDeliveries(ctx, hospitalID, status, limit, offset)
+}
+
+// Redeliver queues a delivery (typically a dead one) for another round of attempts.
+func (m *Manager) Redeliver(ctx context.Context, hospitalID, id string) (*repository.WebhookDelivery, error) {
+	d, err := m.repo.Redeliver(ctx, hospitalID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, ErrNotFound
+	}
+	return d, nil
+}
+
+// validateURL checks the scheme and, unless allowPrivate, that the host
+// resolves to public addresses only. Deliveries check again at dial time.
+func (m *Manager) validateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	if u.Scheme != "https" && !(m.allowHTTP && u.Scheme == "http") {
+		return ErrInvalidURL
+	}
+	if m.allowPrivate {
+		return nil
+	}
+	return checkHost(ctx, m.resolver, u.Hostname())
+}
+
+func supported(eventType string) bool {
+	for _, et := range SupportedEvents {
+		if et == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
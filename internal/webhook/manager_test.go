@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/haniscreator/agnos-search/internal/repository"
+)
+
+func TestManager_SubscribeValidates(t *testing.T) {
+	m := NewManager(nil, false, false)
+
+	_, _, err := m.Subscribe(context.Background(), "HIS-1", "http://lab.example/hook", nil)
+	assert.ErrorIs(t, err, ErrInvalidURL, "plain http is rejected by default")
+
+	_, _, err = m.Subscribe(context.Background(), "HIS-1", "https://lab.example/hook", []string{"patient.deleted"})
+	assert.ErrorIs(t, err, ErrInvalidEventType)
+
+	for _, u := range []string{
+		"https://127.0.0.1/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hook",
+		"https://[fd00::1]/hook",
+		"https://[::1]:8443/hook",
+		"https://localhost/hook",
+	} {
+		_, _, err = m.Subscribe(context.Background(), "HIS-1", u, nil)
+		assert.ErrorIs(t, err, ErrBlockedAddress, u)
+	}
+}
+
+func TestBlockedIP(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "169.254.169.254", "192.168.1.1", "172.16.0.1", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fc00::1", "::ffff:10.0.0.1"} {
+		assert.True(t, blockedIP(net.ParseIP(ip)), ip)
+	}
+	for _, ip := range []string{"8.8.8.8", "203.0.113.10", "2001:4860:4860::8888"} {
+		assert.False(t, blockedIP(net.ParseIP(ip)), ip)
+	}
+}
+
+func TestManager_SubscribeDefaultsToAllEvents(t *testing.T) {
+	mock, err := pgxmock.NewPool()
+	assert.NoError(t, err)
+	defer mock.Close()
+
+	mock.ExpectQuery(`INSERT INTO webhook_subscriptions`).
+		WithArgs("HIS-1", "http://localhost:9000/hook", pgxmock.AnyArg(), SupportedEvents, true).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow("s1", time.Now()))
+
+	m := NewManager(repository.NewWebhookRepo(mock), true, true)
+	sub, secret, err := m.Subscribe(context.Background(), "HIS-1", "http://localhost:9000/hook", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "s1", sub.ID)
+	assert.Equal(t, secret, sub.Secret)
+	assert.Contains(t, secret, "whsec_")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderSignature = "X-Agnos-Signature" // t=<unix>,v1=<hex hmac>
+	HeaderEvent     = "X-Agnos-Event"
+	HeaderDelivery  = "X-Agnos-Delivery"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Sign returns the signature header value for body sent at ts.
+// The MAC is HMAC-SHA256(secret, "<unix ts>.<body>").
+func Sign(secret string, ts time.Time, body []byte) string {
+	unix := strconv.FormatInt(ts.Unix(), 10)
+	return "t=" + unix + ",v1=" + mac(secret, unix, body)
+}
+
+// Verify checks a signature header against body. Signatures older or newer
+// than tolerance are rejected to limit replays; tolerance <= 0 disables the check.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix, sig string
+	for _, part := range strings.Split(header, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			unix = v
+		case "v1":
+			sig = v
+		}
+	}
+	if unix == "" || sig == "" {
+		return ErrInvalidSignature
+	}
+
+	secs, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(secs, 0))
+		if age > tolerance || age < -tolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+		}
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(mac(secret, unix, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func mac(secret, unix string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(unix))
+	m.Write([]byte("."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
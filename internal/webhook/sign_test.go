@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignVerify_RoundTrip(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"type":"patient.created"}`)
+
+	h := Sign("s3cret", now, body)
+	assert.Contains(t, h, "t=1700000000,v1=")
+	assert.NoError(t, Verify("s3cret", h, body, 5*time.Minute, now.Add(time.Minute)))
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{}`)
+	h := Sign("s3cret", now, body)
+
+	assert.True(t, errors.Is(Verify("other", h, body, 0, now), ErrInvalidSignature), "wrong secret")
+	assert.True(t, errors.Is(Verify("s3cret", h, []byte(`{"x":1}`), 0, now), ErrInvalidSignature), "tampered body")
+	assert.True(t, errors.Is(Verify("s3cret", h, body, time.Minute, now.Add(time.Hour)), ErrInvalidSignature), "stale timestamp")
+	assert.True(t, errors.Is(Verify("s3cret", "garbage", body, 0, now), ErrInvalidSignature), "malformed header")
+}
@@ -0,0 +1,12 @@
+-- migrations/005_create_patient_versions.sql
+-- append-only history of patient writes (one row per upsert)
+CREATE TABLE IF NOT EXISTS patient_versions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  patient_id UUID NOT NULL,
+  hospital_id TEXT,
+  source TEXT NOT NULL,                       -- 'his' (adapter back-fill) or 'staff' (POST /v1/patients)
+  snapshot JSONB NOT NULL,                    -- patient row as written
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_versions_patient_id ON patient_versions(patient_id, created_at);
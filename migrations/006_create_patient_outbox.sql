@@ -0,0 +1,20 @@
+-- migrations/006_create_patient_outbox.sql
+-- transactional outbox: rows are written in the same transaction as the patient change
+-- and published by the relay worker in id order.
+CREATE TABLE IF NOT EXISTS patient_outbox (
+  id BIGSERIAL PRIMARY KEY,                   -- publish order
+  event_id UUID NOT NULL UNIQUE,              -- dedupe key for consumers
+  event_type TEXT NOT NULL,                   -- 'patient.created', 'patient.updated'
+  patient_id UUID NOT NULL,
+  hospital_id TEXT,
+  payload JSONB NOT NULL,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  published_at TIMESTAMPTZ,                   -- NULL until every sink accepted the event
+  attempts INTEGER NOT NULL DEFAULT 0,
+  delivered_sinks TEXT[] NOT NULL DEFAULT '{}', -- sinks already done, skipped on retry
+  last_error TEXT,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_outbox_pending ON patient_outbox(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_patient_outbox_hospital_id ON patient_outbox(hospital_id, id);
@@ -0,0 +1,34 @@
+-- migrations/007_create_webhooks.sql
+-- per-hospital webhook subscriptions for patient events
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  hospital_id TEXT NOT NULL,
+  url TEXT NOT NULL,
+  secret TEXT NOT NULL,                       -- HMAC-SHA256 signing secret
+  event_types TEXT[] NOT NULL,                -- e.g. {patient.created,patient.updated}
+  active BOOLEAN NOT NULL DEFAULT true,
+  created_at TIMESTAMPTZ DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_hospital_id ON webhook_subscriptions(hospital_id);
+
+-- one row per (subscription, event); status: pending -> delivered | dead
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  subscription_id UUID NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+  hospital_id TEXT NOT NULL,
+  event_id UUID NOT NULL,
+  event_type TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',
+  attempts INTEGER NOT NULL DEFAULT 0,
+  next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_status_code INTEGER,
+  last_error TEXT,
+  created_at TIMESTAMPTZ DEFAULT now(),
+  delivered_at TIMESTAMPTZ,
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_hospital_status ON webhook_deliveries(hospital_id, status);
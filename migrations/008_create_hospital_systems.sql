@@ -0,0 +1,8 @@
+-- migrations/008_create_hospital_systems.sql
+-- per-hospital HIS adapter configuration (alternative to HIS_REGISTRY_FILE)
+CREATE TABLE IF NOT EXISTS hospital_systems (
+  hospital_id TEXT PRIMARY KEY,
+  config JSONB NOT NULL,                      -- same shape as one entry of the registry file
+  enabled BOOLEAN NOT NULL DEFAULT true,
+  updated_at TIMESTAMPTZ DEFAULT now()
+);
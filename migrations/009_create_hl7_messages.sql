@@ -0,0 +1,17 @@
+-- migrations/009_create_hl7_messages.sql
+-- raw HL7 v2 messages received over MLLP, kept for audit and replay
+CREATE TABLE IF NOT EXISTS hl7_messages (
+  id BIGSERIAL PRIMARY KEY,
+  hospital_id TEXT NOT NULL,
+  control_id TEXT,                            -- MSH-10
+  message_type TEXT,                          -- MSH-9, e.g. ADT^A04
+  raw TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'received',    -- received | accepted | rejected | error
+  error TEXT,
+  patient_id UUID,
+  received_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  processed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_hl7_messages_hospital_received ON hl7_messages (hospital_id, received_at DESC);
+CREATE INDEX IF NOT EXISTS idx_hl7_messages_control_id ON hl7_messages (hospital_id, control_id);
@@ -0,0 +1,18 @@
+-- migrations/010_add_his_synced_at.sql
+-- when a patient was last fetched from its hospital's HIS (NULL: never, e.g. staff-entered);
+-- the reconciler refreshes rows older than the hospital's TTL
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS his_synced_at TIMESTAMPTZ;
+-- a reconciler instance working on the row; claims expire so crashed runs are retried
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS his_sync_claimed_at TIMESTAMPTZ;
+
+-- patients back-filled from the HIS before this migration
+UPDATE patients p SET his_synced_at = v.last_synced
+FROM (
+  SELECT patient_id, max(created_at) AS last_synced
+  FROM patient_versions WHERE source = 'his'
+  GROUP BY patient_id
+) v
+WHERE p.id = v.patient_id AND p.his_synced_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_patients_his_synced_at ON patients (hospital_id, his_synced_at)
+  WHERE his_synced_at IS NOT NULL;
@@ -0,0 +1,22 @@
+-- migrations/011_create_his_quarantine.sql
+-- HIS records that failed validation; admins release (store) or reject them
+CREATE TABLE IF NOT EXISTS his_quarantine (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  hospital_id TEXT NOT NULL,
+  identifier TEXT NOT NULL,                   -- what we asked the HIS for
+  reasons TEXT[] NOT NULL,
+  patient JSONB NOT NULL,                     -- the record as mapped by the adapter
+  raw_body TEXT,                              -- the HIS response as received
+  status TEXT NOT NULL DEFAULT 'pending',     -- pending | released | rejected
+  hits INT NOT NULL DEFAULT 1,                -- lookups that returned this record while pending
+  patient_id UUID,                            -- stored patient after release
+  reviewed_by TEXT,
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- one pending record per identifier; repeated lookups refresh it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_his_quarantine_pending
+  ON his_quarantine (hospital_id, identifier) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_his_quarantine_hospital_status ON his_quarantine (hospital_id, status, created_at DESC);
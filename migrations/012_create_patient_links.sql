@@ -0,0 +1,14 @@
+-- migrations/012_create_patient_links.sql
+-- likely duplicates found by record linkage when HIS / HL7 data is ingested
+CREATE TABLE IF NOT EXISTS patient_links (
+  patient_id UUID NOT NULL,                   -- the ingested record
+  candidate_id UUID NOT NULL,                 -- the stored record it resembles
+  hospital_id TEXT NOT NULL,
+  score DOUBLE PRECISION NOT NULL,
+  decision TEXT NOT NULL,                     -- match | possible
+  source TEXT NOT NULL,                       -- his | hl7
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (patient_id, candidate_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_patient_links_hospital ON patient_links (hospital_id, created_at DESC);
@@ -0,0 +1,33 @@
+-- migrations/013_create_field_survivorship.sql
+-- which source last wrote each patient field, for per-hospital survivorship rules
+CREATE TABLE IF NOT EXISTS patient_field_sources (
+  patient_id UUID NOT NULL,
+  field TEXT NOT NULL,                        -- standard field name, e.g. phone_number
+  source TEXT NOT NULL,                       -- staff | his | hl7 | reconciler | quarantine
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (patient_id, field)
+);
+
+-- writes the survivorship policy refused, kept for review
+CREATE TABLE IF NOT EXISTS patient_field_conflicts (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  patient_id UUID NOT NULL,
+  hospital_id TEXT NOT NULL,
+  field TEXT NOT NULL,
+  kept_value TEXT NOT NULL,
+  kept_source TEXT NOT NULL,
+  overridden_value TEXT NOT NULL,
+  overridden_source TEXT NOT NULL,
+  status TEXT NOT NULL DEFAULT 'pending',     -- pending | accepted | dismissed
+  hits INT NOT NULL DEFAULT 1,                -- refused writes of this value while pending
+  reviewed_by TEXT,
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- one pending conflict per refused value; repeated writes refresh it
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_field_conflicts_pending
+  ON patient_field_conflicts (patient_id, field, overridden_value) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_patient_field_conflicts_hospital_status
+  ON patient_field_conflicts (hospital_id, status, created_at DESC);
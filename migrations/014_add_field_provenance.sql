@@ -0,0 +1,4 @@
+-- migrations/014_add_field_provenance.sql
+-- who wrote each patient field: staff id for staff writes and staff-triggered HIS lookups,
+-- NULL for automated writes (reconciler, HL7 feeds); see GET /v1/patient/{id}?include=provenance
+ALTER TABLE patient_field_sources ADD COLUMN IF NOT EXISTS actor TEXT;
@@ -14,6 +14,16 @@ docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/001_create_pat
 docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/002_create_staffs.sql
 docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/003_add_hospital_id_to_patients.sql || true
 docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/004_create_search_events.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/005_create_patient_versions.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/006_create_patient_outbox.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/007_create_webhooks.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/008_create_hospital_systems.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/009_create_hl7_messages.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/010_add_his_synced_at.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/011_create_his_quarantine.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/012_create_patient_links.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/013_create_field_survivorship.sql || true
+docker exec -i agnos_postgres psql -U agnos -d agnos < migrations/014_add_field_provenance.sql || true
 
 echo "3/5: create staff (idempotent)"
 curl -s -X POST http://localhost:8080/staff/create \